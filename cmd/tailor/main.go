@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,16 @@ import (
 	"github.com/alecthomas/kingpin"
 	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/commands"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+const version = "1.1.4+master"
+
+// Exit codes of Tailor, besides 0 for success.
+const (
+	exitCodeError         = 1
+	exitCodeDriftDetected = 3
+	exitCodeTimeout       = 4
 )
 
 var (
@@ -41,6 +52,14 @@ var (
 		"force",
 		"Force to continue despite warning (e.g. deleting all resources).",
 	).Bool()
+	timeoutFlag = app.Flag(
+		"timeout",
+		"Abort the whole run if it takes longer than given duration (e.g. 5m).",
+	).Duration()
+	tokenRefreshCommandFlag = app.Flag(
+		"token-refresh-command",
+		"Shell command to run when the oc session expired mid-run (e.g. to log in again), after which the failed oc command is retried once.",
+	).String()
 	namespaceFlag = app.Flag(
 		"namespace",
 		"Namespace (omit to use current)",
@@ -53,17 +72,45 @@ var (
 		"exclude",
 		"Exclude kinds, names and labels (repeatable or comma-separated)",
 	).Short('e').Strings()
+	apiGroupFlag = app.Flag(
+		"api-group",
+		"Limit all kinds to those of given API groups, e.g. core or apps (repeatable or comma-separated)",
+	).Strings()
+	excludeAPIGroupFlag = app.Flag(
+		"exclude-api-group",
+		"Exclude kinds of given API groups (repeatable or comma-separated)",
+	).Strings()
+	excludeFieldManagerFlag = app.Flag(
+		"exclude-field-manager",
+		"Exclude resources in the cluster which are managed by given field manager, e.g. a controller (repeatable or comma-separated)",
+	).Strings()
+	onlyKindsFlag = app.Flag(
+		"only-kinds",
+		"Limit all kinds to given kinds, e.g. dc,svc (repeatable or comma-separated)",
+	).Strings()
 	templateDirFlag = app.Flag(
 		"template-dir",
 		"Path to local templates",
 	).Short('t').Default(".").String()
+	recursiveFlag = app.Flag(
+		"recursive",
+		"Read templates from subdirectories of the template dir as well (skipping hidden directories)",
+	).Short('R').Bool()
+	ignoreFileFlag = app.Flag(
+		"ignore-file",
+		"Path to file listing templates which are not processed (defaults to .tailorignore in the template dir)",
+	).String()
 	paramDirFlag = app.Flag(
 		"param-dir",
 		"Path to parameter files for local templates (defaults to <NAMESPACE> or working directory)",
 	).Short('p').Default(".").String()
+	patchDirFlag = app.Flag(
+		"patch-dir",
+		"Path to patches applied to processed templates, in a subdirectory per namespace",
+	).Default("patches").String()
 	publicKeyDirFlag = app.Flag(
 		"public-key-dir",
-		"Path to public key files",
+		"Path to public key files (defaults to public-keys, .tailor/keys at repository root or working directory)",
 	).Default(".").String()
 	privateKeyFlag = app.Flag(
 		"private-key",
@@ -73,11 +120,19 @@ var (
 		"passphrase",
 		"Passphrase to unlock key",
 	).String()
+	passphraseFileFlag = app.Flag(
+		"passphrase-file",
+		"Path to a file containing the passphrase to unlock key",
+	).String()
 
 	versionCommand = app.Command(
 		"version",
 		"Show version",
 	)
+	versionCheckFlag = versionCommand.Flag(
+		"check",
+		"Check whether a newer version is available on GitHub.",
+	).Bool()
 
 	diffCommand = app.Command(
 		"diff",
@@ -85,7 +140,15 @@ var (
 	).Alias("status")
 	diffLabelsFlag = diffCommand.Flag(
 		"labels",
-		"Label to set in all resources for this template.",
+		"Label to set in all resources for this template. Parameters (e.g. ${FOO}) are resolved.",
+	).String()
+	diffNamePrefixFlag = diffCommand.Flag(
+		"name-prefix",
+		"Prefix to prepend to the names of all resources in the templates (and references to them).",
+	).String()
+	diffNameSuffixFlag = diffCommand.Flag(
+		"name-suffix",
+		"Suffix to append to the names of all resources in the templates (and references to them).",
 	).String()
 	diffParamFlag = diffCommand.Flag(
 		"param",
@@ -95,6 +158,10 @@ var (
 		"param-file",
 		"File(s) containing template parameter values to set/override in the template.",
 	).Strings()
+	diffParamFilePrecedenceFlag = diffCommand.Flag(
+		"param-file-precedence",
+		"Which of multiple param files wins when they define the same parameter (first-wins or last-wins).",
+	).PlaceHolder("last-wins").Enum("first-wins", "last-wins")
 	diffIgnorePathFlag = diffCommand.Flag(
 		"ignore-path",
 		"DEPRECATED! Use --preserve instead.",
@@ -103,10 +170,42 @@ var (
 		"preserve",
 		"Path(s) per kind/name for which to preserve current state (e.g. because they are externally modified) in RFC 6901 format.",
 	).PlaceHolder("bc:foobar:/spec/output/to/name").Strings()
+	diffIdentityFlag = diffCommand.Flag(
+		"identity",
+		"Label per kind by which to match desired and current resources instead of by name.",
+	).PlaceHolder("job:app").Strings()
+	diffComparePolicyFlag = diffCommand.Flag(
+		"compare-policy",
+		"Policy per kind defining how resources are compared (ignore, spec-only or strict).",
+	).PlaceHolder("hpa:spec-only").Strings()
+	diffDiffOnlyFlag = diffCommand.Flag(
+		"diff-only",
+		"Resource(s) whose drift is reported but never applied.",
+	).PlaceHolder("cm/foo").Strings()
+	diffUnsetParamFlag = diffCommand.Flag(
+		"unset-param",
+		"Name(s) of params which should resolve to an empty value instead of their template default.",
+	).PlaceHolder("KEY").Strings()
+	diffSensitiveParamFlag = diffCommand.Flag(
+		"sensitive-param",
+		"Name(s) of params whose values are masked in all output.",
+	).PlaceHolder("TOKEN").Strings()
 	diffPreserveImmutableFieldsFlag = diffCommand.Flag(
 		"preserve-immutable-fields",
 		"Preserve current state of all immutable fields (such as host of a route, or storageClassName of a PVC).",
 	).Bool()
+	diffDeprecatedAPIVersionFlag = diffCommand.Flag(
+		"deprecated-api-version",
+		"apiVersion(s) to warn about on top of apiVersions deprecated by default.",
+	).PlaceHolder("apps/v1beta1").Strings()
+	diffStrictParamConflictsFlag = diffCommand.Flag(
+		"strict-param-conflicts",
+		"Fail if a param is defined with different values in multiple param files.",
+	).Bool()
+	diffRequireParamFileFlag = diffCommand.Flag(
+		"require-param-file",
+		"Fail if no param file is found for a template (when param files are looked up in the param dir).",
+	).Bool()
 	diffIgnoreUnknownParametersFlag = diffCommand.Flag(
 		"ignore-unknown-parameters",
 		"If true, will not stop processing if a provided parameter does not exist in the template.",
@@ -119,10 +218,114 @@ var (
 		"allow-recreate",
 		"Allow to recreate the whole resource when an immutable field is changed.",
 	).Bool()
+	diffOnImmutableFlag = diffCommand.Flag(
+		"on-immutable",
+		"How to handle drift of immutable fields: fail, recreate the whole resource, or warn and leave the resource as-is (error, recreate or warn).",
+	).PlaceHolder("error").Enum("error", "recreate", "warn")
+	diffIgnoreWhitespaceFlag = diffCommand.Flag(
+		"ignore-whitespace",
+		"Ignore differences in trailing whitespace and line endings (CRLF vs. LF) of string fields.",
+	).Bool()
+	diffAdoptFlag = diffCommand.Flag(
+		"adopt",
+		"Show resources which exist in the cluster but are not selected (e.g. as they lack the selector label) as updates instead of creations, so that their labels and desired state are applied.",
+	).Bool()
+	diffPruneFlag = diffCommand.Flag(
+		"prune",
+		"Delete resources carrying the prune label which are not defined in any template, regardless of the given kinds and selector.",
+	).Bool()
+	diffPruneLabelFlag = diffCommand.Flag(
+		"prune-label",
+		"Label identifying resources managed by Tailor for --prune (defaults to --selector).",
+	).PlaceHolder("app=foo").String()
+	diffPruneAgeFlag = diffCommand.Flag(
+		"prune-age",
+		"Only delete resources which have been created longer than given duration ago.",
+	).PlaceHolder("720h").Duration()
+	diffParamsHashFlag = diffCommand.Flag(
+		"params-hash",
+		"Annotate pod templates of workloads with a hash of all params, so that they roll out when any param changes.",
+	).Bool()
+	diffTemplateHashFlag = diffCommand.Flag(
+		"template-hash",
+		"Annotate resources with a hash of the template source they are generated from.",
+	).Bool()
 	diffRevealSecretsFlag = diffCommand.Flag(
 		"reveal-secrets",
 		"Reveal drift of Secret resources (might show secret values in clear text).",
 	).Bool()
+	diffOutputFlag = diffCommand.Flag(
+		"output",
+		"Output format of the diff (text, html or merge-patch).",
+	).Short('o').PlaceHolder("text").Enum("text", "html", "merge-patch")
+	diffEventsFlag = diffCommand.Flag(
+		"events",
+		"Emit newline-delimited JSON events on STDOUT instead of the human-readable output.",
+	).Bool()
+	diffSummaryOnlyFlag = diffCommand.Flag(
+		"summary-only",
+		"Only print the number of changes as key=value pairs, e.g. create=1 update=0 delete=0 noop=3.",
+	).Bool()
+	diffInSyncThresholdFlag = diffCommand.Flag(
+		"in-sync-threshold",
+		"Collapse in sync resources into one line if there are more than given number (defaults to listing all).",
+	).PlaceHolder("50").Int()
+	diffShowDesiredFlag = diffCommand.Flag(
+		"show-desired",
+		"Print the rendered desired state of given resource and exit.",
+	).PlaceHolder("dc/foo").String()
+	diffStrictOwnershipFlag = diffCommand.Flag(
+		"strict-ownership",
+		"Fail if resources in the cluster match but are not defined in the templates.",
+	).Bool()
+	diffFailIfEmptyFlag = diffCommand.Flag(
+		"fail-if-empty",
+		"Fail if neither the cluster nor the templates contain any resources, which usually indicates a misconfiguration.",
+	).Bool()
+	diffGitLabMRNoteFlag = diffCommand.Flag(
+		"gitlab-mr-note",
+		"Post a summary of the drift as note to the GitLab merge request of the CI pipeline. With best-effort, failing to post the note is only reported (best-effort or required).",
+	).PlaceHolder("best-effort").Enum("best-effort", "required")
+	diffCompareNamespaceFlag = diffCommand.Flag(
+		"compare-namespace",
+		"Compare the templates against each of given namespaces instead, reporting drift per namespace (repeatable or comma-separated).",
+	).PlaceHolder("foo-dev").Strings()
+	diffRemoteFileFlag = diffCommand.Flag(
+		"remote-file",
+		"Compare against resources in given file (e.g. a saved export) instead of the cluster.",
+	).PlaceHolder("snapshot.yml").String()
+	diffRemoteCmdFlag = diffCommand.Flag(
+		"remote-cmd",
+		"Compare against resources printed by given command (e.g. 'helm get manifest foo') instead of the cluster.",
+	).PlaceHolder("CMD").String()
+	diffWatchFlag = diffCommand.Flag(
+		"watch",
+		"Re-run the diff whenever a file in the template, param or patch directory changes.",
+	).Bool()
+	diffMaskSecretsFlag = diffCommand.Flag(
+		"mask-secrets",
+		"Do not name Secret resources in the output, but only report their number (unless --reveal-secrets is given).",
+	).Bool()
+	diffExplainFlag = diffCommand.Flag(
+		"explain",
+		"Describe why each change was detected.",
+	).Bool()
+	diffDiffFormatFlag = diffCommand.Flag(
+		"diff",
+		"Show drift as text diff, as JSON patch, or both (text, json or both).",
+	).PlaceHolder("text").Enum("text", "json", "both")
+	diffDiffOnlyPathFlag = diffCommand.Flag(
+		"diff-only-path",
+		"Only show drift at given path (repeatable or comma-separated). Changes without drift at any of the paths are hidden.",
+	).PlaceHolder("/spec/template/spec/containers").Strings()
+	diffServerDefaultsFlag = diffCommand.Flag(
+		"server-defaults",
+		"Apply server-side defaults to the desired state (via a server dry run) before comparing.",
+	).Bool()
+	diffPrintProcessedOnErrorFlag = diffCommand.Flag(
+		"print-processed-on-error",
+		"Print the processed output of a template which cannot be parsed into resources (only in combination with --debug).",
+	).Bool()
 	diffResourceArg = diffCommand.Arg(
 		"resource", "Remote resource (defaults to all)",
 	).String()
@@ -133,7 +336,15 @@ var (
 	).Alias("update")
 	applyLabelsFlag = applyCommand.Flag(
 		"labels",
-		"Label to set in all resources for this template.",
+		"Label to set in all resources for this template. Parameters (e.g. ${FOO}) are resolved.",
+	).String()
+	applyNamePrefixFlag = applyCommand.Flag(
+		"name-prefix",
+		"Prefix to prepend to the names of all resources in the templates (and references to them).",
+	).String()
+	applyNameSuffixFlag = applyCommand.Flag(
+		"name-suffix",
+		"Suffix to append to the names of all resources in the templates (and references to them).",
 	).String()
 	applyParamFlag = applyCommand.Flag(
 		"param",
@@ -143,6 +354,10 @@ var (
 		"param-file",
 		"File(s) containing template parameter values to set/override in the template.",
 	).Strings()
+	applyParamFilePrecedenceFlag = applyCommand.Flag(
+		"param-file-precedence",
+		"Which of multiple param files wins when they define the same parameter (first-wins or last-wins).",
+	).PlaceHolder("last-wins").Enum("first-wins", "last-wins")
 	applyIgnorePathFlag = applyCommand.Flag(
 		"ignore-path",
 		"DEPRECATED! Use --preserve instead.",
@@ -151,10 +366,42 @@ var (
 		"preserve",
 		"Path(s) per kind for which to preserve current state (e.g. because they are externally modified) in RFC 6901 format.",
 	).PlaceHolder("bc:foobar:/spec/output/to/name").Strings()
+	applyIdentityFlag = applyCommand.Flag(
+		"identity",
+		"Label per kind by which to match desired and current resources instead of by name.",
+	).PlaceHolder("job:app").Strings()
+	applyComparePolicyFlag = applyCommand.Flag(
+		"compare-policy",
+		"Policy per kind defining how resources are compared (ignore, spec-only or strict).",
+	).PlaceHolder("hpa:spec-only").Strings()
+	applyDiffOnlyFlag = applyCommand.Flag(
+		"diff-only",
+		"Resource(s) whose drift is reported but never applied.",
+	).PlaceHolder("cm/foo").Strings()
+	applyUnsetParamFlag = applyCommand.Flag(
+		"unset-param",
+		"Name(s) of params which should resolve to an empty value instead of their template default.",
+	).PlaceHolder("KEY").Strings()
+	applySensitiveParamFlag = applyCommand.Flag(
+		"sensitive-param",
+		"Name(s) of params whose values are masked in all output.",
+	).PlaceHolder("TOKEN").Strings()
 	applyPreserveImmutableFieldsFlag = applyCommand.Flag(
 		"preserve-immutable-fields",
 		"Preserve current state of all immutable fields (such as host of a route, or storageClassName of a PVC).",
 	).Bool()
+	applyDeprecatedAPIVersionFlag = applyCommand.Flag(
+		"deprecated-api-version",
+		"apiVersion(s) to warn about on top of apiVersions deprecated by default.",
+	).PlaceHolder("apps/v1beta1").Strings()
+	applyStrictParamConflictsFlag = applyCommand.Flag(
+		"strict-param-conflicts",
+		"Fail if a param is defined with different values in multiple param files.",
+	).Bool()
+	applyRequireParamFileFlag = applyCommand.Flag(
+		"require-param-file",
+		"Fail if no param file is found for a template (when param files are looked up in the param dir).",
+	).Bool()
 	applyIgnoreUnknownParametersFlag = applyCommand.Flag(
 		"ignore-unknown-parameters",
 		"If true, will not stop processing if a provided parameter does not exist in the template.",
@@ -167,6 +414,74 @@ var (
 		"allow-recreate",
 		"Allow to recreate the whole resource when an immutable field is changed.",
 	).Bool()
+	applyOnImmutableFlag = applyCommand.Flag(
+		"on-immutable",
+		"How to handle drift of immutable fields: fail, recreate the whole resource, or warn and leave the resource as-is (error, recreate or warn).",
+	).PlaceHolder("error").Enum("error", "recreate", "warn")
+	applyIgnoreWhitespaceFlag = applyCommand.Flag(
+		"ignore-whitespace",
+		"Ignore differences in trailing whitespace and line endings (CRLF vs. LF) of string fields.",
+	).Bool()
+	applyPruneFlag = applyCommand.Flag(
+		"prune",
+		"Delete resources carrying the prune label which are not defined in any template, regardless of the given kinds and selector.",
+	).Bool()
+	applyPruneLabelFlag = applyCommand.Flag(
+		"prune-label",
+		"Label identifying resources managed by Tailor for --prune (defaults to --selector).",
+	).PlaceHolder("app=foo").String()
+	applyPruneAgeFlag = applyCommand.Flag(
+		"prune-age",
+		"Only delete resources which have been created longer than given duration ago.",
+	).PlaceHolder("720h").Duration()
+	applyBatchSizeFlag = applyCommand.Flag(
+		"apply-batch-size",
+		"Apply changes in batches of given size (defaults to no batching).",
+	).PlaceHolder("20").Int()
+	applyBatchDelayFlag = applyCommand.Flag(
+		"apply-batch-delay",
+		"Wait given duration between batches (requires --apply-batch-size).",
+	).PlaceHolder("5s").Duration()
+	applyPreApplyHookFlag = applyCommand.Flag(
+		"pre-apply-hook",
+		"Shell command to run before changes are applied. Apply is aborted if it fails.",
+	).PlaceHolder("./scale-down.sh").String()
+	applyPostApplyHookFlag = applyCommand.Flag(
+		"post-apply-hook",
+		"Shell command to run after changes are applied. Failure is reported only.",
+	).PlaceHolder("./migrate.sh").String()
+	applyChangeIDFlag = applyCommand.Flag(
+		"change-id",
+		"Change (e.g. ticket) ID to record for each deletion, for traceability.",
+	).PlaceHolder("CHG-123").String()
+	applyAuditLogFlag = applyCommand.Flag(
+		"audit-log",
+		"File to append a JSON line to for each applied change, for traceability.",
+	).PlaceHolder("audit.log").String()
+	applyOnlyFlag = applyCommand.Flag(
+		"only",
+		"Apply only changes of given actions (create, update or delete; repeatable or comma-separated).",
+	).PlaceHolder("create").Strings()
+	applyResumeFlag = applyCommand.Flag(
+		"resume",
+		"Record applied changes in a checkpoint file, and skip changes recorded by a previous, failed run.",
+	).Bool()
+	applyAdoptFlag = applyCommand.Flag(
+		"adopt",
+		"Adopt resources which exist in the cluster but are not selected (e.g. as they lack the selector label) by applying their desired state as updates instead of failing to create them.",
+	).Bool()
+	applyFromExportFlag = applyCommand.Flag(
+		"from-export",
+		"Apply all resources of given template (e.g. an export from another namespace) as-is, without calculating drift.",
+	).PlaceHolder("template.yml").String()
+	applyApplyVerbFlag = applyCommand.Flag(
+		"apply-verb",
+		"oc verb (apply, create or replace) used to push resources of a kind to the cluster (repeatable or comma-separated, e.g. job:create).",
+	).PlaceHolder("job:create").Strings()
+	applyInSyncThresholdFlag = applyCommand.Flag(
+		"in-sync-threshold",
+		"Collapse in sync resources into one line if there are more than given number (defaults to listing all).",
+	).PlaceHolder("50").Int()
 	applyRevealSecretsFlag = applyCommand.Flag(
 		"reveal-secrets",
 		"Reveal drift of Secret resources (might show secret values in clear text).",
@@ -175,6 +490,58 @@ var (
 		"verify",
 		"Verify if resources are in sync after changes are applied.",
 	).Bool()
+	applyCheckResourceVersionFlag = applyCommand.Flag(
+		"check-resource-version",
+		"Abort if a resource has been modified since the changes were calculated.",
+	).Bool()
+	applyCreateNamespaceFlag = applyCommand.Flag(
+		"create-namespace",
+		"Create the project (namespace) if it does not exist yet.",
+	).Bool()
+	applyWaitForDeleteFlag = applyCommand.Flag(
+		"wait-for-delete",
+		"Wait (up to given duration) until deleted resources are gone before continuing.",
+	).PlaceHolder("2m").Duration()
+	applyDryRunFlag = applyCommand.Flag(
+		"dry-run",
+		"Let the server validate the changes (including admission control) without persisting them. Only \"server\" is supported.",
+	).PlaceHolder("server").String()
+	applyParamsHashFlag = applyCommand.Flag(
+		"params-hash",
+		"Annotate pod templates of workloads with a hash of all params, so that they roll out when any param changes.",
+	).Bool()
+	applyTemplateHashFlag = applyCommand.Flag(
+		"template-hash",
+		"Annotate resources with a hash of the template source they are generated from.",
+	).Bool()
+	applyMaskSecretsFlag = applyCommand.Flag(
+		"mask-secrets",
+		"Do not name Secret resources in the output, but only report their number (unless --reveal-secrets is given).",
+	).Bool()
+	applyExplainFlag = applyCommand.Flag(
+		"explain",
+		"Describe why each change was detected.",
+	).Bool()
+	applyEventsFlag = applyCommand.Flag(
+		"events",
+		"Emit newline-delimited JSON events on STDOUT instead of the human-readable output (requires --non-interactive).",
+	).Bool()
+	applyDiffFormatFlag = applyCommand.Flag(
+		"diff",
+		"Show drift as text diff, as JSON patch, or both (text, json or both).",
+	).PlaceHolder("text").Enum("text", "json", "both")
+	applyDiffOnlyPathFlag = applyCommand.Flag(
+		"diff-only-path",
+		"Only show drift at given path (repeatable or comma-separated). Changes without drift at any of the paths are hidden, but still applied.",
+	).PlaceHolder("/spec/template/spec/containers").Strings()
+	applyServerDefaultsFlag = applyCommand.Flag(
+		"server-defaults",
+		"Apply server-side defaults to the desired state (via a server dry run) before comparing.",
+	).Bool()
+	applyPrintProcessedOnErrorFlag = applyCommand.Flag(
+		"print-processed-on-error",
+		"Print the processed output of a template which cannot be parsed into resources (only in combination with --debug).",
+	).Bool()
 	applyResourceArg = applyCommand.Arg(
 		"resource", "Remote resource (defaults to all)",
 	).String()
@@ -195,10 +562,125 @@ var (
 		"trim-annotation",
 		"Annotation (prefix) to trim on top of annotations trimmed by default. ",
 	).PlaceHolder("template.openshift.io/").Strings()
+	exportAnnotationPrefixFlag = exportCommand.Flag(
+		"annotation-prefix",
+		"Annotation prefix to keep. If given, all other annotations are trimmed.",
+	).PlaceHolder("app.example.com/").Strings()
+	exportStripPathFlag = exportCommand.Flag(
+		"strip-path",
+		"Path(s) per kind/name to remove from exported resources in RFC 6901 format.",
+	).PlaceHolder("route:/spec/host").Strings()
+	exportWriteFlag = exportCommand.Flag(
+		"write",
+		"Write template into template directory instead of STDOUT.",
+	).Bool()
+	exportFormatFlag = exportCommand.Flag(
+		"format",
+		"Output format: OpenShift template or plain multi-document YAML (template or list).",
+	).PlaceHolder("template").Enum("template", "list")
+	exportGroupByLabelFlag = exportCommand.Flag(
+		"group-by-label",
+		"Export one template per distinct value of given label.",
+	).PlaceHolder("app").String()
 	exportResourceArg = exportCommand.Arg(
 		"resource", "Remote resource (defaults to all)",
 	).String()
 
+	listCommand = app.Command(
+		"list",
+		"List resources managed by the templates (without contacting the cluster)",
+	)
+	listParamFlag = listCommand.Flag(
+		"param",
+		"Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template.",
+	).Strings()
+	listParamFileFlag = listCommand.Flag(
+		"param-file",
+		"File(s) containing template parameter values to set/override in the template.",
+	).Strings()
+	listNamePrefixFlag = listCommand.Flag(
+		"name-prefix",
+		"Prefix to prepend to the names of all resources in the templates (and references to them).",
+	).String()
+	listNameSuffixFlag = listCommand.Flag(
+		"name-suffix",
+		"Suffix to append to the names of all resources in the templates (and references to them).",
+	).String()
+	listIgnoreUnknownParametersFlag = listCommand.Flag(
+		"ignore-unknown-parameters",
+		"If true, will not stop processing if a provided parameter does not exist in the template.",
+	).Bool()
+	listOutputFlag = listCommand.Flag(
+		"output",
+		"Output format (text or json).",
+	).Short('o').Default("text").Enum("text", "json")
+	listResourceArg = listCommand.Arg(
+		"resource", "Resource kind(s) to list (defaults to all)",
+	).String()
+
+	diffParamsCommand = app.Command(
+		"diff-params",
+		"Compare the templates rendered with two param files (without contacting the cluster)",
+	)
+	diffParamsParamFlag = diffParamsCommand.Flag(
+		"param",
+		"Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template (for both renderings).",
+	).Strings()
+	diffParamsIgnoreUnknownParametersFlag = diffParamsCommand.Flag(
+		"ignore-unknown-parameters",
+		"If true, will not stop processing if a provided parameter does not exist in the template.",
+	).Bool()
+	diffParamsRevealSecretsFlag = diffParamsCommand.Flag(
+		"reveal-secrets",
+		"Reveal drift of Secret resources (might show secret values in clear text).",
+	).Bool()
+	diffParamsParamFileAArg = diffParamsCommand.Arg(
+		"param-file-a", "File containing the first set of param values",
+	).Required().String()
+	diffParamsParamFileBArg = diffParamsCommand.Arg(
+		"param-file-b", "File containing the second set of param values",
+	).Required().String()
+
+	relabelCommand = app.Command(
+		"relabel",
+		"Move resources from one management label to another",
+	)
+	relabelFromFlag = relabelCommand.Flag(
+		"from",
+		"Label (key=value) currently carried by the resources.",
+	).Required().PlaceHolder("app=foo").String()
+	relabelToFlag = relabelCommand.Flag(
+		"to",
+		"Label (key=value) to move the resources to.",
+	).Required().PlaceHolder("app.example.com/name=foo").String()
+	relabelResourceArg = relabelCommand.Arg(
+		"resource", "Resource kind(s) to relabel (defaults to all)",
+	).String()
+
+	diffOcCommand = app.Command(
+		"diff-oc",
+		"Compare the templates rendered with the configured oc binary and with another one (without contacting the cluster)",
+	)
+	diffOcParamFlag = diffOcCommand.Flag(
+		"param",
+		"Specify a key-value pair (eg. -p FOO=BAR) to set/override a parameter value in the template.",
+	).Strings()
+	diffOcParamFileFlag = diffOcCommand.Flag(
+		"param-file",
+		"File(s) containing template parameter values to set/override in the template.",
+	).Strings()
+	diffOcIgnoreUnknownParametersFlag = diffOcCommand.Flag(
+		"ignore-unknown-parameters",
+		"If true, will not stop processing if a provided parameter does not exist in the template.",
+	).Bool()
+	diffOcRevealSecretsFlag = diffOcCommand.Flag(
+		"reveal-secrets",
+		"Reveal differences of Secret resources (might show secret values in clear text).",
+	).Bool()
+	diffOcOtherOcBinaryArg = diffOcCommand.Arg(
+		"other-oc-binary", "oc binary to compare the rendering of --oc-binary with (e.g. a newer version)",
+	).Required().String()
+
 	secretsCommand = app.Command(
 		"secrets",
 		"Work with secrets",
@@ -215,6 +697,10 @@ var (
 		"re-encrypt",
 		"Re-Encrypt param file(s)",
 	)
+	reEncryptCheckFlag = reEncryptCommand.Flag(
+		"check",
+		"Only report which files would change, without writing them.",
+	).Bool()
 	reEncryptFileArg = reEncryptCommand.Arg(
 		"file", "File to re-encrypt",
 	).String()
@@ -223,10 +709,33 @@ var (
 		"reveal",
 		"Show param file contents with revealed secrets",
 	)
+	revealMaskFlag = revealCommand.Flag(
+		"mask",
+		"Mask all but the last few characters of each value.",
+	).Bool()
+	revealOutputFlag = revealCommand.Flag(
+		"output",
+		"Output format (text or json). JSON maps each param to its value.",
+	).Short('o').Default("text").Enum("text", "json")
 	revealFileArg = revealCommand.Arg(
 		"file", "File to show",
 	).Required().String()
 
+	secretsDiffCommand = secretsCommand.Command(
+		"diff",
+		"Show which params differ between two param files",
+	)
+	secretsDiffRevealFlag = secretsDiffCommand.Flag(
+		"reveal",
+		"Show the values of differing params in clear text.",
+	).Bool()
+	secretsDiffFromArg = secretsDiffCommand.Arg(
+		"from", "File to compare from",
+	).Required().String()
+	secretsDiffToArg = secretsDiffCommand.Arg(
+		"to", "File to compare to",
+	).Required().String()
+
 	generateKeyCommand = secretsCommand.Command(
 		"generate-key",
 		"Generate new keypair",
@@ -251,15 +760,48 @@ func main() {
 	command := kingpin.MustParse(app.Parse(os.Args[1:]))
 
 	if command == versionCommand.FullCommand() {
-		fmt.Println("1.1.4+master")
+		fmt.Println(version)
+		if *versionCheckFlag {
+			commands.CheckVersion(os.Stdout, version)
+		}
+		return
+	}
+
+	err := run(command)
+	if err == nil {
 		return
 	}
+	// A command aborted because the timeout is exceeded may report a
+	// consequence (e.g. a killed oc process) instead of the timeout.
+	var timeoutErr *cli.TimeoutError
+	if errors.As(err, &timeoutErr) || errors.As(cli.RunContextErr(), &timeoutErr) {
+		err = timeoutErr
+	}
+	code := exitCode(err)
+	switch code {
+	case exitCodeDriftDetected:
+		// The drift has been reported by the command already.
+	case exitCodeTimeout:
+		cli.PrintRedf("\n%s.\n", err)
+	default:
+		log.Println(cli.Redact(err.Error()))
+	}
+	os.Exit(code)
+}
 
+// run executes command. Deferred cleanup (e.g. of the run context) happens
+// before run returns, so that Tailor can exit afterwards.
+func run(command string) error {
 	clusterRequired := true
 	if command == editCommand.FullCommand() ||
 		command == revealCommand.FullCommand() ||
+		command == secretsDiffCommand.FullCommand() ||
 		command == reEncryptCommand.FullCommand() ||
-		command == generateKeyCommand.FullCommand() {
+		command == generateKeyCommand.FullCommand() ||
+		command == listCommand.FullCommand() ||
+		command == diffParamsCommand.FullCommand() ||
+		command == diffOcCommand.FullCommand() ||
+		(command == diffCommand.FullCommand() && (len(*diffRemoteFileFlag) > 0 || len(*diffRemoteCmdFlag) > 0)) {
 		clusterRequired = false
 	}
 
@@ -271,10 +813,13 @@ func main() {
 		*nonInteractiveFlag,
 		*ocBinaryFlag,
 		*forceFlag,
+		*timeoutFlag,
+		*tokenRefreshCommandFlag,
 	)
 	if err != nil {
-		log.Fatalln("Options could not be processed:", err)
+		return fmt.Errorf("Options could not be processed: %w", err)
 	}
+	defer cli.CancelRunContext()
 
 	switch command {
 	case editCommand.FullCommand():
@@ -284,13 +829,14 @@ func main() {
 			*publicKeyDirFlag,
 			*privateKeyFlag,
 			*passphraseFlag,
+			*passphraseFileFlag,
 		)
 		if err != nil {
-			log.Fatalln("Options could not be processed:", err)
+			return fmt.Errorf("Options could not be processed: %w", err)
 		}
 		err = commands.Edit(secretsOptions, *editFileArg)
 		if err != nil {
-			log.Fatalf("Failed to edit file: %s.", err)
+			return fmt.Errorf("Failed to edit file: %w.", err)
 		}
 
 	case reEncryptCommand.FullCommand():
@@ -300,13 +846,24 @@ func main() {
 			*publicKeyDirFlag,
 			*privateKeyFlag,
 			*passphraseFlag,
+			*passphraseFileFlag,
 		)
 		if err != nil {
-			log.Fatalln("Options could not be processed:", err)
+			return fmt.Errorf("Options could not be processed: %w", err)
+		}
+		if *reEncryptCheckFlag {
+			changesRequired, err := commands.CheckReEncrypt(secretsOptions, *reEncryptFileArg)
+			if err != nil {
+				return fmt.Errorf("Failed to check re-encryption: %w.", err)
+			}
+			if changesRequired {
+				return &openshift.DriftDetectedError{}
+			}
+			return nil
 		}
-		err = commands.ReEncrypt(secretsOptions, *reEncryptFileArg)
+		err = commands.ReEncrypt(secretsOptions, *reEncryptFileArg, os.Stdin)
 		if err != nil {
-			log.Fatalf("Failed to re-encrypt: %s.", err)
+			return fmt.Errorf("Failed to re-encrypt: %w.", err)
 		}
 
 	case revealCommand.FullCommand():
@@ -316,13 +873,34 @@ func main() {
 			*publicKeyDirFlag,
 			*privateKeyFlag,
 			*passphraseFlag,
+			*passphraseFileFlag,
 		)
 		if err != nil {
-			log.Fatalln("Options could not be processed:", err)
+			return fmt.Errorf("Options could not be processed: %w", err)
 		}
-		err = commands.Reveal(secretsOptions, *revealFileArg)
+		err = commands.Reveal(secretsOptions, *revealFileArg, *revealMaskFlag, *revealOutputFlag)
 		if err != nil {
-			log.Fatalf("Failed to reveal file: %s.", err)
+			return fmt.Errorf("Failed to reveal file: %w.", err)
+		}
+
+	case secretsDiffCommand.FullCommand():
+		secretsOptions, err := cli.NewSecretsOptions(
+			globalOptions,
+			*paramDirFlag,
+			*publicKeyDirFlag,
+			*privateKeyFlag,
+			*passphraseFlag,
+			*passphraseFileFlag,
+		)
+		if err != nil {
+			return fmt.Errorf("Options could not be processed: %w", err)
+		}
+		differencesFound, err := commands.DiffSecrets(secretsOptions, *secretsDiffFromArg, *secretsDiffToArg, *secretsDiffRevealFlag)
+		if err != nil {
+			return fmt.Errorf("Failed to compare files: %w.", err)
+		}
+		if differencesFound {
+			return &openshift.DriftDetectedError{}
 		}
 
 	case generateKeyCommand.FullCommand():
@@ -332,13 +910,14 @@ func main() {
 			*publicKeyDirFlag,
 			*privateKeyFlag,
 			*passphraseFlag,
+			*passphraseFileFlag,
 		)
 		if err != nil {
-			log.Fatalln("Options could not be processed:", err)
+			return fmt.Errorf("Options could not be processed: %w", err)
 		}
 		err = commands.GenerateKey(secretsOptions, *generateKeyEmailArg, *generateKeyNameFlag)
 		if err != nil {
-			log.Fatalf("Failed to generate keypair: %s.", err)
+			return fmt.Errorf("Failed to generate keypair: %w.", err)
 		}
 
 	case diffCommand.FullCommand():
@@ -349,33 +928,98 @@ func main() {
 			*namespaceFlag,
 			*selectorFlag,
 			*excludeFlag,
+			*apiGroupFlag,
+			*excludeAPIGroupFlag,
+			*excludeFieldManagerFlag,
+			*onlyKindsFlag,
 			*templateDirFlag,
+			*recursiveFlag,
+			*ignoreFileFlag,
 			*paramDirFlag,
+			*patchDirFlag,
 			*publicKeyDirFlag,
 			*privateKeyFlag,
 			*passphraseFlag,
+			*passphraseFileFlag,
 			*diffLabelsFlag,
+			*diffNamePrefixFlag,
+			*diffNameSuffixFlag,
 			*diffParamFlag,
 			*diffParamFileFlag,
+			*diffParamFilePrecedenceFlag,
 			preservePathFlag,
+			*diffIdentityFlag,
+			*diffComparePolicyFlag,
+			*diffDiffOnlyFlag,
+			*diffSensitiveParamFlag,
+			*diffUnsetParamFlag,
 			*diffPreserveImmutableFieldsFlag,
+			*diffDeprecatedAPIVersionFlag,
 			*diffIgnoreUnknownParametersFlag,
+			*diffStrictParamConflictsFlag,
+			*diffRequireParamFileFlag,
 			*diffUpsertOnlyFlag,
 			*diffAllowRecreateFlag,
+			*diffOnImmutableFlag,
+			*diffIgnoreWhitespaceFlag,
 			*diffRevealSecretsFlag,
+			*diffMaskSecretsFlag,
+			*diffExplainFlag,
+			*diffPrintProcessedOnErrorFlag,
+			*diffServerDefaultsFlag,
 			false, // verification only when changes are applied
+			false, // resource version only checked when changes are applied
+			false, // namespace can only be created by apply
+			*diffTemplateHashFlag,
+			*diffParamsHashFlag,
+			0, // waiting only when changes are applied
+			*diffPruneAgeFlag,
+			*diffPruneFlag,
+			*diffPruneLabelFlag,
+			0,          // batching only when changes are applied
+			0,          // batching only when changes are applied
+			"",         // hooks only run when changes are applied
+			"",         // hooks only run when changes are applied
+			"",         // change ID only recorded when changes are applied
+			"",         // audit log only written when changes are applied
+			[]string{}, // all changes are shown by diff
+			false,      // only apply can resume
+			*diffAdoptFlag,
+			[]string{}, // verbs only matter when changes are applied
+			*diffOutputFlag,
+			*diffEventsFlag,
+			*diffDiffFormatFlag,
+			*diffDiffOnlyPathFlag,
+			*diffSummaryOnlyFlag,
+			*diffInSyncThresholdFlag,
+			*diffShowDesiredFlag,
+			*diffStrictOwnershipFlag,
+			*diffFailIfEmptyFlag,
+			*diffGitLabMRNoteFlag,
+			*diffCompareNamespaceFlag,
+			*diffRemoteFileFlag,
+			*diffRemoteCmdFlag,
+			"", // dry run only when changes are applied
 			*diffResourceArg,
 		)
 		if err != nil {
-			log.Fatalln("Options could not be processed:", err)
+			return fmt.Errorf("Options could not be processed: %w", err)
+		}
+
+		if *diffWatchFlag {
+			err := commands.WatchDiff(compareOptions)
+			if err != nil {
+				return err
+			}
+			return nil
 		}
 
 		driftDectected, err := commands.Diff(compareOptions)
 		if err != nil {
-			log.Fatalln(err)
+			return err
 		}
 		if driftDectected {
-			os.Exit(3)
+			return &openshift.DriftDetectedError{}
 		}
 
 	case applyCommand.FullCommand():
@@ -386,28 +1030,98 @@ func main() {
 			*namespaceFlag,
 			*selectorFlag,
 			*excludeFlag,
+			*apiGroupFlag,
+			*excludeAPIGroupFlag,
+			*excludeFieldManagerFlag,
+			*onlyKindsFlag,
 			*templateDirFlag,
+			*recursiveFlag,
+			*ignoreFileFlag,
 			*paramDirFlag,
+			*patchDirFlag,
 			*publicKeyDirFlag,
 			*privateKeyFlag,
 			*passphraseFlag,
+			*passphraseFileFlag,
 			*applyLabelsFlag,
+			*applyNamePrefixFlag,
+			*applyNameSuffixFlag,
 			*applyParamFlag,
 			*applyParamFileFlag,
+			*applyParamFilePrecedenceFlag,
 			preservePathFlag,
+			*applyIdentityFlag,
+			*applyComparePolicyFlag,
+			*applyDiffOnlyFlag,
+			*applySensitiveParamFlag,
+			*applyUnsetParamFlag,
 			*applyPreserveImmutableFieldsFlag,
+			*applyDeprecatedAPIVersionFlag,
 			*applyIgnoreUnknownParametersFlag,
+			*applyStrictParamConflictsFlag,
+			*applyRequireParamFileFlag,
 			*applyUpsertOnlyFlag,
 			*applyAllowRecreateFlag,
+			*applyOnImmutableFlag,
+			*applyIgnoreWhitespaceFlag,
 			*applyRevealSecretsFlag,
+			*applyMaskSecretsFlag,
+			*applyExplainFlag,
+			*applyPrintProcessedOnErrorFlag,
+			*applyServerDefaultsFlag,
 			*applyVerifyFlag,
+			*applyCheckResourceVersionFlag,
+			*applyCreateNamespaceFlag,
+			*applyTemplateHashFlag,
+			*applyParamsHashFlag,
+			*applyWaitForDeleteFlag,
+			*applyPruneAgeFlag,
+			*applyPruneFlag,
+			*applyPruneLabelFlag,
+			*applyBatchSizeFlag,
+			*applyBatchDelayFlag,
+			*applyPreApplyHookFlag,
+			*applyPostApplyHookFlag,
+			*applyChangeIDFlag,
+			*applyAuditLogFlag,
+			*applyOnlyFlag,
+			*applyResumeFlag,
+			*applyAdoptFlag,
+			*applyApplyVerbFlag,
+			"text", // apply always prints text
+			*applyEventsFlag,
+			*applyDiffFormatFlag,
+			*applyDiffOnlyPathFlag,
+			false, // apply always prints the full drift
+			*applyInSyncThresholdFlag,
+			"",         // showing desired state is only supported by diff
+			false,      // ownership is only enforced by diff
+			false,      // empty state is only guarded against by diff
+			"",         // only diff posts merge request notes
+			[]string{}, // apply targets exactly one namespace
+			"",         // apply always compares against the cluster
+			"",         // apply always compares against the cluster
+			*applyDryRunFlag,
 			*applyResourceArg,
 		)
 		if err != nil {
-			log.Fatalln("Options could not be processed:", err)
+			return fmt.Errorf("Options could not be processed: %w", err)
 		}
 
 		ocClient := cli.NewOcClient(compareOptions.Namespace)
+		if len(*applyFromExportFlag) > 0 {
+			err := commands.ApplyFromExport(
+				globalOptions.NonInteractive,
+				compareOptions,
+				*applyFromExportFlag,
+				ocClient,
+				os.Stdin,
+			)
+			if err != nil {
+				return err
+			}
+			return nil
+		}
 		driftDectected, err := commands.Apply(
 			globalOptions.NonInteractive,
 			compareOptions,
@@ -415,10 +1129,10 @@ func main() {
 			os.Stdin,
 		)
 		if err != nil {
-			log.Fatalln(err)
+			return err
 		}
 		if driftDectected {
-			os.Exit(3)
+			return &openshift.DriftDetectedError{}
 		}
 
 	case exportCommand.FullCommand():
@@ -427,19 +1141,180 @@ func main() {
 			*namespaceFlag,
 			*selectorFlag,
 			*excludeFlag,
+			*apiGroupFlag,
+			*excludeAPIGroupFlag,
+			*excludeFieldManagerFlag,
+			*onlyKindsFlag,
 			*templateDirFlag,
 			*paramDirFlag,
 			*exportWithAnnotationsFlag,
 			*exportWithHardcodedNamespaceFlag,
 			*exportTrimAnnotationFlag,
+			*exportAnnotationPrefixFlag,
+			*exportStripPathFlag,
+			*exportWriteFlag,
+			*exportFormatFlag,
+			*exportGroupByLabelFlag,
 			*exportResourceArg,
 		)
 		if err != nil {
-			log.Fatalln("Options could not be processed:", err)
+			return fmt.Errorf("Options could not be processed: %w", err)
 		}
 		err = commands.Export(exportOptions)
 		if err != nil {
-			log.Fatalln(err)
+			return err
+		}
+
+	case relabelCommand.FullCommand():
+		relabelOptions, err := cli.NewRelabelOptions(
+			globalOptions,
+			*namespaceFlag,
+			*relabelFromFlag,
+			*relabelToFlag,
+			*excludeFlag,
+			*relabelResourceArg,
+		)
+		if err != nil {
+			return fmt.Errorf("Options could not be processed: %w", err)
+		}
+		ocClient := cli.NewOcClient(relabelOptions.Namespace)
+		err = commands.Relabel(relabelOptions, ocClient, os.Stdin)
+		if err != nil {
+			return err
+		}
+
+	case listCommand.FullCommand():
+		compareOptions, err := cli.NewRenderOptions(
+			globalOptions,
+			*namespaceFlag,
+			*selectorFlag,
+			*excludeFlag,
+			*apiGroupFlag,
+			*excludeAPIGroupFlag,
+			*excludeFieldManagerFlag,
+			*onlyKindsFlag,
+			*templateDirFlag,
+			*recursiveFlag,
+			*ignoreFileFlag,
+			*paramDirFlag,
+			*patchDirFlag,
+			*publicKeyDirFlag,
+			*privateKeyFlag,
+			*passphraseFlag,
+			*passphraseFileFlag,
+			*listNamePrefixFlag,
+			*listNameSuffixFlag,
+			*listParamFlag,
+			*listParamFileFlag,
+			*listIgnoreUnknownParametersFlag,
+			false, // secrets are not shown
+			*listResourceArg,
+		)
+		if err != nil {
+			return fmt.Errorf("Options could not be processed: %w", err)
+		}
+		err = commands.List(compareOptions, *listOutputFlag)
+		if err != nil {
+			return err
+		}
+	case diffParamsCommand.FullCommand():
+		compareOptions, err := cli.NewRenderOptions(
+			globalOptions,
+			*namespaceFlag,
+			*selectorFlag,
+			*excludeFlag,
+			*apiGroupFlag,
+			*excludeAPIGroupFlag,
+			*excludeFieldManagerFlag,
+			*onlyKindsFlag,
+			*templateDirFlag,
+			*recursiveFlag,
+			*ignoreFileFlag,
+			*paramDirFlag,
+			*patchDirFlag,
+			*publicKeyDirFlag,
+			*privateKeyFlag,
+			*passphraseFlag,
+			*passphraseFileFlag,
+			"", // name prefix is the same for both renderings
+			"", // name suffix is the same for both renderings
+			*diffParamsParamFlag,
+			[]string{}, // param files are given as arguments
+			*diffParamsIgnoreUnknownParametersFlag,
+			*diffParamsRevealSecretsFlag,
+			"", // all resources are rendered
+		)
+		if err != nil {
+			return fmt.Errorf("Options could not be processed: %w", err)
+		}
+		differencesDetected, err := commands.DiffParams(
+			compareOptions,
+			*diffParamsParamFileAArg,
+			*diffParamsParamFileBArg,
+		)
+		if err != nil {
+			return err
+		}
+		if differencesDetected {
+			return &openshift.DriftDetectedError{}
 		}
+
+	case diffOcCommand.FullCommand():
+		compareOptions, err := cli.NewRenderOptions(
+			globalOptions,
+			*namespaceFlag,
+			*selectorFlag,
+			*excludeFlag,
+			*apiGroupFlag,
+			*excludeAPIGroupFlag,
+			*excludeFieldManagerFlag,
+			*onlyKindsFlag,
+			*templateDirFlag,
+			*recursiveFlag,
+			*ignoreFileFlag,
+			*paramDirFlag,
+			*patchDirFlag,
+			*publicKeyDirFlag,
+			*privateKeyFlag,
+			*passphraseFlag,
+			*passphraseFileFlag,
+			"", // name prefix is the same for both renderings
+			"", // name suffix is the same for both renderings
+			*diffOcParamFlag,
+			*diffOcParamFileFlag,
+			*diffOcIgnoreUnknownParametersFlag,
+			*diffOcRevealSecretsFlag,
+			"", // all resources are rendered
+		)
+		if err != nil {
+			return fmt.Errorf("Options could not be processed: %w", err)
+		}
+		differencesDetected, err := commands.DiffOcBinaries(
+			compareOptions,
+			*diffOcOtherOcBinaryArg,
+		)
+		if err != nil {
+			return err
+		}
+		if differencesDetected {
+			return &openshift.DriftDetectedError{}
+		}
+	}
+	return nil
+}
+
+// exitCode maps the error returned by run to the exit code of Tailor.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var driftErr *openshift.DriftDetectedError
+	if errors.As(err, &driftErr) {
+		return exitCodeDriftDetected
+	}
+	var timeoutErr *cli.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return exitCodeTimeout
 	}
+	return exitCodeError
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want int
+	}{
+		"success": {
+			err:  nil,
+			want: 0,
+		},
+		"drift detected": {
+			err:  &openshift.DriftDetectedError{},
+			want: 3,
+		},
+		"timeout exceeded": {
+			err:  &cli.TimeoutError{Timeout: time.Minute},
+			want: 4,
+		},
+		"wrapped timeout": {
+			err:  fmt.Errorf("Apply aborted: %w", &cli.TimeoutError{Timeout: time.Minute}),
+			want: 4,
+		},
+		"typed error": {
+			err:  &openshift.ExportError{Target: "dc", Err: errors.New("Unauthorized")},
+			want: 1,
+		},
+		"plain error": {
+			err:  errors.New("foo"),
+			want: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := exitCode(tc.err); got != tc.want {
+				t.Fatalf("Want exit code %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
@@ -3,20 +3,39 @@ package cli
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 var verbose bool
 var debug bool
 var ocBinary string
+var tokenRefreshCommand string
+
+// runContext is used for all commands executed by Tailor. It carries the
+// deadline of the whole run if a timeout is set.
+var runContext = context.Background()
+var cancelRunContext = func() {}
+var runTimeout time.Duration
+
+// TimeoutError is returned once the timeout of the run is exceeded.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("Aborting as timeout of %s is exceeded", e.Timeout)
+}
 
 // PrintGreenf prints in green.
 var PrintGreenf func(format string, a ...interface{})
@@ -59,17 +78,23 @@ func init() {
 // Verbose mode is implicitly turned on when debug mode is on.
 func VerboseMsg(messages ...string) {
 	if verbose {
-		PrintBluef("--> %s\n", strings.Join(messages, " "))
+		PrintBluef("--> %s\n", Redact(strings.Join(messages, " ")))
 	}
 }
 
 // DebugMsg prints given message when debug mode is on.
 func DebugMsg(messages ...string) {
 	if debug {
-		PrintBluef("--> %s\n", strings.Join(messages, " "))
+		PrintBluef("--> %s\n", Redact(strings.Join(messages, " ")))
 	}
 }
 
+// DebugEnabled returns true when debug mode is on. It allows to skip
+// assembling expensive debug output.
+func DebugEnabled() bool {
+	return debug
+}
+
 // ExecOcCmd executes "oc" with given namespace and selector applied.
 func ExecOcCmd(args []string, namespace string, selector string) *exec.Cmd {
 	if len(namespace) > 0 {
@@ -99,16 +124,65 @@ func RunCmd(cmd *exec.Cmd) (outBytes, errBytes []byte, err error) {
 
 func execCmd(executable string, args []string) *exec.Cmd {
 	VerboseMsg(executable + " " + strings.Join(args, " "))
-	return exec.Command(executable, args...)
+	return exec.CommandContext(runContext, executable, args...)
+}
+
+// InitRunContext limits the duration of the run to timeout. A timeout of
+// zero means no limit.
+func InitRunContext(timeout time.Duration) {
+	cancelRunContext()
+	runTimeout = timeout
+	if timeout > 0 {
+		runContext, cancelRunContext = context.WithTimeout(context.Background(), timeout)
+	} else {
+		runContext, cancelRunContext = context.Background(), func() {}
+	}
+}
+
+// CancelRunContext releases resources associated with the run context.
+func CancelRunContext() {
+	cancelRunContext()
+}
+
+// RunContext returns the context of the current run, which is done once the
+// timeout (if any) is exceeded.
+func RunContext() context.Context {
+	return runContext
+}
+
+// RunContextErr returns a *TimeoutError if the timeout of the run is
+// exceeded, the error of the run context if it is done otherwise, and nil if
+// the run may continue.
+func RunContextErr() error {
+	err := runContext.Err()
+	if err == context.DeadlineExceeded {
+		return &TimeoutError{Timeout: runTimeout}
+	}
+	return err
+}
+
+// PromptPassphrase asks the user for a passphrase, without echoing the input.
+func PromptPassphrase(question string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", errors.New("Cannot prompt for passphrase as STDIN is not a terminal")
+	}
+	fmt.Printf("%s: ", question)
+	b, err := terminal.ReadPassword(fd)
+	fmt.Println("")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
 }
 
 // AskForAction asks the user the given question. A user must type in one of the presented options and
 // then press enter.If the input is not recognized, it will ask again. The function does not return
-// until it gets a valid response from the user.
+// until it gets a valid response from the user, the input ends or the run context is done.
 // Options are of form "y=yes". The matching is fuzzy, which means allowed values are
 // "y", "Y", "yes", "YES", "Yes" and so on. The returned value is always the "key" ("y" in this case),
 // regardless if the input was "y" or "yes" etc.
-func AskForAction(question string, options []string, reader *bufio.Reader) string {
+func AskForAction(question string, options []string, reader *bufio.Reader) (string, error) {
 	validAnswers := map[string]string{}
 	for _, v := range options {
 		p := strings.Split(v, "=")
@@ -119,9 +193,9 @@ func AskForAction(question string, options []string, reader *bufio.Reader) strin
 	for {
 		fmt.Printf("%s [%s]: ", question, strings.Join(options, ", "))
 
-		answer, err := reader.ReadString('\n')
+		answer, err := readLine(reader)
 		if err != nil {
-			log.Fatal(err)
+			return "", err
 		}
 
 		answer = strings.ToLower(strings.TrimSpace(answer))
@@ -129,11 +203,33 @@ func AskForAction(question string, options []string, reader *bufio.Reader) strin
 		if v, ok := validAnswers[answer]; !ok {
 			fmt.Printf("'%s' is not a valid option. Please try again.\n", answer)
 		} else {
-			return v
+			return v, nil
 		}
 	}
 }
 
+// readLine reads up to and including the next newline from reader. It gives
+// up once the run context is done, e.g. because the timeout is exceeded
+// while waiting for the user.
+func readLine(reader *bufio.Reader) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	c := make(chan result, 1)
+	go func() {
+		line, err := reader.ReadString('\n')
+		c <- result{line: line, err: err}
+	}()
+	select {
+	case r := <-c:
+		return r.line, r.err
+	case <-runContext.Done():
+		fmt.Println("")
+		return "", RunContextErr()
+	}
+}
+
 // EditEnvFile opens content in EDITOR, and returns saved content.
 func EditEnvFile(content string) (string, error) {
 	err := ioutil.WriteFile(".ENV.DEC", []byte(content), 0644)
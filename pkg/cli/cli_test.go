@@ -3,7 +3,10 @@ package cli
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"io"
 	"testing"
+	"time"
 )
 
 func TestAskForAction(t *testing.T) {
@@ -54,10 +57,47 @@ func TestAskForAction(t *testing.T) {
 			var stdin bytes.Buffer
 			stdin.Write([]byte(tc.input))
 			stdinReader := bufio.NewReader(&stdin)
-			a := AskForAction("What?", tc.options, stdinReader)
+			a, err := AskForAction("What?", tc.options, stdinReader)
+			if err != nil {
+				t.Fatal(err)
+			}
 			if a != tc.expectedAnswer {
 				t.Fatalf("Want: '%s', got: '%s'", tc.expectedAnswer, a)
 			}
 		})
 	}
 }
+
+func TestAskForActionTimeout(t *testing.T) {
+	InitRunContext(10 * time.Millisecond)
+	defer InitRunContext(0)
+	// The pipe is never written to, so only the timeout can end the prompt.
+	stdin, w := io.Pipe()
+	defer w.Close()
+	_, err := AskForAction("What?", []string{"y=yes", "n=no"}, bufio.NewReader(stdin))
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Want TimeoutError, got: %v", err)
+	}
+	if timeoutErr.Timeout != 10*time.Millisecond {
+		t.Fatalf("Want timeout of 10ms, got %s", timeoutErr.Timeout)
+	}
+}
+
+func TestRunContextErr(t *testing.T) {
+	InitRunContext(0)
+	if err := RunContextErr(); err != nil {
+		t.Fatalf("Want no error without timeout, got: %v", err)
+	}
+	InitRunContext(time.Hour)
+	defer InitRunContext(0)
+	if err := RunContextErr(); err != nil {
+		t.Fatalf("Want no error before timeout is exceeded, got: %v", err)
+	}
+	InitRunContext(time.Nanosecond)
+	<-RunContext().Done()
+	err := RunContextErr()
+	if err == nil || err.Error() != "Aborting as timeout of 1ns is exceeded" {
+		t.Fatalf("Want timeout error, got: %v", err)
+	}
+}
@@ -2,27 +2,50 @@ package cli
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os/exec"
 	"strings"
 )
 
+// ErrSessionExpired is returned when an oc command fails because the session
+// (token) is not valid anymore.
+var ErrSessionExpired = errors.New("OpenShift session expired, please re-login (oc login)")
+
+var sessionExpiredMessages = []string{
+	"You must be logged in to the server",
+	"the server has asked for the client to provide credentials",
+	"error: Unauthorized",
+	"token has expired",
+}
+
 type ClientApplier interface {
 	ClientProcessorExporter
 	ClientModifier
+	OcClientProjectCreator
 }
 
 // ClientProcessorExporter allows to process templates and export resources.
 type ClientProcessorExporter interface {
 	OcClientProcessor
 	OcClientExporter
+	OcClientDefaulter
 }
 
 // ClientModifier allows to delete and create/update resources.
 type ClientModifier interface {
 	OcClientApplier
 	OcClientDeleter
+	OcClientResourceVersionGetter
+	OcClientExistenceChecker
+	OcClientPodGetter
+}
+
+// ClientRelabeler allows to find resources by label and to change their labels.
+type ClientRelabeler interface {
+	OcClientExporter
+	OcClientLabeler
 }
 
 // OcClientProcessor is a stop-gap solution only ... should have a better API.
@@ -35,14 +58,45 @@ type OcClientExporter interface {
 	Export(target string, label string) ([]byte, error)
 }
 
+// OcClientDefaulter allows to apply server-side defaults to a resource.
+type OcClientDefaulter interface {
+	Default(config string) ([]byte, error)
+}
+
 // OcClientDeleter allows to delete a resource.
 type OcClientDeleter interface {
-	Delete(kind string, name string) ([]byte, error)
+	Delete(kind string, name string, dryRun string) ([]byte, error)
 }
 
 // OcClientApplier allows to create/update a resource.
 type OcClientApplier interface {
-	Apply(config string, selector string) ([]byte, error)
+	Apply(verb string, config string, selector string, dryRun string) ([]byte, error)
+}
+
+// OcClientResourceVersionGetter allows to retrieve the resource version of a resource.
+type OcClientResourceVersionGetter interface {
+	ResourceVersion(kind string, name string) (string, error)
+}
+
+// OcClientExistenceChecker allows to check whether a resource exists.
+type OcClientExistenceChecker interface {
+	Exists(kind string, name string) (bool, error)
+}
+
+// OcClientPodGetter allows to retrieve all pods of the namespace.
+type OcClientPodGetter interface {
+	Pods() ([]byte, error)
+}
+
+// OcClientLabeler allows to set and remove labels of a resource.
+type OcClientLabeler interface {
+	Label(kind string, name string, labels []string) ([]byte, error)
+}
+
+// OcClientProjectCreator allows to check for and create a project (namespace).
+type OcClientProjectCreator interface {
+	CheckProjectExists(p string) (bool, error)
+	CreateProject(p string) ([]byte, error)
 }
 
 // OcClientVersioner allows to retrieve the OpenShift version..
@@ -53,6 +107,8 @@ type OcClientVersioner interface {
 // OcClient is a wrapper around the "oc" binary (client).
 type OcClient struct {
 	namespace string
+	// binary overrides the globally configured oc binary if set.
+	binary string
 }
 
 // NewOcClient creates a new ocClient.
@@ -60,6 +116,12 @@ func NewOcClient(namespace string) *OcClient {
 	return &OcClient{namespace: namespace}
 }
 
+// NewOcClientWithBinary creates a new ocClient using given oc binary instead
+// of the globally configured one.
+func NewOcClientWithBinary(namespace string, binary string) *OcClient {
+	return &OcClient{namespace: namespace, binary: binary}
+}
+
 // Version returns the output of "ov versiopn".
 func (c *OcClient) Version() ([]byte, []byte, error) {
 	cmd := c.execPlainOcCmd([]string{"version"})
@@ -81,9 +143,23 @@ func (c *OcClient) CheckProjectExists(p string) (bool, error) {
 	return err == nil, err
 }
 
+// CreateProject creates given project. If projects are not available (e.g.
+// on plain Kubernetes), a namespace is created instead.
+func (c *OcClient) CreateProject(p string) ([]byte, error) {
+	cmd := c.execPlainOcCmd([]string{"new-project", p, "--skip-config-write"})
+	_, _, err := c.runCmd(cmd)
+	if err == nil {
+		return []byte(""), nil
+	}
+	DebugMsg("Could not create project", p, "- trying to create namespace instead")
+	cmd = c.execPlainOcCmd([]string{"create", "namespace", p})
+	_, errBytes, err := c.runCmd(cmd)
+	return errBytes, err
+}
+
 // CheckLoggedIn returns true if the given project (namespace) exists.
 func (c *OcClient) CheckLoggedIn() (bool, error) {
-	cmd := exec.Command(ocBinary, "whoami")
+	cmd := exec.CommandContext(runContext, ocBinary, "whoami")
 	_, err := cmd.CombinedOutput()
 	return err == nil, err
 }
@@ -124,29 +200,110 @@ func (c *OcClient) Export(target string, label string) ([]byte, error) {
 	return outBytes, nil
 }
 
-// Apply applies given resource configuration.
-func (c *OcClient) Apply(config string, selector string) ([]byte, error) {
-	args := []string{"apply", "-f", "-"}
+// Apply pushes given resource configuration to the cluster using verb
+// ("apply", "create" or "replace"). If dryRun is given (e.g. "server"), the
+// change is validated but not persisted.
+func (c *OcClient) Apply(verb string, config string, selector string, dryRun string) ([]byte, error) {
+	args := []string{verb, "-f", "-"}
+	if len(dryRun) > 0 {
+		args = append(args, "--dry-run="+dryRun)
+	}
+	// Only "oc apply" supports a selector.
+	if verb != "apply" {
+		selector = ""
+	}
 	cmd := c.execOcCmd(
 		args,
 		c.namespace,
 		selector,
 	)
-	stdin, err := cmd.StdinPipe()
+	cmd.Stdin = strings.NewReader(config)
+	_, errBytes, err := c.runCmd(cmd)
+	return errBytes, err
+}
+
+// Default returns given resource configuration with server-side defaults
+// applied, by submitting it via "oc apply" as a server dry run.
+func (c *OcClient) Default(config string) ([]byte, error) {
+	args := []string{"apply", "-f", "-", "--dry-run=server", "--output=yaml"}
+	cmd := c.execOcCmd(
+		args,
+		c.namespace,
+		"", // empty as the resource is given via stdin
+	)
+	cmd.Stdin = strings.NewReader(config)
+	outBytes, errBytes, err := c.runCmd(cmd)
 	if err != nil {
-		return nil, err
+		return nil, errors.New(string(errBytes))
 	}
-	go func() {
-		defer stdin.Close()
-		_, _ = io.WriteString(stdin, config)
-	}()
+	return outBytes, nil
+}
+
+// Delete deletes given resource. If dryRun is given (e.g. "server"), the
+// deletion is validated but not persisted.
+func (c *OcClient) Delete(kind string, name string, dryRun string) ([]byte, error) {
+	args := []string{"delete", kind, name}
+	if len(dryRun) > 0 {
+		args = append(args, "--dry-run="+dryRun)
+	}
+	cmd := c.execOcCmd(
+		args,
+		c.namespace,
+		"", // empty as name and selector is not allowed
+	)
 	_, errBytes, err := c.runCmd(cmd)
 	return errBytes, err
 }
 
-// Delete deletes given resource.
-func (c *OcClient) Delete(kind string, name string) ([]byte, error) {
-	args := []string{"delete", kind, name}
+// ResourceVersion returns the current resource version of given resource.
+func (c *OcClient) ResourceVersion(kind string, name string) (string, error) {
+	args := []string{"get", kind, name, "--output=jsonpath={.metadata.resourceVersion}"}
+	cmd := c.execOcCmd(
+		args,
+		c.namespace,
+		"", // empty as name and selector is not allowed
+	)
+	outBytes, errBytes, err := c.runCmd(cmd)
+	if err != nil {
+		return "", errors.New(string(errBytes))
+	}
+	return strings.TrimSpace(string(outBytes)), nil
+}
+
+// Exists returns true if given resource exists.
+func (c *OcClient) Exists(kind string, name string) (bool, error) {
+	args := []string{"get", kind, name, "--ignore-not-found", "--output=name"}
+	cmd := c.execOcCmd(
+		args,
+		c.namespace,
+		"", // empty as name and selector is not allowed
+	)
+	outBytes, errBytes, err := c.runCmd(cmd)
+	if err != nil {
+		return false, errors.New(string(errBytes))
+	}
+	return len(strings.TrimSpace(string(outBytes))) > 0, nil
+}
+
+// Pods returns all pods of the namespace as JSON list.
+func (c *OcClient) Pods() ([]byte, error) {
+	args := []string{"get", "pods", "--output=json"}
+	cmd := c.execOcCmd(
+		args,
+		c.namespace,
+		"",
+	)
+	outBytes, errBytes, err := c.runCmd(cmd)
+	if err != nil {
+		return nil, errors.New(string(errBytes))
+	}
+	return outBytes, nil
+}
+
+// Label sets (key=value) or removes (key-) given labels of given resource.
+// Existing values are overwritten.
+func (c *OcClient) Label(kind string, name string, labels []string) ([]byte, error) {
+	args := append([]string{"label", kind, name, "--overwrite"}, labels...)
 	cmd := c.execOcCmd(
 		args,
 		c.namespace,
@@ -167,6 +324,9 @@ func (c *OcClient) execOcCmd(args []string, namespace string, selector string) *
 }
 
 func (c *OcClient) execPlainOcCmd(args []string) *exec.Cmd {
+	if len(c.binary) > 0 {
+		return c.execCmd(c.binary, args)
+	}
 	return c.execCmd(ocBinary, args)
 }
 
@@ -174,10 +334,39 @@ func (c *OcClient) execCmd(executable string, args []string) *exec.Cmd {
 	if verbose {
 		PrintBluef("--> %s\n", executable+" "+strings.Join(args, " "))
 	}
-	return exec.Command(executable, args...)
+	return exec.CommandContext(runContext, executable, args...)
 }
 
+// runCmd runs given command. If it fails because the session expired, the
+// token refresh command (if configured) is run and the command is retried
+// once. If the session is still expired, ErrSessionExpired is returned, and
+// errBytes holds its message so that callers report it as the cause.
 func (c *OcClient) runCmd(cmd *exec.Cmd) (outBytes, errBytes []byte, err error) {
+	outBytes, errBytes, err = runAndCapture(cmd)
+	if err == nil || !sessionExpired(errBytes) {
+		return outBytes, errBytes, err
+	}
+	if len(tokenRefreshCommand) > 0 {
+		VerboseMsg("Session expired, running token refresh command")
+		refreshErr := refreshToken()
+		if refreshErr != nil {
+			DebugMsg("Token refresh failed:", refreshErr.Error())
+		} else {
+			retryCmd, retryErr := copyCmd(cmd)
+			if retryErr != nil {
+				return outBytes, errBytes, retryErr
+			}
+			outBytes, errBytes, err = runAndCapture(retryCmd)
+			if err == nil || !sessionExpired(errBytes) {
+				return outBytes, errBytes, err
+			}
+		}
+	}
+	DebugMsg("oc failed due to expired session:", string(errBytes))
+	return outBytes, []byte(ErrSessionExpired.Error()), ErrSessionExpired
+}
+
+func runAndCapture(cmd *exec.Cmd) (outBytes, errBytes []byte, err error) {
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
@@ -186,3 +375,44 @@ func (c *OcClient) runCmd(cmd *exec.Cmd) (outBytes, errBytes []byte, err error)
 	errBytes = stderr.Bytes()
 	return outBytes, errBytes, err
 }
+
+// sessionExpired returns true if errBytes indicates that oc failed because
+// the session is not valid (anymore).
+func sessionExpired(errBytes []byte) bool {
+	ret := string(errBytes)
+	for _, msg := range sessionExpiredMessages {
+		if strings.Contains(ret, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+func refreshToken() error {
+	cmd := exec.CommandContext(runContext, "sh", "-c", tokenRefreshCommand)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// copyCmd returns a fresh command equal to cmd, as a command cannot be run
+// twice. Input given via a seekable reader is rewound.
+func copyCmd(cmd *exec.Cmd) (*exec.Cmd, error) {
+	c := exec.CommandContext(runContext, cmd.Path, cmd.Args[1:]...)
+	c.Env = cmd.Env
+	c.Dir = cmd.Dir
+	if cmd.Stdin != nil {
+		seeker, ok := cmd.Stdin.(io.Seeker)
+		if !ok {
+			return nil, errors.New("Cannot retry command as its input cannot be rewound")
+		}
+		_, err := seeker.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		c.Stdin = cmd.Stdin
+	}
+	return c, nil
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCmdSessionExpired(t *testing.T) {
+	tests := map[string]struct {
+		refreshCommand string
+		wantErr        error
+		wantOutput     string
+	}{
+		"without refresh command": {
+			refreshCommand: "",
+			wantErr:        ErrSessionExpired,
+		},
+		"with failing refresh command": {
+			refreshCommand: "exit 1",
+			wantErr:        ErrSessionExpired,
+		},
+		"with refresh command": {
+			refreshCommand: "touch $TOKEN_FILE",
+			wantErr:        nil,
+			wantOutput:     "applied",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "tailor-oc-client")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			tokenFile := filepath.Join(dir, "token")
+			os.Setenv("TOKEN_FILE", tokenFile)
+			defer os.Unsetenv("TOKEN_FILE")
+			// Fake oc which fails until a token was (re-)issued, and which
+			// requires its input to be passed again on retry.
+			fakeOc := filepath.Join(dir, "oc")
+			script := "#!/bin/sh\n" +
+				"input=$(cat)\n" +
+				"if [ ! -f \"$TOKEN_FILE\" ]; then\n" +
+				"  echo 'error: You must be logged in to the server (Unauthorized)' >&2\n" +
+				"  exit 1\n" +
+				"fi\n" +
+				"echo \"$input\"\n"
+			err = ioutil.WriteFile(fakeOc, []byte(script), 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func(b, r string) { ocBinary, tokenRefreshCommand = b, r }(ocBinary, tokenRefreshCommand)
+			ocBinary = fakeOc
+			tokenRefreshCommand = tc.refreshCommand
+
+			c := NewOcClient("")
+			cmd := c.execPlainOcCmd([]string{"apply"})
+			cmd.Stdin = strings.NewReader("applied")
+			outBytes, errBytes, err := c.runCmd(cmd)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Want error %v, got %v (%s)", tc.wantErr, err, errBytes)
+			}
+			if err != nil && string(errBytes) != ErrSessionExpired.Error() {
+				t.Fatalf("Want clear message, got: %s", errBytes)
+			}
+			if err == nil && string(outBytes) != tc.wantOutput+"\n" {
+				t.Fatalf("Want output '%s', got '%s'", tc.wantOutput, outBytes)
+			}
+		})
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	tests := map[string]struct {
+		stderr string
+		want   bool
+	}{
+		"logged out": {
+			stderr: "error: You must be logged in to the server (Unauthorized)",
+			want:   true,
+		},
+		"credentials required": {
+			stderr: "error: the server has asked for the client to provide credentials",
+			want:   true,
+		},
+		"other error": {
+			stderr: "Error from server (NotFound): configmaps \"foo\" not found",
+			want:   false,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := sessionExpired([]byte(tc.stderr))
+			if got != tc.want {
+				t.Fatalf("Want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package cli
 
 import (
+	"strconv"
 	"strings"
 )
 
+// minimumOcClientVersion is the oldest oc client version known to work with
+// Tailor.
+const minimumOcClientVersion = "v3.9"
+
 // openshiftVersion represents the client/server version pair.
 type openshiftVersion struct {
 	client string
@@ -20,6 +25,40 @@ func (ov openshiftVersion) Incomplete() bool {
 	return ov.client == "?" || ov.server == "?"
 }
 
+// ClientOlderThan returns true if the client version is known and older than
+// given version (e.g. "v3.9"). Only major and minor version are compared.
+func (ov openshiftVersion) ClientOlderThan(version string) bool {
+	client, ok := parseMajorMinor(ov.client)
+	if !ok {
+		return false
+	}
+	other, ok := parseMajorMinor(version)
+	if !ok {
+		return false
+	}
+	if client[0] != other[0] {
+		return client[0] < other[0]
+	}
+	return client[1] < other[1]
+}
+
+// parseMajorMinor parses a version like "v3.11" into its major and minor part.
+func parseMajorMinor(version string) ([2]int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return [2]int{}, false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return [2]int{}, false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return [2]int{}, false
+	}
+	return [2]int{major, minor}, true
+}
+
 // Get OC client and server version. See tests for example output of "oc version".
 func ocVersion(ocClient OcClientVersioner) openshiftVersion {
 	ov := openshiftVersion{"?", "?"}
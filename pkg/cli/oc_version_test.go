@@ -52,3 +52,46 @@ func TestOcVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestClientOlderThan(t *testing.T) {
+	tests := map[string]struct {
+		client  string
+		version string
+		want    bool
+	}{
+		"older minor": {
+			client:  "v3.7",
+			version: "v3.9",
+			want:    true,
+		},
+		"same version": {
+			client:  "v3.9",
+			version: "v3.9",
+			want:    false,
+		},
+		"newer minor with two digits": {
+			client:  "v3.11",
+			version: "v3.9",
+			want:    false,
+		},
+		"newer major": {
+			client:  "v4.1",
+			version: "v3.9",
+			want:    false,
+		},
+		"unknown client": {
+			client:  "?",
+			version: "v3.9",
+			want:    false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ov := openshiftVersion{client: tc.client, server: "?"}
+			if got := ov.ClientOlderThan(tc.version); got != tc.want {
+				t.Fatalf("Want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
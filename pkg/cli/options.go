@@ -6,28 +6,40 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/opendevstack/tailor/pkg/utils"
 )
 
 // GlobalOptions are app-wide.
 type GlobalOptions struct {
-	Verbose         bool
-	Debug           bool
-	NonInteractive  bool
-	OcBinary        string
-	File            string
-	Force           bool
-	IsLoggedIn      bool
-	ClusterRequired bool
-	fs              utils.FileStater
+	Verbose             bool
+	Debug               bool
+	NonInteractive      bool
+	OcBinary            string
+	File                string
+	Force               bool
+	Timeout             time.Duration
+	TokenRefreshCommand string
+	IsLoggedIn          bool
+	ClusterRequired     bool
+	fs                  utils.FileStater
+	// ocBinaryFlagGiven is true if --oc-binary was passed explicitly, in
+	// which case it takes precedence over any Tailorfile.
+	ocBinaryFlagGiven bool
 }
 
 // NamespaceOptions define which namespace Tailor works against.
 type NamespaceOptions struct {
 	Namespace         string
 	CheckedNamespaces []string
+	// ContextNamespace is the namespace oc currently points to. It is only
+	// set if it differs from a target namespace which was not given
+	// explicitly via --namespace (e.g. when taken from a Tailorfile).
+	ContextNamespace   string
+	namespaceFlagGiven bool
 }
 
 // CompareOptions define how to compare desired and current state.
@@ -36,20 +48,76 @@ type CompareOptions struct {
 	*NamespaceOptions
 	Selector                string
 	Excludes                []string
+	APIGroups               []string
+	ExcludedAPIGroups       []string
+	ExcludedFieldManagers   []string
+	OnlyKinds               []string
 	TemplateDir             string
+	Recursive               bool
+	IgnoreFile              string
 	ParamDir                string
+	PatchDir                string
 	PrivateKey              string
 	Passphrase              string
 	Labels                  string
+	NamePrefix              string
+	NameSuffix              string
 	Params                  []string
 	ParamFiles              []string
+	ParamFilePrecedence     string
+	SensitiveParams         []string
+	UnsetParams             []string
+	DeprecatedAPIVersions   []string
 	PreservePaths           []string
+	Identities              []string
+	ComparePolicies         []string
+	DiffOnly                []string
 	PreserveImmutableFields bool
 	IgnoreUnknownParameters bool
+	StrictParamConflicts    bool
+	RequireParamFile        bool
 	UpsertOnly              bool
 	AllowRecreate           bool
+	OnImmutable             string
+	IgnoreWhitespace        bool
 	RevealSecrets           bool
+	MaskSecrets             bool
+	Explain                 bool
+	PrintProcessedOnError   bool
+	ServerDefaults          bool
 	Verify                  bool
+	CheckResourceVersion    bool
+	CreateNamespace         bool
+	TemplateHash            bool
+	ParamsHash              bool
+	WaitForDelete           time.Duration
+	PruneAge                time.Duration
+	Prune                   bool
+	PruneLabel              string
+	ApplyBatchSize          int
+	ApplyBatchDelay         time.Duration
+	PreApplyHook            string
+	PostApplyHook           string
+	ChangeID                string
+	AuditLog                string
+	OnlyActions             []string
+	Resume                  bool
+	Adopt                   bool
+	ApplyVerbs              []string
+	Output                  string
+	Events                  bool
+	DiffFormat              string
+	DiffOnlyPaths           []string
+	SummaryOnly             bool
+	InSyncThreshold         int
+	ShowDesired             string
+	StrictOwnership         bool
+	FailIfEmpty             bool
+	GitLabMRNote            string
+	CompareNamespaces       []string
+	RemoteFile              string
+	RemoteCmd               string
+	DryRun                  string
 	Resource                string
 }
 
@@ -59,14 +127,33 @@ type ExportOptions struct {
 	*NamespaceOptions
 	Selector               string
 	Excludes               []string
+	APIGroups              []string
+	ExcludedAPIGroups      []string
+	ExcludedFieldManagers  []string
+	OnlyKinds              []string
 	TemplateDir            string
 	ParamDir               string
 	WithAnnotations        bool
 	WithHardcodedNamespace bool
 	TrimAnnotations        []string
+	AnnotationPrefixes     []string
+	StripPaths             []string
+	Write                  bool
+	Format                 string
+	GroupByLabel           string
 	Resource               string
 }
 
+// RelabelOptions define how to move resources to a new management label.
+type RelabelOptions struct {
+	*GlobalOptions
+	*NamespaceOptions
+	From     string
+	To       string
+	Excludes []string
+	Resource string
+}
+
 // SecretsOptions define how to work with encrypted files.
 type SecretsOptions struct {
 	*GlobalOptions
@@ -90,7 +177,9 @@ func NewGlobalOptions(
 	debugFlag bool,
 	nonInteractiveFlag bool,
 	ocBinaryFlag string,
-	forceFlag bool) (*GlobalOptions, error) {
+	forceFlag bool,
+	timeoutFlag time.Duration,
+	tokenRefreshCommandFlag string) (*GlobalOptions, error) {
 	o := InitGlobalOptions(&utils.OsFS{})
 	o.ClusterRequired = clusterRequired
 
@@ -123,6 +212,7 @@ func NewGlobalOptions(
 
 	if len(ocBinaryFlag) > 0 {
 		o.OcBinary = ocBinaryFlag
+		o.ocBinaryFlagGiven = true
 	} else if val, ok := fileFlags["oc-binary"]; ok {
 		o.OcBinary = val
 	}
@@ -133,9 +223,27 @@ func NewGlobalOptions(
 		o.Force = true
 	}
 
+	if timeoutFlag > 0 {
+		o.Timeout = timeoutFlag
+	} else if val, ok := fileFlags["timeout"]; ok {
+		t, err := time.ParseDuration(val)
+		if err != nil {
+			return o, fmt.Errorf("Invalid timeout '%s': %s", val, err)
+		}
+		o.Timeout = t
+	}
+
+	if len(tokenRefreshCommandFlag) > 0 {
+		o.TokenRefreshCommand = tokenRefreshCommandFlag
+	} else if val, ok := fileFlags["token-refresh-command"]; ok {
+		o.TokenRefreshCommand = val
+	}
+
 	verbose = o.Verbose || o.Debug
 	debug = o.Debug
 	ocBinary = o.OcBinary
+	tokenRefreshCommand = o.TokenRefreshCommand
+	InitRunContext(o.Timeout)
 
 	DebugMsg(fmt.Sprintf("%#v", o))
 
@@ -148,21 +256,78 @@ func NewCompareOptions(
 	namespaceFlag string,
 	selectorFlag string,
 	excludeFlag []string,
+	apiGroupFlag []string,
+	excludeAPIGroupFlag []string,
+	excludeFieldManagerFlag []string,
+	onlyKindsFlag []string,
 	templateDirFlag string,
+	recursiveFlag bool,
+	ignoreFileFlag string,
 	paramDirFlag string,
+	patchDirFlag string,
 	publicKeyDirFlag string,
 	privateKeyFlag string,
 	passphraseFlag string,
+	passphraseFileFlag string,
 	labelsFlag string,
+	namePrefixFlag string,
+	nameSuffixFlag string,
 	paramFlag []string,
 	paramFileFlag []string,
+	paramFilePrecedenceFlag string,
 	preserveFlag []string,
+	identityFlag []string,
+	comparePolicyFlag []string,
+	diffOnlyFlag []string,
+	sensitiveParamFlag []string,
+	unsetParamFlag []string,
 	preserveImmutableFieldsFlag bool,
+	deprecatedAPIVersionFlag []string,
 	ignoreUnknownParametersFlag bool,
+	strictParamConflictsFlag bool,
+	requireParamFileFlag bool,
 	upsertOnlyFlag bool,
 	allowRecreateFlag bool,
+	onImmutableFlag string,
+	ignoreWhitespaceFlag bool,
 	revealSecretsFlag bool,
+	maskSecretsFlag bool,
+	explainFlag bool,
+	printProcessedOnErrorFlag bool,
+	serverDefaultsFlag bool,
 	verifyFlag bool,
+	checkResourceVersionFlag bool,
+	createNamespaceFlag bool,
+	templateHashFlag bool,
+	paramsHashFlag bool,
+	waitForDeleteFlag time.Duration,
+	pruneAgeFlag time.Duration,
+	pruneFlag bool,
+	pruneLabelFlag string,
+	applyBatchSizeFlag int,
+	applyBatchDelayFlag time.Duration,
+	preApplyHookFlag string,
+	postApplyHookFlag string,
+	changeIDFlag string,
+	auditLogFlag string,
+	onlyFlag []string,
+	resumeFlag bool,
+	adoptFlag bool,
+	applyVerbFlag []string,
+	outputFlag string,
+	eventsFlag bool,
+	diffFormatFlag string,
+	diffOnlyPathFlag []string,
+	summaryOnlyFlag bool,
+	inSyncThresholdFlag int,
+	showDesiredFlag string,
+	strictOwnershipFlag bool,
+	failIfEmptyFlag bool,
+	gitLabMRNoteFlag string,
+	compareNamespaceFlag []string,
+	remoteFileFlag string,
+	remoteCmdFlag string,
+	dryRunFlag string,
 	resourceArg string) (*CompareOptions, error) {
 	o := &CompareOptions{
 		GlobalOptions:    globalOptions,
@@ -175,8 +340,14 @@ func NewCompareOptions(
 		return o, fmt.Errorf("Could not read '%s': %s", filename, err)
 	}
 
+	err = o.useNamespacedOcBinary(filename, fileFlags)
+	if err != nil {
+		return o, err
+	}
+
 	if len(namespaceFlag) > 0 {
 		o.Namespace = namespaceFlag
+		o.namespaceFlagGiven = true
 	} else if val, ok := fileFlags["namespace"]; ok {
 		o.Namespace = val
 	}
@@ -196,6 +367,42 @@ func NewCompareOptions(
 		o.Excludes = strings.Split(val, ",")
 	}
 
+	o.APIGroups = []string{}
+	if len(apiGroupFlag) > 0 {
+		for _, val := range apiGroupFlag {
+			o.APIGroups = append(o.APIGroups, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["api-group"]; ok {
+		o.APIGroups = strings.Split(val, ",")
+	}
+
+	o.ExcludedAPIGroups = []string{}
+	if len(excludeAPIGroupFlag) > 0 {
+		for _, val := range excludeAPIGroupFlag {
+			o.ExcludedAPIGroups = append(o.ExcludedAPIGroups, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["exclude-api-group"]; ok {
+		o.ExcludedAPIGroups = strings.Split(val, ",")
+	}
+
+	o.ExcludedFieldManagers = []string{}
+	if len(excludeFieldManagerFlag) > 0 {
+		for _, val := range excludeFieldManagerFlag {
+			o.ExcludedFieldManagers = append(o.ExcludedFieldManagers, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["exclude-field-manager"]; ok {
+		o.ExcludedFieldManagers = strings.Split(val, ",")
+	}
+
+	o.OnlyKinds = []string{}
+	if len(onlyKindsFlag) > 0 {
+		for _, val := range onlyKindsFlag {
+			o.OnlyKinds = append(o.OnlyKinds, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["only-kinds"]; ok {
+		o.OnlyKinds = strings.Split(val, ",")
+	}
+
 	o.TemplateDir = "."
 	if templateDirFlag != "." {
 		o.TemplateDir = templateDirFlag
@@ -203,6 +410,18 @@ func NewCompareOptions(
 		o.TemplateDir = val
 	}
 
+	if recursiveFlag {
+		o.Recursive = true
+	} else if fileFlags["recursive"] == "true" {
+		o.Recursive = true
+	}
+
+	if len(ignoreFileFlag) > 0 {
+		o.IgnoreFile = ignoreFileFlag
+	} else if val, ok := fileFlags["ignore-file"]; ok {
+		o.IgnoreFile = val
+	}
+
 	o.ParamDir = "."
 	if paramDirFlag != "." {
 		o.ParamDir = paramDirFlag
@@ -210,6 +429,13 @@ func NewCompareOptions(
 		o.ParamDir = val
 	}
 
+	o.PatchDir = "patches"
+	if patchDirFlag != "patches" {
+		o.PatchDir = patchDirFlag
+	} else if val, ok := fileFlags["patch-dir"]; ok {
+		o.PatchDir = val
+	}
+
 	o.PrivateKey = "private.key"
 	if privateKeyFlag != "private.key" {
 		o.PrivateKey = privateKeyFlag
@@ -217,10 +443,9 @@ func NewCompareOptions(
 		o.PrivateKey = val
 	}
 
-	if len(passphraseFlag) > 0 {
-		o.Passphrase = passphraseFlag
-	} else if val, ok := fileFlags["passphrase"]; ok {
-		o.Passphrase = val
+	o.Passphrase, err = resolvePassphrase(passphraseFlag, passphraseFileFlag, fileFlags)
+	if err != nil {
+		return o, err
 	}
 
 	if len(labelsFlag) > 0 {
@@ -229,6 +454,18 @@ func NewCompareOptions(
 		o.Labels = val
 	}
 
+	if len(namePrefixFlag) > 0 {
+		o.NamePrefix = namePrefixFlag
+	} else if val, ok := fileFlags["name-prefix"]; ok {
+		o.NamePrefix = val
+	}
+
+	if len(nameSuffixFlag) > 0 {
+		o.NameSuffix = nameSuffixFlag
+	} else if val, ok := fileFlags["name-suffix"]; ok {
+		o.NameSuffix = val
+	}
+
 	if val, ok := fileFlags["param"]; ok {
 		o.Params = strings.Split(val, ",")
 	}
@@ -257,6 +494,10 @@ func NewCompareOptions(
 			}
 		}
 	}
+	o.Params, err = resolveParamFileValues(o.Params)
+	if err != nil {
+		return o, err
+	}
 
 	if len(paramFileFlag) > 0 {
 		o.ParamFiles = paramFileFlag
@@ -264,6 +505,13 @@ func NewCompareOptions(
 		o.ParamFiles = strings.Split(val, ",")
 	}
 
+	o.ParamFilePrecedence = "last-wins"
+	if len(paramFilePrecedenceFlag) > 0 {
+		o.ParamFilePrecedence = paramFilePrecedenceFlag
+	} else if val, ok := fileFlags["param-file-precedence"]; ok {
+		o.ParamFilePrecedence = val
+	}
+
 	if len(preserveFlag) > 0 {
 		o.PreservePaths = preserveFlag
 	} else if val, ok := fileFlags["ignore-path"]; ok {
@@ -272,18 +520,66 @@ func NewCompareOptions(
 		o.PreservePaths = strings.Split(val, ",")
 	}
 
+	if len(identityFlag) > 0 {
+		o.Identities = identityFlag
+	} else if val, ok := fileFlags["identity"]; ok {
+		o.Identities = strings.Split(val, ",")
+	}
+
+	if len(comparePolicyFlag) > 0 {
+		o.ComparePolicies = comparePolicyFlag
+	} else if val, ok := fileFlags["compare-policy"]; ok {
+		o.ComparePolicies = strings.Split(val, ",")
+	}
+
+	if len(diffOnlyFlag) > 0 {
+		o.DiffOnly = diffOnlyFlag
+	} else if val, ok := fileFlags["diff-only"]; ok {
+		o.DiffOnly = strings.Split(val, ",")
+	}
+
+	if len(sensitiveParamFlag) > 0 {
+		o.SensitiveParams = sensitiveParamFlag
+	} else if val, ok := fileFlags["sensitive-param"]; ok {
+		o.SensitiveParams = strings.Split(val, ",")
+	}
+
+	if len(unsetParamFlag) > 0 {
+		o.UnsetParams = unsetParamFlag
+	} else if val, ok := fileFlags["unset-param"]; ok {
+		o.UnsetParams = strings.Split(val, ",")
+	}
+
 	if preserveImmutableFieldsFlag {
 		o.PreserveImmutableFields = true
 	} else if fileFlags["preserve-immutable-fields"] == "true" {
 		o.PreserveImmutableFields = true
 	}
 
+	if len(deprecatedAPIVersionFlag) > 0 {
+		o.DeprecatedAPIVersions = deprecatedAPIVersionFlag
+	} else if val, ok := fileFlags["deprecated-api-version"]; ok {
+		o.DeprecatedAPIVersions = strings.Split(val, ",")
+	}
+
 	if ignoreUnknownParametersFlag {
 		o.IgnoreUnknownParameters = true
 	} else if fileFlags["ignore-unknown-parameters"] == "true" {
 		o.IgnoreUnknownParameters = true
 	}
 
+	if strictParamConflictsFlag {
+		o.StrictParamConflicts = true
+	} else if fileFlags["strict-param-conflicts"] == "true" {
+		o.StrictParamConflicts = true
+	}
+
+	if requireParamFileFlag {
+		o.RequireParamFile = true
+	} else if fileFlags["require-param-file"] == "true" {
+		o.RequireParamFile = true
+	}
+
 	if upsertOnlyFlag {
 		o.UpsertOnly = true
 	} else if fileFlags["upsert-only"] == "true" {
@@ -296,27 +592,412 @@ func NewCompareOptions(
 		o.AllowRecreate = true
 	}
 
+	if len(onImmutableFlag) > 0 {
+		o.OnImmutable = onImmutableFlag
+	} else if val, ok := fileFlags["on-immutable"]; ok {
+		o.OnImmutable = val
+	}
+	if o.AllowRecreate && len(o.OnImmutable) == 0 {
+		o.OnImmutable = "recreate"
+	}
+
+	if ignoreWhitespaceFlag {
+		o.IgnoreWhitespace = true
+	} else if fileFlags["ignore-whitespace"] == "true" {
+		o.IgnoreWhitespace = true
+	}
+
 	if revealSecretsFlag {
 		o.RevealSecrets = true
 	} else if fileFlags["reveal-secrets"] == "true" {
 		o.RevealSecrets = true
 	}
 
+	if maskSecretsFlag {
+		o.MaskSecrets = true
+	} else if fileFlags["mask-secrets"] == "true" {
+		o.MaskSecrets = true
+	}
+
+	if explainFlag {
+		o.Explain = true
+	} else if fileFlags["explain"] == "true" {
+		o.Explain = true
+	}
+
+	if printProcessedOnErrorFlag {
+		o.PrintProcessedOnError = true
+	} else if fileFlags["print-processed-on-error"] == "true" {
+		o.PrintProcessedOnError = true
+	}
+
+	if serverDefaultsFlag {
+		o.ServerDefaults = true
+	} else if fileFlags["server-defaults"] == "true" {
+		o.ServerDefaults = true
+	}
+
 	if verifyFlag {
 		o.Verify = true
 	} else if fileFlags["verify"] == "true" {
 		o.Verify = true
 	}
 
+	if checkResourceVersionFlag {
+		o.CheckResourceVersion = true
+	} else if fileFlags["check-resource-version"] == "true" {
+		o.CheckResourceVersion = true
+	}
+
+	if createNamespaceFlag {
+		o.CreateNamespace = true
+	} else if fileFlags["create-namespace"] == "true" {
+		o.CreateNamespace = true
+	}
+
+	if templateHashFlag {
+		o.TemplateHash = true
+	} else if fileFlags["template-hash"] == "true" {
+		o.TemplateHash = true
+	}
+
+	if paramsHashFlag {
+		o.ParamsHash = true
+	} else if fileFlags["params-hash"] == "true" {
+		o.ParamsHash = true
+	}
+
+	if waitForDeleteFlag > 0 {
+		o.WaitForDelete = waitForDeleteFlag
+	} else if val, ok := fileFlags["wait-for-delete"]; ok {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return o, fmt.Errorf("Invalid wait-for-delete duration '%s': %s", val, err)
+		}
+		o.WaitForDelete = d
+	}
+
+	if pruneAgeFlag > 0 {
+		o.PruneAge = pruneAgeFlag
+	} else if val, ok := fileFlags["prune-age"]; ok {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return o, fmt.Errorf("Invalid prune-age duration '%s': %s", val, err)
+		}
+		o.PruneAge = d
+	}
+
+	if pruneFlag {
+		o.Prune = true
+	} else if fileFlags["prune"] == "true" {
+		o.Prune = true
+	}
+
+	if len(pruneLabelFlag) > 0 {
+		o.PruneLabel = pruneLabelFlag
+	} else if val, ok := fileFlags["prune-label"]; ok {
+		o.PruneLabel = val
+	} else {
+		o.PruneLabel = o.Selector
+	}
+
+	if applyBatchSizeFlag > 0 {
+		o.ApplyBatchSize = applyBatchSizeFlag
+	} else if val, ok := fileFlags["apply-batch-size"]; ok {
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return o, fmt.Errorf("Invalid apply-batch-size '%s': %s", val, err)
+		}
+		o.ApplyBatchSize = i
+	}
+
+	if applyBatchDelayFlag > 0 {
+		o.ApplyBatchDelay = applyBatchDelayFlag
+	} else if val, ok := fileFlags["apply-batch-delay"]; ok {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return o, fmt.Errorf("Invalid apply-batch-delay duration '%s': %s", val, err)
+		}
+		o.ApplyBatchDelay = d
+	}
+
+	if len(preApplyHookFlag) > 0 {
+		o.PreApplyHook = preApplyHookFlag
+	} else if val, ok := fileFlags["pre-apply-hook"]; ok {
+		o.PreApplyHook = val
+	}
+
+	if len(postApplyHookFlag) > 0 {
+		o.PostApplyHook = postApplyHookFlag
+	} else if val, ok := fileFlags["post-apply-hook"]; ok {
+		o.PostApplyHook = val
+	}
+
+	if len(changeIDFlag) > 0 {
+		o.ChangeID = changeIDFlag
+	} else if val, ok := fileFlags["change-id"]; ok {
+		o.ChangeID = val
+	}
+
+	if len(auditLogFlag) > 0 {
+		o.AuditLog = auditLogFlag
+	} else if val, ok := fileFlags["audit-log"]; ok {
+		o.AuditLog = val
+	}
+
+	o.OnlyActions = []string{}
+	if len(onlyFlag) > 0 {
+		for _, val := range onlyFlag {
+			o.OnlyActions = append(o.OnlyActions, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["only"]; ok {
+		o.OnlyActions = strings.Split(val, ",")
+	}
+
+	if resumeFlag {
+		o.Resume = true
+	} else if fileFlags["resume"] == "true" {
+		o.Resume = true
+	}
+
+	if adoptFlag {
+		o.Adopt = true
+	} else if fileFlags["adopt"] == "true" {
+		o.Adopt = true
+	}
+
+	o.ApplyVerbs = []string{}
+	if len(applyVerbFlag) > 0 {
+		for _, val := range applyVerbFlag {
+			o.ApplyVerbs = append(o.ApplyVerbs, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["apply-verb"]; ok {
+		o.ApplyVerbs = strings.Split(val, ",")
+	}
+
+	o.Output = "text"
+	if len(outputFlag) > 0 {
+		o.Output = outputFlag
+	} else if val, ok := fileFlags["output"]; ok {
+		o.Output = val
+	}
+
+	if eventsFlag {
+		o.Events = true
+	} else if fileFlags["events"] == "true" {
+		o.Events = true
+	}
+
+	o.DiffFormat = "text"
+	if len(diffFormatFlag) > 0 {
+		o.DiffFormat = diffFormatFlag
+	} else if val, ok := fileFlags["diff"]; ok {
+		o.DiffFormat = val
+	}
+
+	o.DiffOnlyPaths = []string{}
+	if len(diffOnlyPathFlag) > 0 {
+		for _, val := range diffOnlyPathFlag {
+			o.DiffOnlyPaths = append(o.DiffOnlyPaths, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["diff-only-path"]; ok {
+		o.DiffOnlyPaths = strings.Split(val, ",")
+	}
+
+	if summaryOnlyFlag {
+		o.SummaryOnly = true
+	} else if fileFlags["summary-only"] == "true" {
+		o.SummaryOnly = true
+	}
+
+	if inSyncThresholdFlag > 0 {
+		o.InSyncThreshold = inSyncThresholdFlag
+	} else if val, ok := fileFlags["in-sync-threshold"]; ok {
+		i, err := strconv.Atoi(val)
+		if err != nil {
+			return o, fmt.Errorf("Invalid in-sync-threshold '%s': %s", val, err)
+		}
+		o.InSyncThreshold = i
+	}
+
+	o.ShowDesired = showDesiredFlag
+
+	if strictOwnershipFlag {
+		o.StrictOwnership = true
+	} else if fileFlags["strict-ownership"] == "true" {
+		o.StrictOwnership = true
+	}
+
+	if failIfEmptyFlag {
+		o.FailIfEmpty = true
+	} else if fileFlags["fail-if-empty"] == "true" {
+		o.FailIfEmpty = true
+	}
+
+	if len(gitLabMRNoteFlag) > 0 {
+		o.GitLabMRNote = gitLabMRNoteFlag
+	} else if val, ok := fileFlags["gitlab-mr-note"]; ok {
+		o.GitLabMRNote = val
+	}
+
+	o.CompareNamespaces = []string{}
+	if len(compareNamespaceFlag) > 0 {
+		for _, val := range compareNamespaceFlag {
+			o.CompareNamespaces = append(o.CompareNamespaces, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["compare-namespace"]; ok {
+		o.CompareNamespaces = strings.Split(val, ",")
+	}
+
+	if len(remoteFileFlag) > 0 {
+		o.RemoteFile = remoteFileFlag
+	} else if val, ok := fileFlags["remote-file"]; ok {
+		o.RemoteFile = val
+	}
+
+	if len(remoteCmdFlag) > 0 {
+		o.RemoteCmd = remoteCmdFlag
+	} else if val, ok := fileFlags["remote-cmd"]; ok {
+		o.RemoteCmd = val
+	}
+
+	if len(dryRunFlag) > 0 {
+		o.DryRun = dryRunFlag
+	} else if val, ok := fileFlags["dry-run"]; ok {
+		o.DryRun = val
+	}
+
 	if len(resourceArg) > 0 {
 		o.Resource = resourceArg
 	} else if val, ok := fileFlags["resource"]; ok {
 		o.Resource = val
 	}
 
+	err = o.check(o.ClusterRequired)
+	if err != nil {
+		return o, err
+	}
+
+	if len(selectorFlag) == 0 && len(o.Selector) == 0 {
+		err = o.useNamespacedSelector(filename)
+		if err != nil {
+			return o, err
+		}
+	}
+
 	DebugMsg(fmt.Sprintf("%#v", o))
 
-	return o, o.check(o.ClusterRequired)
+	return o, nil
+}
+
+// NewRenderOptions returns new options for commands which only render the
+// templates, without comparing against or applying to the cluster (e.g. list
+// or diff-params). Options which only affect comparing and applying are left
+// at their defaults.
+func NewRenderOptions(
+	globalOptions *GlobalOptions,
+	namespaceFlag string,
+	selectorFlag string,
+	excludeFlag []string,
+	apiGroupFlag []string,
+	excludeAPIGroupFlag []string,
+	excludeFieldManagerFlag []string,
+	onlyKindsFlag []string,
+	templateDirFlag string,
+	recursiveFlag bool,
+	ignoreFileFlag string,
+	paramDirFlag string,
+	patchDirFlag string,
+	publicKeyDirFlag string,
+	privateKeyFlag string,
+	passphraseFlag string,
+	passphraseFileFlag string,
+	namePrefixFlag string,
+	nameSuffixFlag string,
+	paramFlag []string,
+	paramFileFlag []string,
+	ignoreUnknownParametersFlag bool,
+	revealSecretsFlag bool,
+	resourceArg string) (*CompareOptions, error) {
+	return NewCompareOptions(
+		globalOptions,
+		namespaceFlag,
+		selectorFlag,
+		excludeFlag,
+		apiGroupFlag,
+		excludeAPIGroupFlag,
+		excludeFieldManagerFlag,
+		onlyKindsFlag,
+		templateDirFlag,
+		recursiveFlag,
+		ignoreFileFlag,
+		paramDirFlag,
+		patchDirFlag,
+		publicKeyDirFlag,
+		privateKeyFlag,
+		passphraseFlag,
+		passphraseFileFlag,
+		"", // labels
+		namePrefixFlag,
+		nameSuffixFlag,
+		paramFlag,
+		paramFileFlag,
+		"",         // param file precedence
+		[]string{}, // preserve
+		[]string{}, // identity
+		[]string{}, // compare policy
+		[]string{}, // diff-only
+		[]string{}, // sensitive params
+		[]string{}, // unset params
+		false,      // preserve immutable fields
+		[]string{}, // deprecated API versions
+		ignoreUnknownParametersFlag,
+		false, // strict param conflicts
+		false, // require param file
+		false, // upsert only
+		false, // allow recreate
+		"",    // on immutable
+		false, // ignore whitespace
+		revealSecretsFlag,
+		false,      // mask secrets
+		false,      // explain
+		false,      // print processed on error
+		false,      // server defaults
+		false,      // verify
+		false,      // check resource version
+		false,      // create namespace
+		false,      // template hash
+		false,      // params hash
+		0,          // wait for delete
+		0,          // prune age
+		false,      // prune
+		"",         // prune label
+		0,          // apply batch size
+		0,          // apply batch delay
+		"",         // pre-apply hook
+		"",         // post-apply hook
+		"",         // change ID
+		"",         // audit log
+		[]string{}, // only
+		false,      // resume
+		false,      // adopt
+		[]string{}, // apply verbs
+		"text",     // output
+		false,      // events
+		"text",     // diff format
+		[]string{}, // diff-only paths
+		false,      // summary only
+		0,          // in sync threshold
+		"",         // show desired
+		false,      // strict ownership
+		false,      // fail if empty
+		"",         // GitLab MR note
+		[]string{}, // compare namespaces
+		"",         // remote file
+		"",         // remote command
+		"",         // dry run
+		resourceArg,
+	)
 }
 
 // NewExportOptions returns new options for the export command based on file/flags.
@@ -325,11 +1006,20 @@ func NewExportOptions(
 	namespaceFlag string,
 	selectorFlag string,
 	excludeFlag []string,
+	apiGroupFlag []string,
+	excludeAPIGroupFlag []string,
+	excludeFieldManagerFlag []string,
+	onlyKindsFlag []string,
 	templateDirFlag string,
 	paramDirFlag string,
 	withAnnotationsFlag bool,
 	withHardcodedNamespaceFlag bool,
 	trimAnnotationsFlag []string,
+	annotationPrefixFlag []string,
+	stripPathFlag []string,
+	writeFlag bool,
+	formatFlag string,
+	groupByLabelFlag string,
 	resourceArg string) (*ExportOptions, error) {
 	o := &ExportOptions{
 		GlobalOptions:    globalOptions,
@@ -342,8 +1032,14 @@ func NewExportOptions(
 		return o, fmt.Errorf("Could not read %s: %s", filename, err)
 	}
 
+	err = o.useNamespacedOcBinary(filename, fileFlags)
+	if err != nil {
+		return o, err
+	}
+
 	if len(namespaceFlag) > 0 {
 		o.Namespace = namespaceFlag
+		o.namespaceFlagGiven = true
 	} else if val, ok := fileFlags["namespace"]; ok {
 		o.Namespace = val
 	}
@@ -363,6 +1059,42 @@ func NewExportOptions(
 		o.Excludes = strings.Split(val, ",")
 	}
 
+	o.APIGroups = []string{}
+	if len(apiGroupFlag) > 0 {
+		for _, val := range apiGroupFlag {
+			o.APIGroups = append(o.APIGroups, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["api-group"]; ok {
+		o.APIGroups = strings.Split(val, ",")
+	}
+
+	o.ExcludedAPIGroups = []string{}
+	if len(excludeAPIGroupFlag) > 0 {
+		for _, val := range excludeAPIGroupFlag {
+			o.ExcludedAPIGroups = append(o.ExcludedAPIGroups, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["exclude-api-group"]; ok {
+		o.ExcludedAPIGroups = strings.Split(val, ",")
+	}
+
+	o.ExcludedFieldManagers = []string{}
+	if len(excludeFieldManagerFlag) > 0 {
+		for _, val := range excludeFieldManagerFlag {
+			o.ExcludedFieldManagers = append(o.ExcludedFieldManagers, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["exclude-field-manager"]; ok {
+		o.ExcludedFieldManagers = strings.Split(val, ",")
+	}
+
+	o.OnlyKinds = []string{}
+	if len(onlyKindsFlag) > 0 {
+		for _, val := range onlyKindsFlag {
+			o.OnlyKinds = append(o.OnlyKinds, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["only-kinds"]; ok {
+		o.OnlyKinds = strings.Split(val, ",")
+	}
+
 	o.TemplateDir = "."
 	if templateDirFlag != "." {
 		o.TemplateDir = templateDirFlag
@@ -395,6 +1127,37 @@ func NewExportOptions(
 		o.TrimAnnotations = strings.Split(val, ",")
 	}
 
+	if len(annotationPrefixFlag) > 0 {
+		o.AnnotationPrefixes = annotationPrefixFlag
+	} else if val, ok := fileFlags["annotation-prefix"]; ok {
+		o.AnnotationPrefixes = strings.Split(val, ",")
+	}
+
+	if len(stripPathFlag) > 0 {
+		o.StripPaths = stripPathFlag
+	} else if val, ok := fileFlags["strip-path"]; ok {
+		o.StripPaths = strings.Split(val, ",")
+	}
+
+	if writeFlag {
+		o.Write = true
+	} else if fileFlags["write"] == "true" {
+		o.Write = true
+	}
+
+	o.Format = "template"
+	if len(formatFlag) > 0 {
+		o.Format = formatFlag
+	} else if val, ok := fileFlags["format"]; ok {
+		o.Format = val
+	}
+
+	if len(groupByLabelFlag) > 0 {
+		o.GroupByLabel = groupByLabelFlag
+	} else if val, ok := fileFlags["group-by-label"]; ok {
+		o.GroupByLabel = val
+	}
+
 	if len(resourceArg) > 0 {
 		o.Resource = resourceArg
 	} else if val, ok := fileFlags["resource"]; ok {
@@ -406,13 +1169,66 @@ func NewExportOptions(
 	return o, o.check()
 }
 
+// NewRelabelOptions returns new options for the relabel command based on file/flags.
+func NewRelabelOptions(
+	globalOptions *GlobalOptions,
+	namespaceFlag string,
+	fromFlag string,
+	toFlag string,
+	excludeFlag []string,
+	resourceArg string) (*RelabelOptions, error) {
+	o := &RelabelOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &NamespaceOptions{},
+	}
+	filename := o.resolvedFile(namespaceFlag)
+
+	fileFlags, err := getFileFlags(filename, verbose)
+	if err != nil {
+		return o, fmt.Errorf("Could not read %s: %s", filename, err)
+	}
+
+	err = o.useNamespacedOcBinary(filename, fileFlags)
+	if err != nil {
+		return o, err
+	}
+
+	if len(namespaceFlag) > 0 {
+		o.Namespace = namespaceFlag
+		o.namespaceFlagGiven = true
+	} else if val, ok := fileFlags["namespace"]; ok {
+		o.Namespace = val
+	}
+
+	// The selector of the Tailorfile is deliberately not used as fallback for
+	// --from, as it usually already refers to the new label scheme.
+	o.From = fromFlag
+	o.To = toFlag
+
+	o.Excludes = []string{}
+	if len(excludeFlag) > 0 {
+		for _, val := range excludeFlag {
+			o.Excludes = append(o.Excludes, strings.Split(val, ",")...)
+		}
+	} else if val, ok := fileFlags["exclude"]; ok {
+		o.Excludes = strings.Split(val, ",")
+	}
+
+	o.Resource = resourceArg
+
+	DebugMsg(fmt.Sprintf("%#v", o))
+
+	return o, o.check()
+}
+
 // NewSecretsOptions returns new options for the secrets subcommand based on file/flags.
 func NewSecretsOptions(
 	globalOptions *GlobalOptions,
 	paramDirFlag string,
 	publicKeyDirFlag string,
 	privateKeyFlag string,
-	passphraseFlag string) (*SecretsOptions, error) {
+	passphraseFlag string,
+	passphraseFileFlag string) (*SecretsOptions, error) {
 	o := &SecretsOptions{
 		GlobalOptions: globalOptions,
 	}
@@ -445,11 +1261,45 @@ func NewSecretsOptions(
 		o.PrivateKey = val
 	}
 
+	o.Passphrase, err = resolvePassphrase(passphraseFlag, passphraseFileFlag, fileFlags)
+	if err != nil {
+		return o, err
+	}
+
 	DebugMsg(fmt.Sprintf("%#v", o))
 
 	return o, o.check()
 }
 
+// resolvePassphrase returns the passphrase given via --passphrase, or read
+// from the file given via --passphrase-file. Reading it from a file keeps it
+// out of the process arguments. Flags take precedence over the Tailorfile.
+func resolvePassphrase(passphraseFlag string, passphraseFileFlag string, fileFlags map[string]string) (string, error) {
+	if len(passphraseFlag) > 0 {
+		return passphraseFlag, nil
+	}
+	if len(passphraseFileFlag) > 0 {
+		return readPassphraseFile(passphraseFileFlag)
+	}
+	if val, ok := fileFlags["passphrase"]; ok {
+		return val, nil
+	}
+	if val, ok := fileFlags["passphrase-file"]; ok {
+		return readPassphraseFile(val)
+	}
+	return "", nil
+}
+
+// readPassphraseFile reads the passphrase from given file. A trailing
+// newline is not considered to be part of the passphrase.
+func readPassphraseFile(filename string) (string, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("Could not read passphrase file: %s", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
 // resolvedFile returns either the user-supplied value, or, if the default is used
 // AND a namespaceFlag is given, "Tailorfile.${NAMESPACE}" (if it exists).
 func (o *GlobalOptions) resolvedFile(namespaceFlag string) string {
@@ -466,6 +1316,49 @@ func (o *GlobalOptions) resolvedFile(namespaceFlag string) string {
 	return namespacedFile
 }
 
+// useNamespacedOcBinary switches to the oc binary configured in a namespaced
+// file (e.g. Tailorfile.foo), unless --oc-binary was given explicitly. This
+// allows to target clusters which require different client versions.
+func (o *GlobalOptions) useNamespacedOcBinary(filename string, fileFlags map[string]string) error {
+	val, ok := fileFlags["oc-binary"]
+	if !ok || filename == o.File || o.ocBinaryFlagGiven || val == o.OcBinary {
+		return nil
+	}
+	DebugMsg("Using oc binary", val, "from", filename)
+	o.OcBinary = val
+	ocBinary = val
+	return o.check(o.ClusterRequired)
+}
+
+// useNamespacedSelector uses the selector configured in the namespaced file
+// (e.g. Tailorfile.foo) of the target namespace if filename is not that file
+// already. This allows a default selector per namespace even if the namespace
+// is not passed explicitly, but e.g. taken from the current oc project.
+func (o *CompareOptions) useNamespacedSelector(filename string) error {
+	if len(o.Namespace) == 0 {
+		return nil
+	}
+	namespacedFile := o.resolvedFile(o.Namespace)
+	if namespacedFile == filename {
+		return nil
+	}
+	fileFlags, err := getFileFlags(namespacedFile, verbose)
+	if err != nil {
+		return fmt.Errorf("Could not read '%s': %s", namespacedFile, err)
+	}
+	val, ok := fileFlags["selector"]
+	if !ok {
+		return nil
+	}
+	if strings.Contains(o.Resource, "/") {
+		DebugMsg("Ignoring selector", val, "from", namespacedFile, "as resource is given")
+		return nil
+	}
+	DebugMsg("Using selector", val, "from", namespacedFile)
+	o.Selector = val
+	return nil
+}
+
 // FileExists checks whether given file exists.
 func (o *GlobalOptions) FileExists(file string) bool {
 	_, err := o.fs.Stat(file)
@@ -474,14 +1367,23 @@ func (o *GlobalOptions) FileExists(file string) bool {
 
 func (o *GlobalOptions) check(clusterRequired bool) error {
 	if !o.checkOcBinary() {
-		return fmt.Errorf("No such oc binary: %s", o.OcBinary)
+		return fmt.Errorf("No such oc binary: %s. Install oc or point to it via --oc-binary", o.OcBinary)
 	}
 	if clusterRequired {
 		if !o.checkLoggedIn() {
 			return errors.New("You need to login with 'oc login' first")
 		}
 		c := NewOcClient("")
-		if v := ocVersion(c); !v.ExactMatch() {
+		v := ocVersion(c)
+		DebugMsg("Using oc binary", o.OcBinary, "with client version", v.client, "and server version", v.server)
+		if v.ClientOlderThan(minimumOcClientVersion) {
+			PrintYellowf(
+				"Warning: oc client version %s is older than %s, which is the oldest version known to work with Tailor.\n",
+				v.client,
+				minimumOcClientVersion,
+			)
+		}
+		if !v.ExactMatch() {
 			if v.Incomplete() {
 				VerboseMsg(fmt.Sprintf("Version information is incomplete: client (%s) and server (%s) detected. "+
 					"This is likely due to a local cluster setup. "+
@@ -528,6 +1430,12 @@ func (o *CompareOptions) check(clusterRequired bool) error {
 			return fmt.Errorf("Template directory '%s' does not exist", td)
 		}
 	}
+	// Check if ignore file exists
+	if len(o.IgnoreFile) > 0 {
+		if _, err := os.Stat(o.IgnoreFile); os.IsNotExist(err) {
+			return fmt.Errorf("Ignore file '%s' does not exist", o.IgnoreFile)
+		}
+	}
 	// Check if param dir exists
 	if o.ParamDir != "." {
 		pd := o.ParamDir
@@ -536,11 +1444,142 @@ func (o *CompareOptions) check(clusterRequired bool) error {
 		}
 	}
 
+	if len(o.ShowDesired) > 0 && len(strings.Split(o.ShowDesired, "/")) != 2 {
+		return fmt.Errorf("Resource to show must be of form kind/name, got '%s'", o.ShowDesired)
+	}
+
+	if o.Output != "text" && o.Output != "html" && o.Output != "merge-patch" {
+		return fmt.Errorf("Output must be 'text', 'html' or 'merge-patch', got '%s'", o.Output)
+	}
+
+	if len(o.OnImmutable) > 0 && o.OnImmutable != "error" && o.OnImmutable != "recreate" && o.OnImmutable != "warn" {
+		return fmt.Errorf("On immutable must be 'error', 'recreate' or 'warn', got '%s'", o.OnImmutable)
+	}
+	if o.AllowRecreate && o.OnImmutable != "recreate" {
+		return fmt.Errorf("--allow-recreate cannot be combined with --on-immutable=%s", o.OnImmutable)
+	}
+
+	if o.Prune && len(o.PruneLabel) == 0 {
+		return errors.New("--prune requires a label identifying the resources managed by Tailor, given via --prune-label or --selector")
+	}
+
+	if len(o.GitLabMRNote) > 0 && o.GitLabMRNote != "best-effort" && o.GitLabMRNote != "required" {
+		return fmt.Errorf("GitLab MR note must be 'best-effort' or 'required', got '%s'", o.GitLabMRNote)
+	}
+	if len(o.GitLabMRNote) > 0 && len(o.CompareNamespaces) > 0 {
+		return errors.New("--gitlab-mr-note cannot be combined with --compare-namespace")
+	}
+
+	if o.DiffFormat != "text" && o.DiffFormat != "json" && o.DiffFormat != "both" {
+		return fmt.Errorf("Diff format must be 'text', 'json' or 'both', got '%s'", o.DiffFormat)
+	}
+
+	for _, path := range o.DiffOnlyPaths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("--diff-only-path must be a JSON pointer starting with '/', got '%s'", path)
+		}
+	}
+
+	if o.SummaryOnly && o.Output != "text" {
+		return fmt.Errorf("--summary-only cannot be combined with --output %s", o.Output)
+	}
+
+	if o.Events && (o.Output != "text" || o.SummaryOnly || len(o.CompareNamespaces) > 0) {
+		return errors.New("--events cannot be combined with --output, --summary-only or --compare-namespace")
+	}
+
+	if len(o.DryRun) > 0 {
+		if o.DryRun != "server" {
+			return fmt.Errorf("Dry run must be 'server', got '%s'", o.DryRun)
+		}
+		if o.CreateNamespace {
+			return errors.New("Dry run cannot be combined with --create-namespace")
+		}
+		if o.Verify {
+			return errors.New("Dry run cannot be combined with --verify")
+		}
+	}
+
+	for _, action := range o.OnlyActions {
+		if action != "create" && action != "update" && action != "delete" {
+			return fmt.Errorf("Action to apply must be 'create', 'update' or 'delete', got '%s'", action)
+		}
+	}
+	if len(o.OnlyActions) > 0 && o.Verify {
+		return errors.New("--only cannot be combined with --verify")
+	}
+
+	if o.Resume && len(o.DryRun) > 0 {
+		return errors.New("--resume cannot be combined with --dry-run")
+	}
+
+	if len(o.RemoteFile) > 0 && len(o.RemoteCmd) > 0 {
+		return errors.New("--remote-file cannot be combined with --remote-cmd")
+	}
+
+	if o.ServerDefaults && len(o.RemoteFile) > 0 {
+		return errors.New("--server-defaults cannot be combined with --remote-file")
+	}
+	if o.ServerDefaults && len(o.RemoteCmd) > 0 {
+		return errors.New("--server-defaults cannot be combined with --remote-cmd")
+	}
+
+	if len(o.CompareNamespaces) > 0 {
+		if len(o.RemoteFile) > 0 {
+			return errors.New("--compare-namespace cannot be combined with --remote-file")
+		}
+		if len(o.RemoteCmd) > 0 {
+			return errors.New("--compare-namespace cannot be combined with --remote-cmd")
+		}
+		if o.Output != "text" {
+			return fmt.Errorf("--compare-namespace cannot be combined with --output %s", o.Output)
+		}
+		if clusterRequired {
+			for _, n := range o.CompareNamespaces {
+				err := o.checkOcNamespace(n)
+				if err != nil {
+					return fmt.Errorf("No such project: %s", n)
+				}
+			}
+		}
+	}
+
+	if o.ApplyBatchSize < 0 {
+		return fmt.Errorf("Apply batch size must not be negative, got %d", o.ApplyBatchSize)
+	}
+
+	if o.ParamFilePrecedence != "first-wins" && o.ParamFilePrecedence != "last-wins" {
+		return fmt.Errorf("Param file precedence must be 'first-wins' or 'last-wins', got '%s'", o.ParamFilePrecedence)
+	}
+
 	if strings.Contains(o.Resource, "/") && len(o.Selector) > 0 {
 		DebugMsg("Ignoring selector", o.Selector, "as resource is given")
 		o.Selector = ""
 	}
 
+	// When comparing against a snapshot, the cluster is not contacted.
+	if len(o.RemoteFile) > 0 {
+		if _, err := os.Stat(o.RemoteFile); os.IsNotExist(err) {
+			return fmt.Errorf("Remote file '%s' does not exist", o.RemoteFile)
+		}
+		if len(o.Namespace) == 0 {
+			return errors.New("A namespace is required when comparing against a remote file")
+		}
+		return nil
+	}
+	if len(o.RemoteCmd) > 0 {
+		if len(o.Namespace) == 0 {
+			return errors.New("A namespace is required when comparing against the output of a remote command")
+		}
+		return nil
+	}
+
+	// The existence of the namespace is checked by apply, which might
+	// create it.
+	if o.CreateNamespace && len(o.Namespace) > 0 {
+		return nil
+	}
+
 	return o.setNamespace(clusterRequired)
 }
 
@@ -559,7 +1598,30 @@ func (o *CompareOptions) PathsToPreserve() []string {
 	return append(pathsToPreserve, o.PreservePaths...)
 }
 
+// APIVersionsToWarnAbout returns the deprecated apiVersions which are known
+// by default, and those given by the user.
+func (o *CompareOptions) APIVersionsToWarnAbout() []string {
+	apiVersions := []string{
+		"extensions/v1beta1",
+		"apps/v1beta1",
+		"apps/v1beta2",
+	}
+	return append(apiVersions, o.DeprecatedAPIVersions...)
+}
+
 func (o *ExportOptions) check() error {
+	if o.Format != "template" && o.Format != "list" {
+		return fmt.Errorf("Format must be 'template' or 'list', got '%s'", o.Format)
+	}
+
+	if len(o.GroupByLabel) > 0 && o.Format != "template" {
+		return errors.New("--group-by-label can only be used with --format template")
+	}
+
+	if len(o.AnnotationPrefixes) > 0 && o.WithAnnotations {
+		return errors.New("--annotation-prefix cannot be combined with --with-annotations")
+	}
+
 	if strings.Contains(o.Resource, "/") && len(o.Selector) > 0 {
 		DebugMsg("Ignoring selector", o.Selector, "as resource is given")
 		o.Selector = ""
@@ -568,7 +1630,43 @@ func (o *ExportOptions) check() error {
 	return o.setNamespace(o.ClusterRequired)
 }
 
+func (o *RelabelOptions) check() error {
+	labels := []struct{ flag, val string }{{"--from", o.From}, {"--to", o.To}}
+	for _, l := range labels {
+		parts := strings.SplitN(l.val, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return fmt.Errorf("%s must be a label in the form key=value, got '%s'", l.flag, l.val)
+		}
+	}
+	if o.From == o.To {
+		return errors.New("--from and --to must be different labels")
+	}
+
+	if strings.Contains(o.Resource, "/") {
+		return errors.New("Resources are selected via --from, only kinds can be given")
+	}
+
+	return o.setNamespace(o.ClusterRequired)
+}
+
 func (o *SecretsOptions) check() error {
+	// Ask for the passphrase of a protected private key unless given, so
+	// that it does not need to be passed on the command line.
+	if len(o.Passphrase) > 0 || !o.FileExists(o.PrivateKey) {
+		return nil
+	}
+	encrypted, err := utils.PrivateKeyEncrypted(o.PrivateKey)
+	if err != nil || !encrypted {
+		return err
+	}
+	if o.NonInteractive {
+		return fmt.Errorf("Private key '%s' is protected by a passphrase, but no --passphrase was given", o.PrivateKey)
+	}
+	passphrase, err := PromptPassphrase(fmt.Sprintf("Passphrase for '%s'", o.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("Could not read passphrase: %s", err)
+	}
+	o.Passphrase = passphrase
 	return nil
 }
 
@@ -585,6 +1683,11 @@ func (o *NamespaceOptions) setNamespace(clusterRequired bool) error {
 			if err != nil {
 				return fmt.Errorf("No such project: %s", o.Namespace)
 			}
+			if !o.namespaceFlagGiven {
+				if n, err := getOcNamespace(); err == nil && n != o.Namespace {
+					o.ContextNamespace = n
+				}
+			}
 		}
 	}
 	return nil
@@ -607,6 +1710,26 @@ func getOcNamespace() (string, error) {
 	return c.CurrentProject()
 }
 
+// resolveParamFileValues replaces values of the form "@path/to/file" with
+// the contents of the referenced file, e.g. to pass a certificate as param
+// without having to escape it in the shell.
+func resolveParamFileValues(params []string) ([]string, error) {
+	resolved := []string{}
+	for _, param := range params {
+		pair := strings.SplitN(param, "=", 2)
+		if len(pair) < 2 || !strings.HasPrefix(pair[1], "@") {
+			resolved = append(resolved, param)
+			continue
+		}
+		b, err := ioutil.ReadFile(strings.TrimPrefix(pair[1], "@"))
+		if err != nil {
+			return params, fmt.Errorf("Could not read value of param %s: %s", pair[0], err)
+		}
+		resolved = append(resolved, pair[0]+"="+string(b))
+	}
+	return resolved, nil
+}
+
 func getFileFlags(filename string, verbose bool) (map[string]string, error) {
 	fileFlags := make(map[string]string)
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
@@ -1,6 +1,8 @@
 package cli
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -58,6 +60,182 @@ func TestResolvedFile(t *testing.T) {
 	}
 }
 
+func TestUseNamespacedOcBinary(t *testing.T) {
+	defer func(b string) { ocBinary = b }(ocBinary)
+
+	tests := map[string]struct {
+		filename     string
+		fileOcBinary string
+		flagGiven    bool
+		wantOcBinary string
+		wantError    bool
+	}{
+		"general file": {
+			filename:     "Tailorfile",
+			fileOcBinary: "sh",
+			wantOcBinary: "oc",
+		},
+		"namespaced file": {
+			filename:     "Tailorfile.foo",
+			fileOcBinary: "sh",
+			wantOcBinary: "sh",
+		},
+		"flag takes precedence": {
+			filename:     "Tailorfile.foo",
+			fileOcBinary: "sh",
+			flagGiven:    true,
+			wantOcBinary: "oc",
+		},
+		"missing binary": {
+			filename:     "Tailorfile.foo",
+			fileOcBinary: "does-not-exist",
+			wantError:    true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := InitGlobalOptions(&helper.SomeFilesExistFS{})
+			o.File = "Tailorfile"
+			o.OcBinary = "oc"
+			o.ocBinaryFlagGiven = tc.flagGiven
+			err := o.useNamespacedOcBinary(tc.filename, map[string]string{"oc-binary": tc.fileOcBinary})
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("Want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.OcBinary != tc.wantOcBinary {
+				t.Fatalf("Want oc binary '%s', got '%s'", tc.wantOcBinary, o.OcBinary)
+			}
+		})
+	}
+}
+
+func TestUseNamespacedSelector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-selector")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("Tailorfile.foo", []byte("selector app=foo\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		filename     string
+		namespace    string
+		resource     string
+		wantSelector string
+	}{
+		"namespace from context": {
+			filename:     "Tailorfile",
+			namespace:    "foo",
+			wantSelector: "app=foo",
+		},
+		"namespaced file already used": {
+			filename:     "Tailorfile.foo",
+			namespace:    "foo",
+			wantSelector: "",
+		},
+		"no namespaced file": {
+			filename:     "Tailorfile",
+			namespace:    "bar",
+			wantSelector: "",
+		},
+		"resource given": {
+			filename:     "Tailorfile",
+			namespace:    "foo",
+			resource:     "dc/foo",
+			wantSelector: "",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			o := &CompareOptions{
+				GlobalOptions:    InitGlobalOptions(&utils.OsFS{}),
+				NamespaceOptions: &NamespaceOptions{Namespace: tc.namespace},
+				Resource:         tc.resource,
+			}
+			o.File = "Tailorfile"
+			err := o.useNamespacedSelector(tc.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.Selector != tc.wantSelector {
+				t.Fatalf("Want selector '%s', got '%s'", tc.wantSelector, o.Selector)
+			}
+		})
+	}
+}
+
+func TestResolvePassphrase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	passphraseFile := dir + "/passphrase"
+	err = ioutil.WriteFile(passphraseFile, []byte("from-file\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		passphraseFlag     string
+		passphraseFileFlag string
+		fileFlags          map[string]string
+		want               string
+	}{
+		"flag wins over file": {
+			passphraseFlag:     "from-flag",
+			passphraseFileFlag: passphraseFile,
+			want:               "from-flag",
+		},
+		"file flag": {
+			passphraseFileFlag: passphraseFile,
+			fileFlags:          map[string]string{"passphrase": "from-tailorfile"},
+			want:               "from-file",
+		},
+		"file from Tailorfile": {
+			fileFlags: map[string]string{"passphrase-file": passphraseFile},
+			want:      "from-file",
+		},
+		"none": {
+			want: "",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolvePassphrase(tc.passphraseFlag, tc.passphraseFileFlag, tc.fileFlags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("Want passphrase '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+
+	_, err = resolvePassphrase("", dir+"/missing", nil)
+	if err == nil {
+		t.Fatal("Want error for missing passphrase file, got none")
+	}
+}
+
 func TestNewCompareOptionsExcludes(t *testing.T) {
 	tests := map[string]struct {
 		excludeFlag  []string
@@ -86,31 +264,36 @@ func TestNewCompareOptionsExcludes(t *testing.T) {
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			o, err := NewGlobalOptions(false, "Tailorfile", false, false, false, "oc", false)
+			o, err := NewGlobalOptions(false, "Tailorfile", false, false, false, "oc", false, 0, "")
 			if err != nil {
 				t.Fatal(err)
 			}
-			got, err := NewCompareOptions(
+			got, err := NewRenderOptions(
 				o,
 				"",
 				"",
 				tc.excludeFlag,
+				[]string{},
+				[]string{},
+				[]string{},
+				[]string{},
 				".",
+				false,
+				"",
 				".",
+				"patches",
+				"",
+				"",
 				"",
 				"",
 				"",
 				"",
 				[]string{},
 				[]string{},
-				[]string{},
-				false,
-				false,
 				false,
 				false,
-				false,
-				false,
-				"")
+				"",
+			)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -149,7 +332,7 @@ func TestNewExportOptionsExcludes(t *testing.T) {
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			o, err := NewGlobalOptions(false, "Tailorfile", false, false, false, "oc", false)
+			o, err := NewGlobalOptions(false, "Tailorfile", false, false, false, "oc", false, 0, "")
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -158,11 +341,20 @@ func TestNewExportOptionsExcludes(t *testing.T) {
 				"",
 				"",
 				tc.excludeFlag,
+				[]string{},
+				[]string{},
+				[]string{},
+				[]string{},
 				".",
 				".",
 				false,
 				false,
 				[]string{},
+				[]string{},
+				[]string{},
+				false,
+				"",
+				"",
 				"")
 			if err != nil {
 				t.Fatal(err)
@@ -173,3 +365,52 @@ func TestNewExportOptionsExcludes(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveParamFileValues(t *testing.T) {
+	f, err := ioutil.TempFile("", "tailor-param")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("line1\nline2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tests := map[string]struct {
+		params    []string
+		want      []string
+		wantError bool
+	}{
+		"literal values": {
+			params: []string{"FOO=bar", "BAZ="},
+			want:   []string{"FOO=bar", "BAZ="},
+		},
+		"file value": {
+			params: []string{"FOO=bar", "CERT=@" + f.Name()},
+			want:   []string{"FOO=bar", "CERT=line1\nline2\n"},
+		},
+		"missing file": {
+			params:    []string{"CERT=@does-not-exist.pem"},
+			wantError: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := resolveParamFileValues(tc.params)
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("Want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Params mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"encoding/base64"
+	"sort"
+	"strings"
+)
+
+const redactedPlaceholder = "***"
+
+// minRedactedValueLength is the minimum length of values which are masked.
+// Shorter values (e.g. "1", "true" or "app") appear in many unrelated places,
+// so masking them would garble the output without protecting anything.
+const minRedactedValueLength = 6
+
+// redactedValues holds the values of sensitive params, which must not appear
+// in any output of Tailor.
+var redactedValues = []string{}
+
+// AddRedactedValue registers given value to be masked in all output. As
+// values of Secret resources are base64-encoded, the encoded form of value
+// is masked as well. It returns false if value is too short to be masked.
+func AddRedactedValue(value string) bool {
+	if len(value) == 0 || value == redactedPlaceholder {
+		return true
+	}
+	if len(value) < minRedactedValueLength {
+		return false
+	}
+	addRedactedValue(value)
+	addRedactedValue(base64.StdEncoding.EncodeToString([]byte(value)))
+	// Longer values go first so that values containing other values are
+	// masked completely.
+	sort.Slice(redactedValues, func(i, j int) bool {
+		return len(redactedValues[i]) > len(redactedValues[j])
+	})
+	return true
+}
+
+func addRedactedValue(value string) {
+	for _, v := range redactedValues {
+		if v == value {
+			return
+		}
+	}
+	redactedValues = append(redactedValues, value)
+}
+
+// Redact masks all registered sensitive values in s.
+func Redact(s string) string {
+	for _, v := range redactedValues {
+		s = strings.Replace(s, v, redactedPlaceholder, -1)
+	}
+	return s
+}
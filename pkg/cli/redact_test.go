@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	defer func() { redactedValues = []string{} }()
+	AddRedactedValue("s3cr3t")
+	AddRedactedValue("s3cr3t-token")
+	AddRedactedValue("")
+	if AddRedactedValue("true") {
+		t.Fatal("Want short value to be rejected")
+	}
+
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"no sensitive value": {
+			input:    "oc process --param=FOO=bar",
+			expected: "oc process --param=FOO=bar",
+		},
+		"sensitive value": {
+			input:    "oc process --param=PASSWORD=s3cr3t",
+			expected: "oc process --param=PASSWORD=***",
+		},
+		"value containing another value": {
+			input:    "token: s3cr3t-token",
+			expected: "token: ***",
+		},
+		"base64-encoded value": {
+			input:    "data:\n  password: czNjcjN0\n",
+			expected: "data:\n  password: ***\n",
+		},
+		"short value": {
+			input:    "enabled: true",
+			expected: "enabled: true",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Redact(tc.input)
+			if got != tc.expected {
+				t.Fatalf("Want '%s', got '%s'", tc.expected, got)
+			}
+		})
+	}
+}
@@ -6,12 +6,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/openshift"
 )
 
-type printChange func(w io.Writer, change *openshift.Change, revealSecrets bool)
+var waitForDeletionPollInterval = time.Second
+
+type printChange func(w io.Writer, change *openshift.Change, revealSecrets bool, explain bool, diffFormat string)
 type handleChange func(label string, change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error
 
 // Apply prints the drift between desired and current state to STDOUT.
@@ -19,19 +25,88 @@ type handleChange func(label string, change *openshift.Change, compareOptions *c
 func Apply(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli.ClientApplier, stdin io.Reader) (bool, error) {
 	stdinReader := bufio.NewReader(stdin)
 
+	_, err := openshift.ParseApplyVerbs(compareOptions.ApplyVerbs)
+	if err != nil {
+		return false, err
+	}
+
+	if compareOptions.Events {
+		if !nonInteractive {
+			return false, errors.New("--events requires --non-interactive")
+		}
+		restore, err := suppressHumanOutput()
+		if err != nil {
+			return false, err
+		}
+		defer restore()
+	}
+
+	// Guard against applying to the wrong environment when oc points to a
+	// different namespace than the one configured e.g. in the Tailorfile.
+	if len(compareOptions.ContextNamespace) > 0 && !nonInteractive {
+		a, err := cli.AskForAction(
+			fmt.Sprintf(
+				"Target namespace %s differs from current oc project %s. Continue?",
+				compareOptions.Namespace,
+				compareOptions.ContextNamespace,
+			),
+			[]string{"y=yes", "n=no"},
+			stdinReader,
+		)
+		if err != nil {
+			return false, err
+		}
+		if a != "y" {
+			return false, errors.New("Apply aborted as target namespace differs from current oc project")
+		}
+	}
+
+	if compareOptions.CreateNamespace {
+		err = ensureNamespace(compareOptions.Namespace, ocClient)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	var buf bytes.Buffer
-	driftDetected, changeset, err := calculateChangeset(&buf, compareOptions, ocClient)
-	fmt.Print(buf.String())
+	driftDetected, changeset, err := calculateChangeset(&buf, &buf, compareOptions, ocClient)
+	fmt.Print(cli.Redact(buf.String()))
 	if err != nil {
 		return driftDetected, err
 	}
 
 	if driftDetected {
+		applicable, diffOnly := changeset.WithoutDiffOnly()
+		for _, change := range diffOnly {
+			fmt.Printf("Skipping %s as it is diff-only.\n", printedItemName(change, compareOptions))
+		}
+		if applicable.Blank() {
+			return true, nil
+		}
+		changeset = applicable
+		// Drift of diff-only resources remains after applying.
+		driftRemains := len(diffOnly) > 0
+
+		// A dry run does not persist anything, so there is no need to ask.
+		if len(compareOptions.DryRun) > 0 {
+			fmt.Println("")
+			return true, dryRun(compareOptions, changeset, ocClient)
+		}
+		audit, err := openAuditLog(compareOptions)
+		if err != nil {
+			return true, err
+		}
+		defer audit.close()
 		if nonInteractive {
-			err = apply(compareOptions, changeset, ocClient)
+			err = runPreApplyHook(compareOptions)
+			if err != nil {
+				return true, fmt.Errorf("Apply aborted: %w", err)
+			}
+			err = apply(compareOptions, changeset, ocClient, audit)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
+				return true, fmt.Errorf("Apply aborted: %w", err)
 			}
+			runPostApplyHook(compareOptions)
 			if compareOptions.Verify {
 				err := performVerification(compareOptions, ocClient)
 				if err != nil {
@@ -39,8 +114,8 @@ func Apply(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli
 				}
 			}
 			// As apply has run successfully, there should not be any drift
-			// anymore. Therefore we report no drift here.
-			return false, nil
+			// anymore (except for diff-only resources).
+			return driftRemains, nil
 		}
 
 		options := []string{"y=yes", "n=no"}
@@ -51,13 +126,21 @@ func Apply(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli
 		if allowSelecting {
 			options = append(options, "s=select")
 		}
-		a := cli.AskForAction("Apply all changes?", options, stdinReader)
+		a, err := cli.AskForAction("Apply all changes?", options, stdinReader)
+		if err != nil {
+			return true, err
+		}
 		if a == "y" {
 			fmt.Println("")
-			err = apply(compareOptions, changeset, ocClient)
+			err = runPreApplyHook(compareOptions)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
+				return true, fmt.Errorf("Apply aborted: %w", err)
 			}
+			err = apply(compareOptions, changeset, ocClient, audit)
+			if err != nil {
+				return true, fmt.Errorf("Apply aborted: %w", err)
+			}
+			runPostApplyHook(compareOptions)
 			if compareOptions.Verify {
 				err := performVerification(compareOptions, ocClient)
 				if err != nil {
@@ -65,31 +148,45 @@ func Apply(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli
 				}
 			}
 			// As apply has run successfully, there should not be any drift
-			// anymore. Therefore we report no drift here.
-			return false, nil
+			// anymore (except for diff-only resources).
+			return driftRemains, nil
 		} else if allowSelecting && a == "s" {
 			anyChangeSkipped := false
 
-			anyDeleteChangeSkipped, err := askAndApply(compareOptions, ocClient, stdinReader, changeset.Delete, printDeleteChange, "Deleting", ocDelete)
+			// The pre-apply hook runs only once the first selected change
+			// is about to be applied, so that skipping all changes does not
+			// trigger it.
+			hookRan := false
+			beforeApply := func() error {
+				if hookRan {
+					return nil
+				}
+				hookRan = true
+				return runPreApplyHook(compareOptions)
+			}
+			anyDeleteChangeSkipped, err := askAndApply(compareOptions, ocClient, audit, stdinReader, beforeApply, changeset.Delete, printDeleteChange, "Deleting", ocDelete)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
+				return true, fmt.Errorf("Apply aborted: %w", err)
 			} else if anyDeleteChangeSkipped {
 				anyChangeSkipped = true
 			}
-			anyCreateChangeSkipped, err := askAndApply(compareOptions, ocClient, stdinReader, changeset.Create, printCreateChange, "Creating", ocApply)
+			anyCreateChangeSkipped, err := askAndApply(compareOptions, ocClient, audit, stdinReader, beforeApply, changeset.Create, printCreateChange, "Creating", ocApply)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
+				return true, fmt.Errorf("Apply aborted: %w", err)
 			} else if anyCreateChangeSkipped {
 				anyChangeSkipped = true
 			}
-			anyUpdateChangeSkipped, err := askAndApply(compareOptions, ocClient, stdinReader, changeset.Update, printUpdateChange, "Updating", ocApply)
+			anyUpdateChangeSkipped, err := askAndApply(compareOptions, ocClient, audit, stdinReader, beforeApply, changeset.Update, printUpdateChange, "Updating", ocApply)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
+				return true, fmt.Errorf("Apply aborted: %w", err)
 			} else if anyUpdateChangeSkipped {
 				anyChangeSkipped = true
 			}
+			if hookRan {
+				runPostApplyHook(compareOptions)
+			}
 
-			return anyChangeSkipped, nil
+			return anyChangeSkipped || driftRemains, nil
 		}
 
 		// Changes were not applied, so we report that drift was detected.
@@ -100,24 +197,59 @@ func Apply(nonInteractive bool, compareOptions *cli.CompareOptions, ocClient cli
 	return false, nil
 }
 
-func askAndApply(compareOptions *cli.CompareOptions, ocClient cli.ClientApplier, stdinReader *bufio.Reader, changes []*openshift.Change, changePrinter printChange, label string, changeHandler handleChange) (bool, error) {
+// ensureNamespace creates the namespace if it does not exist yet.
+func ensureNamespace(namespace string, ocClient cli.OcClientProjectCreator) error {
+	if exists, _ := ocClient.CheckProjectExists(namespace); exists {
+		return nil
+	}
+	fmt.Printf("Creating project %s ... ", namespace)
+	errBytes, err := ocClient.CreateProject(namespace)
+	if err != nil {
+		fmt.Printf("failed\n")
+		return fmt.Errorf("Could not create project %s: %s", namespace, string(errBytes))
+	}
+	fmt.Printf("done\n")
+	return nil
+}
+
+// askAndApply asks for each change whether to apply it. beforeApply is called
+// before each change which is applied.
+func askAndApply(compareOptions *cli.CompareOptions, ocClient cli.ClientApplier, audit *auditLog, stdinReader *bufio.Reader, beforeApply func() error, changes []*openshift.Change, changePrinter printChange, label string, changeHandler handleChange) (bool, error) {
 	anyChangeSkipped := false
 
 	for _, change := range changes {
+		if err := cli.RunContextErr(); err != nil {
+			return true, err
+		}
 		fmt.Println("")
 		var buf bytes.Buffer
-		changePrinter(&buf, change, compareOptions.RevealSecrets)
-		fmt.Print(buf.String())
-		a := cli.AskForAction(
-			fmt.Sprintf("Apply change to %s?", change.ItemName()),
+		if isMaskedSecret(change, compareOptions) {
+			fmt.Fprintf(&buf, "* %s to %s\n", printedItemName(change, compareOptions), strings.ToLower(change.Action))
+		} else {
+			changePrinter(&buf, change, compareOptions.RevealSecrets, compareOptions.Explain, compareOptions.DiffFormat)
+		}
+		fmt.Print(cli.Redact(buf.String()))
+		a, err := cli.AskForAction(
+			fmt.Sprintf("Apply change to %s?", printedItemName(change, compareOptions)),
 			[]string{"y=yes", "n=no"},
 			stdinReader,
 		)
+		if err != nil {
+			return true, err
+		}
 		if a == "y" {
 			fmt.Println("")
-			err := changeHandler(label, change, compareOptions, ocClient)
+			err := beforeApply()
+			if err != nil {
+				return true, err
+			}
+			err = changeHandler(label, change, compareOptions, ocClient)
+			recordErr := recordApplied(compareOptions, audit, change, err)
 			if err != nil {
-				return true, fmt.Errorf("Apply aborted: %s", err)
+				return true, fmt.Errorf("Apply aborted: %w", err)
+			}
+			if recordErr != nil {
+				return true, fmt.Errorf("Apply aborted: %w", recordErr)
 			}
 		} else {
 			anyChangeSkipped = true
@@ -126,67 +258,340 @@ func askAndApply(compareOptions *cli.CompareOptions, ocClient cli.ClientApplier,
 	return anyChangeSkipped, nil
 }
 
-func apply(compareOptions *cli.CompareOptions, c *openshift.Changeset, ocClient cli.ClientModifier) error {
+func apply(compareOptions *cli.CompareOptions, c *openshift.Changeset, ocClient cli.ClientModifier, audit *auditLog) error {
+	throttle := newBatchThrottle(compareOptions.ApplyBatchSize, compareOptions.ApplyBatchDelay)
+
+	var cp *checkpoint
+	if compareOptions.Resume {
+		cp = loadCheckpoint(compareOptions.Namespace, c)
+	}
+	run := func(label string, change *openshift.Change, changeHandler handleChange) error {
+		if cp != nil && cp.Applied[changeKey(change)] {
+			fmt.Printf("Skipping %s (already applied by previous run)\n", printedItemName(change, compareOptions))
+			return nil
+		}
+		err := cli.RunContextErr()
+		if err != nil {
+			return err
+		}
+		throttle.wait()
+		err = changeHandler(label, change, compareOptions, ocClient)
+		recordErr := recordApplied(compareOptions, audit, change, err)
+		if err != nil {
+			return err
+		}
+		if recordErr != nil {
+			return recordErr
+		}
+		if cp != nil {
+			return cp.done(change)
+		}
+		return nil
+	}
 
 	for _, change := range c.Delete {
-		err := ocDelete("Deleting", change, compareOptions, ocClient)
+		err := run("Deleting", change, ocDelete)
 		if err != nil {
 			return err
 		}
 	}
 
 	for _, change := range c.Create {
-		err := ocApply("Creating", change, compareOptions, ocClient)
+		err := run("Creating", change, ocApply)
 		if err != nil {
 			return err
 		}
 	}
 
 	for _, change := range c.Update {
-		err := ocApply("Updating", change, compareOptions, ocClient)
+		err := run("Updating", change, ocApply)
 		if err != nil {
 			return err
 		}
 	}
 
+	if cp != nil {
+		cp.remove()
+	}
 	return nil
 }
 
+// runPreApplyHook runs the configured pre-apply hook, if any. An error
+// means that changes must not be applied.
+func runPreApplyHook(compareOptions *cli.CompareOptions) error {
+	return runHook("pre-apply", compareOptions.PreApplyHook, compareOptions.Namespace)
+}
+
+// runPostApplyHook runs the configured post-apply hook, if any. As changes
+// have been applied already at this point, failure is only reported.
+func runPostApplyHook(compareOptions *cli.CompareOptions) {
+	err := runHook("post-apply", compareOptions.PostApplyHook, compareOptions.Namespace)
+	if err != nil {
+		cli.PrintRedf("%s\n", err)
+	}
+}
+
+// runHook executes command in a shell, exposing the namespace as
+// TAILOR_NAMESPACE. Output of the command is passed through. The command is
+// killed once the run context is done.
+func runHook(label string, command string, namespace string) error {
+	if len(command) == 0 {
+		return nil
+	}
+	fmt.Printf("Running %s hook '%s' ...\n", label, command)
+	cmd := exec.CommandContext(cli.RunContext(), "sh", "-c", command)
+	cmd.Env = append(os.Environ(), "TAILOR_NAMESPACE="+namespace)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	if ctxErr := cli.RunContextErr(); ctxErr != nil {
+		return fmt.Errorf("The %s hook was aborted: %w", label, ctxErr)
+	}
+	if err != nil {
+		return fmt.Errorf("The %s hook failed: %s", label, err)
+	}
+	return nil
+}
+
+// batchThrottle pauses between batches of changes, e.g. to protect
+// admission webhooks or rate-limited APIs from being overwhelmed.
+type batchThrottle struct {
+	size    int
+	delay   time.Duration
+	applied int
+	sleep   func(time.Duration)
+}
+
+func newBatchThrottle(size int, delay time.Duration) *batchThrottle {
+	return &batchThrottle{size: size, delay: delay, sleep: time.Sleep}
+}
+
+// wait must be called before applying each change. Once a batch is
+// complete, it waits for the configured delay before the next change.
+func (b *batchThrottle) wait() {
+	if b.size > 0 && b.applied > 0 && b.applied%b.size == 0 && b.delay > 0 {
+		fmt.Printf("Applied batch of %d change(s), waiting %s ...\n", b.size, b.delay)
+		b.sleep(b.delay)
+	}
+	b.applied++
+}
+
 func ocDelete(label string, change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error {
-	fmt.Printf("%s %s ... ", label, change.ItemName())
-	errBytes, err := ocClient.Delete(change.Kind, change.Name)
+	if len(compareOptions.ChangeID) > 0 {
+		fmt.Printf("%s %s (change %s) ... ", label, printedItemName(change, compareOptions), compareOptions.ChangeID)
+	} else {
+		fmt.Printf("%s %s ... ", label, printedItemName(change, compareOptions))
+	}
+	err := checkResourceVersion(change, compareOptions, ocClient)
+	if err != nil {
+		fmt.Println("failed")
+		return err
+	}
+	err = checkNotInUse(change, compareOptions, ocClient)
+	if err != nil {
+		fmt.Println("failed")
+		return err
+	}
+	waitTimeout, err := change.WaitTimeout(compareOptions.WaitForDelete)
+	if err != nil {
+		fmt.Println("failed")
+		return err
+	}
+	errBytes, err := ocClient.Delete(change.Kind, change.Name, "")
 	if err == nil {
 		fmt.Println("done")
 	} else {
 		fmt.Println("failed")
 		return errors.New(string(errBytes))
 	}
+	if waitTimeout > 0 {
+		return waitForDeletion(change, compareOptions, waitTimeout, ocClient)
+	}
+	return nil
+}
+
+// dryRun submits all changes to the server without persisting them, and
+// reports for each change whether the server accepted it. Unlike the drift
+// calculation, this exercises validation and admission control.
+func dryRun(compareOptions *cli.CompareOptions, c *openshift.Changeset, ocClient cli.ClientModifier) error {
+	rejected := 0
+	report := func(label string, change *openshift.Change, errBytes []byte, err error) {
+		fmt.Printf("%s %s ... ", label, printedItemName(change, compareOptions))
+		if err == nil {
+			fmt.Println("accepted")
+			return
+		}
+		rejected++
+		fmt.Println("rejected")
+		cli.FprintRedf(os.Stdout, "%s\n", strings.TrimSpace(cli.Redact(string(errBytes))))
+	}
+
+	for _, change := range c.Delete {
+		errBytes, err := ocClient.Delete(change.Kind, change.Name, compareOptions.DryRun)
+		report("Deleting", change, errBytes, err)
+	}
+	for _, change := range c.Create {
+		errBytes, err := dryRunApply(change, compareOptions, ocClient)
+		report("Creating", change, errBytes, err)
+	}
+	for _, change := range c.Update {
+		errBytes, err := dryRunApply(change, compareOptions, ocClient)
+		report("Updating", change, errBytes, err)
+	}
+
+	fmt.Printf("\nDry run (%s): nothing has been changed.\n", compareOptions.DryRun)
+	if rejected > 0 {
+		return fmt.Errorf("Server rejected %d change(s)", rejected)
+	}
 	return nil
 }
 
+func dryRunApply(change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) ([]byte, error) {
+	verb, err := applyVerb(change, compareOptions)
+	if err != nil {
+		return []byte(err.Error()), err
+	}
+	return ocClient.Apply(verb, change.DesiredState, compareOptions.Selector, compareOptions.DryRun)
+}
+
+// waitForDeletion polls until the resource targeted by change is gone, or
+// the timeout is exceeded.
+func waitForDeletion(change *openshift.Change, compareOptions *cli.CompareOptions, timeout time.Duration, ocClient cli.ClientModifier) error {
+	fmt.Printf("Waiting for deletion of %s ... ", printedItemName(change, compareOptions))
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := cli.RunContextErr(); err != nil {
+			fmt.Println("failed")
+			return err
+		}
+		exists, err := ocClient.Exists(change.Kind, change.Name)
+		if err != nil {
+			fmt.Println("failed")
+			return err
+		}
+		if !exists {
+			fmt.Println("done")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			fmt.Println("failed")
+			return fmt.Errorf("%s still exists after %s", printedItemName(change, compareOptions), timeout)
+		}
+		time.Sleep(waitForDeletionPollInterval)
+	}
+}
+
 func ocApply(label string, change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error {
-	fmt.Printf("%s %s ... ", label, change.ItemName())
-	errBytes, err := ocClient.Apply(change.DesiredState, compareOptions.Selector)
+	// Resources not selected are usually calculated as updates already with
+	// --adopt. This covers resources created after the drift was calculated.
+	if change.Action == "Create" && compareOptions.Adopt {
+		exists, err := ocClient.Exists(change.Kind, change.Name)
+		if err != nil {
+			return fmt.Errorf("Could not check whether %s exists: %s", printedItemName(change, compareOptions), err)
+		}
+		if exists {
+			// The resource exists, but was not selected (e.g. because it
+			// lacks the selector label). Applying the desired state, which
+			// carries the label, adopts it.
+			label = "Adopting existing"
+		}
+	}
+	verb, err := applyVerb(change, compareOptions)
+	if err != nil {
+		return err
+	}
+	if verb != openshift.ApplyVerbApply {
+		label = fmt.Sprintf("%s (via oc %s)", label, verb)
+	}
+	fmt.Printf("%s %s ... ", label, printedItemName(change, compareOptions))
+	err = checkResourceVersion(change, compareOptions, ocClient)
+	if err != nil {
+		fmt.Println("failed")
+		return err
+	}
+	errBytes, err := ocClient.Apply(verb, change.DesiredState, compareOptions.Selector, "")
 	if err == nil {
 		fmt.Println("done")
 	} else {
 		fmt.Println("failed")
-		return errors.New(string(errBytes))
+		msg := cli.Redact(string(errBytes))
+		if change.Action == "Create" && strings.Contains(msg, "already exists") && !compareOptions.Adopt {
+			msg = msg + "\nThe resource exists but is not selected (e.g. as it lacks the selector label). Pass --adopt to adopt it."
+		}
+		return errors.New(msg)
 	}
 
 	return nil
 }
 
+// applyVerb returns the oc verb used to push the desired state of change.
+func applyVerb(change *openshift.Change, compareOptions *cli.CompareOptions) (string, error) {
+	kindVerbs, err := openshift.ParseApplyVerbs(compareOptions.ApplyVerbs)
+	if err != nil {
+		return "", err
+	}
+	return change.ApplyVerb(kindVerbs)
+}
+
+// checkNotInUse ensures that resources of guarded kinds (e.g. persistent
+// volume claims) are not deleted while active pods use them. With --force,
+// such deletions only cause a warning.
+func checkNotInUse(change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error {
+	if !openshift.UsageGuarded(change.Kind) {
+		return nil
+	}
+	pods, err := ocClient.Pods()
+	if err != nil {
+		return fmt.Errorf("Could not check whether %s is in use: %s", printedItemName(change, compareOptions), err)
+	}
+	using, err := openshift.PodsUsing(pods, change.Kind, change.Name)
+	if err != nil {
+		return fmt.Errorf("Could not check whether %s is in use: %s", printedItemName(change, compareOptions), err)
+	}
+	if len(using) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("%s is in use by pod(s) %s", printedItemName(change, compareOptions), strings.Join(using, ", "))
+	if compareOptions.Force {
+		cli.FprintYellowf(os.Stdout, "(warning: %s) ", msg)
+		return nil
+	}
+	return fmt.Errorf("%s. Refusing to delete it without --force", msg)
+}
+
+// checkResourceVersion ensures that the resource targeted by change has not
+// been modified since the changeset was calculated.
+func checkResourceVersion(change *openshift.Change, compareOptions *cli.CompareOptions, ocClient cli.ClientModifier) error {
+	if !compareOptions.CheckResourceVersion || len(change.ResourceVersion) == 0 {
+		return nil
+	}
+	currentResourceVersion, err := ocClient.ResourceVersion(change.Kind, change.Name)
+	if err != nil {
+		return fmt.Errorf("Could not get resource version of %s: %s", printedItemName(change, compareOptions), err)
+	}
+	if currentResourceVersion != change.ResourceVersion {
+		return fmt.Errorf(
+			"%s has been modified since the changes were calculated (resource version %s, now %s)",
+			printedItemName(change, compareOptions),
+			change.ResourceVersion,
+			currentResourceVersion,
+		)
+	}
+	return nil
+}
+
 func performVerification(compareOptions *cli.CompareOptions, ocClient cli.ClientProcessorExporter) error {
 	var buf bytes.Buffer
 	fmt.Print("\nVerifying current state matches desired state ... ")
-	driftDetected, _, err := calculateChangeset(&buf, compareOptions, ocClient)
+	_, changeset, err := calculateChangeset(&buf, &buf, compareOptions, ocClient)
 	if err != nil {
 		return fmt.Errorf("Error: %s", err)
 	}
-	if driftDetected {
+	// Drift of diff-only resources is expected to remain.
+	applicable, _ := changeset.WithoutDiffOnly()
+	if !applicable.Blank() {
 		fmt.Print("failed! Detected drift:\n\n")
-		fmt.Println(buf.String())
+		fmt.Println(cli.Redact(buf.String()))
 		return errors.New("Verification failed")
 	}
 	fmt.Println("successful")
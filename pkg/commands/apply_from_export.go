@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// ApplyFromExport processes given template (e.g. created by "tailor export")
+// and applies all of its resources to the target namespace as-is. Unlike
+// Apply, no drift is calculated and the selector is not taken into account.
+// A failing resource does not stop the remaining ones from being applied.
+func ApplyFromExport(nonInteractive bool, compareOptions *cli.CompareOptions, file string, ocClient cli.ClientApplier, stdin io.Reader) error {
+	if _, err := os.Stat(file); err != nil {
+		return fmt.Errorf("Could not read template '%s': %s", file, err)
+	}
+	_, err := openshift.ParseApplyVerbs(compareOptions.ApplyVerbs)
+	if err != nil {
+		return err
+	}
+
+	processedOut, err := openshift.ProcessTemplate(
+		filepath.Dir(file),
+		filepath.Base(file),
+		compareOptions.ParamDir,
+		compareOptions,
+		ocClient,
+	)
+	if err != nil {
+		return err
+	}
+	list, err := openshift.NewTemplateBasedResourceList(&openshift.ResourceFilter{}, processedOut)
+	if err != nil {
+		return &openshift.TemplateProcessError{Template: filepath.Base(file), Err: err}
+	}
+	if list.Length() == 0 {
+		fmt.Printf("Template %s contains no resources, nothing to apply.\n", file)
+		return nil
+	}
+
+	// Adding the resources to a changeset orders them by kind, so that e.g.
+	// service accounts are created before the workloads using them.
+	changeset := &openshift.Changeset{}
+	for _, item := range list.Items {
+		changeset.Add(&openshift.Change{
+			Action:       "Create",
+			Kind:         item.Kind,
+			Name:         item.Name,
+			DesiredState: item.YamlConfig(),
+		})
+	}
+
+	fmt.Printf("Applying %d resource(s) from %s to namespace %s:\n", len(changeset.Create), file, compareOptions.Namespace)
+	for _, change := range changeset.Create {
+		fmt.Printf("* %s\n", change.ItemName())
+	}
+	fmt.Println("")
+
+	if !nonInteractive && len(compareOptions.DryRun) == 0 {
+		a, err := cli.AskForAction("Apply all resources?", []string{"y=yes", "n=no"}, bufio.NewReader(stdin))
+		if err != nil {
+			return err
+		}
+		if a != "y" {
+			return errors.New("Apply aborted")
+		}
+		fmt.Println("")
+	}
+
+	failed := 0
+	for _, change := range changeset.Create {
+		fmt.Printf("Applying %s ... ", change.ItemName())
+		verb, err := applyVerb(change, compareOptions)
+		if err != nil {
+			fmt.Println("failed")
+			cli.FprintRedf(os.Stdout, "%s\n", err)
+			failed++
+			continue
+		}
+		// The selector is deliberately not passed, as the exported resources
+		// are applied as-is.
+		errBytes, err := ocClient.Apply(verb, change.DesiredState, "", compareOptions.DryRun)
+		if err != nil {
+			fmt.Println("failed")
+			cli.FprintRedf(os.Stdout, "%s\n", strings.TrimSpace(cli.Redact(string(errBytes))))
+			failed++
+			continue
+		}
+		fmt.Println("done")
+	}
+
+	if len(compareOptions.DryRun) > 0 {
+		fmt.Printf("\nDry run (%s): nothing has been changed.\n", compareOptions.DryRun)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d resource(s) could not be applied", failed, len(changeset.Create))
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestApplyFromExport(t *testing.T) {
+	tests := map[string]struct {
+		nonInteractive bool
+		stdinInput     string
+		wantApplied    int
+		wantErr        bool
+	}{
+		"non-interactively": {
+			nonInteractive: true,
+			wantApplied:    2,
+		},
+		"interactively": {
+			stdinInput:  "y\n",
+			wantApplied: 2,
+		},
+		"interactively aborted": {
+			stdinInput:  "n\n",
+			wantApplied: 0,
+			wantErr:     true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    cli.InitGlobalOptions(&utils.OsFS{}),
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "bar"},
+				ParamDir:         ".",
+				ParamFiles:       []string{},
+			}
+			ocClient := &mockOcApplyClient{
+				t:              t,
+				desiredFixture: "template-dir/desired-list.yml",
+			}
+			var stdin bytes.Buffer
+			stdin.Write([]byte(tc.stdinInput))
+			err := ApplyFromExport(
+				tc.nonInteractive,
+				compareOptions,
+				"../../internal/test/fixtures/command-apply/template-dir/desired-list.yml",
+				ocClient,
+				&stdin,
+			)
+			if tc.wantErr && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatal(err)
+			}
+			if len(ocClient.verbs) != tc.wantApplied {
+				t.Fatalf("Want %d applied resources, got %d", tc.wantApplied, len(ocClient.verbs))
+			}
+		})
+	}
+}
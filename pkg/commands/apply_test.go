@@ -1,18 +1,34 @@
 package commands
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/opendevstack/tailor/internal/test/helper"
 	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
 	"github.com/opendevstack/tailor/pkg/utils"
 )
 
 type mockOcApplyClient struct {
-	t              *testing.T
-	currentFixture string
-	desiredFixture string
+	t               *testing.T
+	currentFixture  string
+	desiredFixture  string
+	resourceVersion string
+	projectExists   bool
+	createdProjects []string
+	dryRuns         []string
+	defaulted       []string
+	verbs           []string
+	pods            string
 }
 
 func (c *mockOcApplyClient) Export(target string, label string) ([]byte, error) {
@@ -23,11 +39,43 @@ func (c *mockOcApplyClient) Process(args []string) ([]byte, []byte, error) {
 	return helper.ReadFixtureFile(c.t, "command-apply/"+c.desiredFixture), []byte(""), nil
 }
 
-func (c *mockOcApplyClient) Apply(config string, selector string) ([]byte, error) {
+func (c *mockOcApplyClient) Default(config string) ([]byte, error) {
+	c.defaulted = append(c.defaulted, config)
+	return []byte(config), nil
+}
+
+func (c *mockOcApplyClient) Apply(verb string, config string, selector string, dryRun string) ([]byte, error) {
+	c.verbs = append(c.verbs, verb)
+	c.dryRuns = append(c.dryRuns, dryRun)
+	return []byte(""), nil
+}
+
+func (c *mockOcApplyClient) Delete(kind string, name string, dryRun string) ([]byte, error) {
+	c.dryRuns = append(c.dryRuns, dryRun)
 	return []byte(""), nil
 }
 
-func (c *mockOcApplyClient) Delete(kind string, name string) ([]byte, error) {
+func (c *mockOcApplyClient) ResourceVersion(kind string, name string) (string, error) {
+	return c.resourceVersion, nil
+}
+
+func (c *mockOcApplyClient) Exists(kind string, name string) (bool, error) {
+	return false, nil
+}
+
+func (c *mockOcApplyClient) Pods() ([]byte, error) {
+	if len(c.pods) == 0 {
+		return []byte(`{"items": []}`), nil
+	}
+	return []byte(c.pods), nil
+}
+
+func (c *mockOcApplyClient) CheckProjectExists(p string) (bool, error) {
+	return c.projectExists, nil
+}
+
+func (c *mockOcApplyClient) CreateProject(p string) ([]byte, error) {
+	c.createdProjects = append(c.createdProjects, p)
 	return []byte(""), nil
 }
 
@@ -90,3 +138,602 @@ func TestApply(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyCheckResourceVersion(t *testing.T) {
+	tests := map[string]struct {
+		resourceVersion string
+		wantError       bool
+	}{
+		"unchanged resource version": {
+			resourceVersion: "375626209",
+			wantError:       false,
+		},
+		"changed resource version": {
+			resourceVersion: "375626210",
+			wantError:       true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:        globalOptions,
+				NamespaceOptions:     &cli.NamespaceOptions{Namespace: "foo"},
+				TemplateDir:          "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:           []string{},
+				CheckResourceVersion: true,
+				Excludes:             []string{"is"},
+			}
+			ocClient := &mockOcApplyClient{
+				currentFixture:  "current-list.yml",
+				desiredFixture:  "template-dir/desired-list.yml",
+				resourceVersion: tc.resourceVersion,
+			}
+			var stdin bytes.Buffer
+			_, err := Apply(true, compareOptions, ocClient, &stdin)
+			if tc.wantError && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			if !tc.wantError && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestApplyDryRun(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:       []string{},
+		DryRun:           "server",
+	}
+	ocClient := &mockOcApplyClient{
+		currentFixture: "current-list.yml",
+		desiredFixture: "template-dir/desired-list.yml",
+	}
+	var stdin bytes.Buffer
+	drift, err := Apply(false, compareOptions, ocClient, &stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drift {
+		t.Fatal("Want drift as nothing has been persisted")
+	}
+	if len(ocClient.dryRuns) == 0 {
+		t.Fatal("Want changes to be submitted to the server")
+	}
+	for _, dryRun := range ocClient.dryRuns {
+		if dryRun != "server" {
+			t.Fatalf("Want all changes submitted with dry run 'server', got '%s'", dryRun)
+		}
+	}
+}
+
+func TestApplyDiffOnly(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:       []string{},
+		DiffOnly:         []string{"bc/foo"},
+	}
+	ocClient := &mockOcApplyClient{
+		currentFixture: "current-list.yml",
+		desiredFixture: "template-dir/desired-list.yml",
+	}
+	var stdin bytes.Buffer
+	drift, err := Apply(true, compareOptions, ocClient, &stdin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drift {
+		t.Fatal("Want drift as diff-only changes are not applied")
+	}
+	// Only is/foo is applied, bc/foo is diff-only.
+	if len(ocClient.dryRuns) != 1 {
+		t.Fatalf("Want 1 change to be applied, got %d", len(ocClient.dryRuns))
+	}
+}
+
+func TestApplyHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	hookOutput := filepath.Join(dir, "pre-apply")
+
+	tests := map[string]struct {
+		preApplyHook  string
+		postApplyHook string
+		wantError     bool
+		wantApplied   bool
+	}{
+		"successful hooks": {
+			preApplyHook:  "echo $TAILOR_NAMESPACE > " + hookOutput,
+			postApplyHook: "true",
+			wantError:     false,
+			wantApplied:   true,
+		},
+		"failing pre-apply hook aborts": {
+			preApplyHook:  "exit 1",
+			postApplyHook: "true",
+			wantError:     true,
+			wantApplied:   false,
+		},
+		"failing post-apply hook is reported only": {
+			preApplyHook:  "true",
+			postApplyHook: "exit 1",
+			wantError:     false,
+			wantApplied:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:       []string{},
+				PreApplyHook:     tc.preApplyHook,
+				PostApplyHook:    tc.postApplyHook,
+			}
+			ocClient := &mockOcApplyClient{
+				currentFixture: "current-list.yml",
+				desiredFixture: "template-dir/desired-list.yml",
+			}
+			var stdin bytes.Buffer
+			_, err := Apply(true, compareOptions, ocClient, &stdin)
+			if tc.wantError && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			if !tc.wantError && err != nil {
+				t.Fatal(err)
+			}
+			applied := len(ocClient.dryRuns) > 0
+			if applied != tc.wantApplied {
+				t.Fatalf("Want changes applied=%t, got %t", tc.wantApplied, applied)
+			}
+		})
+	}
+
+	b, err := ioutil.ReadFile(hookOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(b)); got != "foo" {
+		t.Fatalf("Want TAILOR_NAMESPACE 'foo' in hook, got '%s'", got)
+	}
+}
+
+func TestAskAndApplyRunsPreApplyHookLazily(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := map[string]struct {
+		input       string
+		wantHookRun bool
+		wantApplied int
+	}{
+		"all changes skipped": {
+			input:       "n\nn\n",
+			wantHookRun: false,
+			wantApplied: 0,
+		},
+		"second change applied": {
+			input:       "n\ny\n",
+			wantHookRun: true,
+			wantApplied: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			hookOutput := filepath.Join(dir, strings.Replace(name, " ", "-", -1))
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				PreApplyHook:     "touch " + hookOutput,
+			}
+			ocClient := &mockOcApplyClient{}
+			changes := []*openshift.Change{
+				{Action: "Delete", Kind: "ConfigMap", Name: "foo"},
+				{Action: "Delete", Kind: "ConfigMap", Name: "bar"},
+			}
+			hookRan := false
+			beforeApply := func() error {
+				if hookRan {
+					return nil
+				}
+				hookRan = true
+				return runPreApplyHook(compareOptions)
+			}
+			stdinReader := bufio.NewReader(strings.NewReader(tc.input))
+			_, err := askAndApply(compareOptions, ocClient, nil, stdinReader, beforeApply, changes, printDeleteChange, "Deleting", ocDelete)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = os.Stat(hookOutput)
+			if hookRun := err == nil; hookRun != tc.wantHookRun {
+				t.Fatalf("Want pre-apply hook run=%t, got %t", tc.wantHookRun, hookRun)
+			}
+			if len(ocClient.dryRuns) != tc.wantApplied {
+				t.Fatalf("Want %d changes applied, got %d", tc.wantApplied, len(ocClient.dryRuns))
+			}
+		})
+	}
+}
+
+func TestApplyTimeout(t *testing.T) {
+	tests := map[string]struct {
+		timeout      time.Duration
+		preApplyHook string
+	}{
+		"hook is killed": {
+			timeout: 200 * time.Millisecond,
+			// exec replaces the shell, so that no orphaned child process
+			// keeps the output open once the shell is killed.
+			preApplyHook: "exec sleep 10",
+		},
+		"no change is applied after timeout": {
+			timeout:      time.Nanosecond,
+			preApplyHook: "",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:       []string{},
+				PreApplyHook:     tc.preApplyHook,
+			}
+			ocClient := &mockOcApplyClient{}
+			changeset := &openshift.Changeset{}
+			changeset.Add(
+				&openshift.Change{Action: "Delete", Kind: "ConfigMap", Name: "foo"},
+				&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "bar"},
+			)
+			cli.InitRunContext(tc.timeout)
+			defer cli.InitRunContext(0)
+			start := time.Now()
+			err := runPreApplyHook(compareOptions)
+			if err == nil {
+				err = apply(compareOptions, changeset, ocClient, nil)
+			}
+			var timeoutErr *cli.TimeoutError
+			if !errors.As(err, &timeoutErr) {
+				t.Fatalf("Want TimeoutError, got: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("Want abort once timeout is exceeded, took %s", elapsed)
+			}
+			if len(ocClient.dryRuns) > 0 {
+				t.Fatal("Want no change applied after timeout")
+			}
+		})
+	}
+}
+
+func TestApplyContextNamespaceMismatch(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		wantError   bool
+		wantApplied bool
+	}{
+		"confirmed": {
+			input:       "y\ny\n",
+			wantError:   false,
+			wantApplied: true,
+		},
+		"declined": {
+			input:       "n\n",
+			wantError:   true,
+			wantApplied: false,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions: globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{
+					Namespace:        "foo",
+					ContextNamespace: "bar",
+				},
+				TemplateDir: "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:  []string{},
+			}
+			ocClient := &mockOcApplyClient{
+				currentFixture: "current-list.yml",
+				desiredFixture: "template-dir/desired-list.yml",
+			}
+			stdin := bytes.NewBufferString(tc.input)
+			_, err := Apply(false, compareOptions, ocClient, stdin)
+			if tc.wantError && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			applied := len(ocClient.dryRuns) > 0
+			if applied != tc.wantApplied {
+				t.Fatalf("Want changes applied=%t, got %t", tc.wantApplied, applied)
+			}
+		})
+	}
+}
+
+func TestBatchThrottle(t *testing.T) {
+	tests := map[string]struct {
+		size       int
+		delay      time.Duration
+		changes    int
+		wantSleeps int
+	}{
+		"no batching": {
+			size:       0,
+			delay:      time.Second,
+			changes:    5,
+			wantSleeps: 0,
+		},
+		"batches without delay": {
+			size:       2,
+			delay:      0,
+			changes:    5,
+			wantSleeps: 0,
+		},
+		"batches with delay": {
+			size:       2,
+			delay:      time.Second,
+			changes:    5,
+			wantSleeps: 2,
+		},
+		"exactly one batch": {
+			size:       2,
+			delay:      time.Second,
+			changes:    2,
+			wantSleeps: 0,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			sleeps := 0
+			throttle := newBatchThrottle(tc.size, tc.delay)
+			throttle.sleep = func(d time.Duration) { sleeps++ }
+			for i := 0; i < tc.changes; i++ {
+				throttle.wait()
+			}
+			if sleeps != tc.wantSleeps {
+				t.Fatalf("Want %d sleeps, got %d", tc.wantSleeps, sleeps)
+			}
+		})
+	}
+}
+
+func TestEnsureNamespace(t *testing.T) {
+	tests := map[string]struct {
+		projectExists bool
+		wantCreated   []string
+	}{
+		"existing project": {
+			projectExists: true,
+			wantCreated:   nil,
+		},
+		"missing project": {
+			projectExists: false,
+			wantCreated:   []string{"foo"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ocClient := &mockOcApplyClient{projectExists: tc.projectExists}
+			err := ensureNamespace("foo", ocClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantCreated, ocClient.createdProjects); diff != "" {
+				t.Fatalf("Created projects mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+type mockOcDeletionClient struct {
+	mockOcApplyClient
+	remainingExistsCalls int
+}
+
+func (c *mockOcDeletionClient) Exists(kind string, name string) (bool, error) {
+	c.remainingExistsCalls--
+	return c.remainingExistsCalls > 0, nil
+}
+
+func TestWaitForDeletion(t *testing.T) {
+	waitForDeletionPollInterval = time.Millisecond
+	tests := map[string]struct {
+		existsCalls int
+		timeout     time.Duration
+		wantError   bool
+	}{
+		"gone immediately": {
+			existsCalls: 1,
+			timeout:     time.Second,
+			wantError:   false,
+		},
+		"gone after a while": {
+			existsCalls: 3,
+			timeout:     time.Second,
+			wantError:   false,
+		},
+		"not gone before timeout": {
+			existsCalls: 1000000,
+			timeout:     10 * time.Millisecond,
+			wantError:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ocClient := &mockOcDeletionClient{remainingExistsCalls: tc.existsCalls}
+			change := &openshift.Change{Kind: "PersistentVolumeClaim", Name: "foo"}
+			err := waitForDeletion(change, &cli.CompareOptions{}, tc.timeout, ocClient)
+			if tc.wantError && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			if !tc.wantError && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+type mockOcExistingClient struct {
+	mockOcApplyClient
+	existsCalls int
+}
+
+func (c *mockOcExistingClient) Exists(kind string, name string) (bool, error) {
+	c.existsCalls++
+	return true, nil
+}
+
+func (c *mockOcExistingClient) Apply(verb string, config string, selector string, dryRun string) ([]byte, error) {
+	c.dryRuns = append(c.dryRuns, dryRun)
+	return []byte("Error from server (AlreadyExists): configmaps \"foo\" already exists"), errors.New("exit status 1")
+}
+
+func TestOcApplyAdopt(t *testing.T) {
+	tests := map[string]struct {
+		adopt           bool
+		wantExistsCalls int
+		wantHint        bool
+	}{
+		"without adopting": {
+			adopt:           false,
+			wantExistsCalls: 0,
+			wantHint:        true,
+		},
+		"with adopting": {
+			adopt:           true,
+			wantExistsCalls: 1,
+			wantHint:        false,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    cli.InitGlobalOptions(&utils.OsFS{}),
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				Adopt:            tc.adopt,
+			}
+			ocClient := &mockOcExistingClient{}
+			change := &openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "foo"}
+			err := ocApply("Creating", change, compareOptions, ocClient)
+			if err == nil {
+				t.Fatal("Want error from mock, got none")
+			}
+			if ocClient.existsCalls != tc.wantExistsCalls {
+				t.Fatalf("Want %d exists calls, got %d", tc.wantExistsCalls, ocClient.existsCalls)
+			}
+			if len(ocClient.dryRuns) != 1 {
+				t.Fatalf("Want resource to be applied once, got %d", len(ocClient.dryRuns))
+			}
+			gotHint := strings.Contains(err.Error(), "Pass --adopt")
+			if gotHint != tc.wantHint {
+				t.Fatalf("Want hint=%t, got error: %s", tc.wantHint, err)
+			}
+		})
+	}
+}
+
+func TestOcApplyVerb(t *testing.T) {
+	tests := map[string]struct {
+		applyVerbs []string
+		annotation string
+		wantVerb   string
+	}{
+		"default": {
+			wantVerb: "apply",
+		},
+		"per kind": {
+			applyVerbs: []string{"cm:create"},
+			wantVerb:   "create",
+		},
+		"annotation wins": {
+			applyVerbs: []string{"cm:create"},
+			annotation: "replace",
+			wantVerb:   "replace",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    cli.InitGlobalOptions(&utils.OsFS{}),
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				ApplyVerbs:       tc.applyVerbs,
+			}
+			desiredState := "kind: ConfigMap\nmetadata:\n  name: foo\n"
+			if len(tc.annotation) > 0 {
+				desiredState += "  annotations:\n    " + openshift.ApplyVerbAnnotation + ": " + tc.annotation + "\n"
+			}
+			ocClient := &mockOcApplyClient{}
+			change := &openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "foo", DesiredState: desiredState}
+			err := ocApply("Creating", change, compareOptions, ocClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ocClient.verbs) != 1 || ocClient.verbs[0] != tc.wantVerb {
+				t.Fatalf("Want verb %s, got %v", tc.wantVerb, ocClient.verbs)
+			}
+		})
+	}
+}
+
+func TestCheckNotInUse(t *testing.T) {
+	pods := `{"items": [{"metadata": {"name": "foo-1"}, "spec": {"volumes": [{"persistentVolumeClaim": {"claimName": "foo"}}]}, "status": {"phase": "Running"}}]}`
+	tests := map[string]struct {
+		kind      string
+		force     bool
+		wantError bool
+	}{
+		"unguarded kind": {
+			kind:      "ConfigMap",
+			wantError: false,
+		},
+		"in use": {
+			kind:      "PersistentVolumeClaim",
+			wantError: true,
+		},
+		"in use with force": {
+			kind:      "PersistentVolumeClaim",
+			force:     true,
+			wantError: false,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			globalOptions.Force = tc.force
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+			}
+			ocClient := &mockOcApplyClient{pods: pods}
+			change := &openshift.Change{Action: "Delete", Kind: tc.kind, Name: "foo"}
+			err := checkNotInUse(change, compareOptions, ocClient)
+			if tc.wantError && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			if !tc.wantError && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time      string `json:"time"`
+	Namespace string `json:"namespace"`
+	Event
+}
+
+// auditLog appends one line of JSON per applied change to a file, so that it
+// can be traced later which changes were applied when, and as part of which
+// change ID.
+type auditLog struct {
+	file      *os.File
+	namespace string
+	now       func() time.Time
+}
+
+// openAuditLog opens the audit log given via --audit-log. It returns nil if
+// no audit log is configured.
+func openAuditLog(compareOptions *cli.CompareOptions) (*auditLog, error) {
+	if len(compareOptions.AuditLog) == 0 {
+		return nil, nil
+	}
+	f, err := os.OpenFile(compareOptions.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open audit log '%s': %s", compareOptions.AuditLog, err)
+	}
+	return &auditLog{file: f, namespace: compareOptions.Namespace, now: time.Now}, nil
+}
+
+// record appends e to the audit log.
+func (a *auditLog) record(e Event) error {
+	if a == nil {
+		return nil
+	}
+	b, err := json.Marshal(auditEntry{
+		Time:      a.now().UTC().Format(time.RFC3339),
+		Namespace: a.namespace,
+		Event:     e,
+	})
+	if err != nil {
+		return fmt.Errorf("Could not encode audit log entry: %s", err)
+	}
+	_, err = fmt.Fprintln(a.file, cli.Redact(string(b)))
+	if err != nil {
+		return fmt.Errorf("Could not write audit log '%s': %s", a.file.Name(), err)
+	}
+	return nil
+}
+
+func (a *auditLog) close() {
+	if a != nil {
+		a.file.Close()
+	}
+}
+
+// recordApplied emits an event for change, which was applied with given
+// result, and appends it to the audit log.
+func recordApplied(compareOptions *cli.CompareOptions, audit *auditLog, change *openshift.Change, err error) error {
+	event := Event{
+		Type:     EventApplied,
+		Action:   change.Action,
+		Kind:     change.Kind,
+		Name:     change.Name,
+		ChangeID: compareOptions.ChangeID,
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	// The audit log keeps the name, as it is not printed.
+	printedEvent := event
+	if isMaskedSecret(change, compareOptions) {
+		printedEvent.Name = maskedSecretName
+	}
+	emitEvent(compareOptions, printedEvent)
+	return audit.record(event)
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+func TestApplyRecordsChangeID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    &cli.GlobalOptions{},
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		ChangeID:         "CHG-123",
+		AuditLog:         filepath.Join(dir, "audit.log"),
+		Events:           true,
+	}
+	changeset := &openshift.Changeset{}
+	changeset.Add(
+		&openshift.Change{Action: "Delete", Kind: "ConfigMap", Name: "foo"},
+		&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "bar", DesiredState: "kind: ConfigMap\n"},
+	)
+
+	var buf bytes.Buffer
+	stdout := eventsOutput
+	eventsOutput = &buf
+	defer func() { eventsOutput = stdout }()
+	audit, err := openAuditLog(compareOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit.now = func() time.Time { return time.Date(2020, 7, 20, 10, 0, 0, 0, time.UTC) }
+	err = apply(compareOptions, changeset, &mockOcApplyClient{t: t}, audit)
+	audit.close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantEvents := `{"type":"applied","action":"Delete","kind":"ConfigMap","name":"foo","changeId":"CHG-123"}
+{"type":"applied","action":"Create","kind":"ConfigMap","name":"bar","changeId":"CHG-123"}
+`
+	if buf.String() != wantEvents {
+		t.Fatalf("Want events:\n%s\ngot:\n%s", wantEvents, buf.String())
+	}
+	b, err := ioutil.ReadFile(compareOptions.AuditLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAuditLog := `{"time":"2020-07-20T10:00:00Z","namespace":"foo","type":"applied","action":"Delete","kind":"ConfigMap","name":"foo","changeId":"CHG-123"}
+{"time":"2020-07-20T10:00:00Z","namespace":"foo","type":"applied","action":"Create","kind":"ConfigMap","name":"bar","changeId":"CHG-123"}
+`
+	if string(b) != wantAuditLog {
+		t.Fatalf("Want audit log:\n%s\ngot:\n%s", wantAuditLog, string(b))
+	}
+}
+
+func TestOpenAuditLogDisabled(t *testing.T) {
+	audit, err := openAuditLog(&cli.CompareOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audit != nil {
+		t.Fatal("Want no audit log if none is configured")
+	}
+	// A missing audit log is ignored when recording.
+	if err := audit.record(Event{Type: EventApplied}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordAppliedMasksSecretName(t *testing.T) {
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    &cli.GlobalOptions{},
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		Events:           true,
+		MaskSecrets:      true,
+	}
+	var buf bytes.Buffer
+	stdout := eventsOutput
+	eventsOutput = &buf
+	defer func() { eventsOutput = stdout }()
+	change := &openshift.Change{Action: "Create", Kind: "Secret", Name: "bar"}
+	err := recordApplied(compareOptions, nil, change, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"applied","action":"Create","kind":"Secret","name":"***"}
+`
+	if buf.String() != want {
+		t.Fatalf("Want event:\n%s\ngot:\n%s", want, buf.String())
+	}
+	if got := printedItemName(change, compareOptions); got != "secret/***" {
+		t.Fatalf("Want masked item name, got %s", got)
+	}
+}
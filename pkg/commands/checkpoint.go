@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// checkpointFile is the file in the working directory in which apply
+// records its progress when --resume is given.
+var checkpointFile = ".tailor-apply-checkpoint.json"
+
+// checkpoint records which changes of a changeset have been applied already.
+// It is only valid for the exact changeset it was created for.
+type checkpoint struct {
+	Namespace string          `json:"namespace"`
+	Changes   []string        `json:"changes"`
+	Applied   map[string]bool `json:"applied"`
+}
+
+// changeKey identifies a change including its desired state, so that a
+// checkpoint is invalidated if the desired state changes in between runs.
+func changeKey(change *openshift.Change) string {
+	sum := sha256.Sum256([]byte(change.DesiredState))
+	return change.Action + ":" + change.ItemName() + ":" + hex.EncodeToString(sum[:8])
+}
+
+func changesetKeys(c *openshift.Changeset) []string {
+	keys := []string{}
+	for _, changes := range [][]*openshift.Change{c.Delete, c.Create, c.Update} {
+		for _, change := range changes {
+			keys = append(keys, changeKey(change))
+		}
+	}
+	return keys
+}
+
+// loadCheckpoint reads the checkpoint file and returns it if it belongs to
+// given changeset. Otherwise a fresh checkpoint is returned.
+func loadCheckpoint(namespace string, c *openshift.Changeset) *checkpoint {
+	fresh := &checkpoint{Namespace: namespace, Changes: changesetKeys(c), Applied: map[string]bool{}}
+	b, err := ioutil.ReadFile(checkpointFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			cli.PrintYellowf("Could not read checkpoint %s: %s\n", checkpointFile, err)
+		}
+		return fresh
+	}
+	previous := &checkpoint{}
+	err = json.Unmarshal(b, previous)
+	if err != nil {
+		cli.PrintYellowf("Ignoring checkpoint %s as it could not be parsed: %s\n", checkpointFile, err)
+		return fresh
+	}
+	if previous.Namespace != namespace || !previous.covers(fresh.Changes) {
+		cli.PrintYellowf("Ignoring checkpoint %s as the changeset has changed since it was written.\n", checkpointFile)
+		return fresh
+	}
+	if previous.Applied == nil {
+		previous.Applied = map[string]bool{}
+	}
+	return previous
+}
+
+// covers returns true if every given key is part of the recorded changeset
+// (either still pending or already applied).
+func (cp *checkpoint) covers(keys []string) bool {
+	recorded := map[string]bool{}
+	for _, k := range cp.Changes {
+		recorded[k] = true
+	}
+	for _, k := range keys {
+		if !recorded[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// done records the change as applied and persists the checkpoint.
+func (cp *checkpoint) done(change *openshift.Change) error {
+	cp.Applied[changeKey(change)] = true
+	b, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(checkpointFile, b, 0644)
+	if err != nil {
+		return fmt.Errorf("Could not write checkpoint %s: %s", checkpointFile, err)
+	}
+	return nil
+}
+
+func (cp *checkpoint) remove() {
+	err := os.Remove(checkpointFile)
+	if err != nil && !os.IsNotExist(err) {
+		cli.PrintYellowf("Could not remove checkpoint %s: %s\n", checkpointFile, err)
+	}
+}
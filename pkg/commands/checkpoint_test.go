@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpointFile = filepath.Join(dir, "checkpoint.json")
+
+	foo := &openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "foo", DesiredState: "foo: a"}
+	bar := &openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "bar", DesiredState: "bar: a"}
+	changeset := &openshift.Changeset{Create: []*openshift.Change{foo, bar}}
+
+	cp := loadCheckpoint("foo", changeset)
+	if len(cp.Applied) != 0 {
+		t.Fatalf("Want fresh checkpoint, got %d applied changes", len(cp.Applied))
+	}
+	err = cp.done(foo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A rerun only has the remaining change, which is covered.
+	rerun := &openshift.Changeset{Create: []*openshift.Change{bar}}
+	cp = loadCheckpoint("foo", rerun)
+	if !cp.Applied[changeKey(foo)] {
+		t.Fatal("Want foo to be recorded as applied")
+	}
+
+	// A change of the desired state invalidates the checkpoint.
+	changedBar := &openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "bar", DesiredState: "bar: b"}
+	changed := &openshift.Changeset{Create: []*openshift.Change{foo, changedBar}}
+	cp = loadCheckpoint("foo", changed)
+	if len(cp.Applied) != 0 {
+		t.Fatal("Want checkpoint to be invalidated when the changeset changes")
+	}
+
+	// Another namespace invalidates the checkpoint.
+	cp = loadCheckpoint("bar", changeset)
+	if len(cp.Applied) != 0 {
+		t.Fatal("Want checkpoint to be invalidated for another namespace")
+	}
+
+	cp.remove()
+	if _, err := os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Fatal("Want checkpoint file to be removed")
+	}
+}
@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
+	"strings"
 
+	"github.com/fatih/color"
 	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/openshift"
 )
@@ -15,22 +20,267 @@ import (
 // Diff prints the drift between desired and current state to STDOUT.
 func Diff(compareOptions *cli.CompareOptions) (bool, error) {
 	ocClient := cli.NewOcClient(compareOptions.Namespace)
-	var buf bytes.Buffer
-	driftDetected, _, err := calculateChangeset(&buf, compareOptions, ocClient)
-	fmt.Print(buf.String())
+	if len(compareOptions.ShowDesired) > 0 {
+		return false, showDesired(os.Stdout, compareOptions, ocClient)
+	}
+	if len(compareOptions.CompareNamespaces) > 0 {
+		return diffNamespaces(os.Stdout, compareOptions, func(namespace string) cli.ClientProcessorExporter {
+			return cli.NewOcClient(namespace)
+		})
+	}
+	if compareOptions.Events {
+		restore, err := suppressHumanOutput()
+		if err != nil {
+			return false, err
+		}
+		defer restore()
+	}
+	var buf, warnings bytes.Buffer
+	// When the human-readable output is not printed, warnings (e.g. about
+	// deprecated apiVersions) are still shown on STDERR.
+	humanReadable := compareOptions.Output != "html" && compareOptions.Output != "merge-patch" &&
+		!compareOptions.SummaryOnly && !compareOptions.Events
+	var warningsOutput io.Writer = &warnings
+	if humanReadable {
+		warningsOutput = &buf
+	}
+	driftDetected, changeset, err := calculateChangeset(&buf, warningsOutput, compareOptions, ocClient)
+	fmt.Fprint(os.Stderr, cli.Redact(warnings.String()))
+	if compareOptions.Output == "html" {
+		if err != nil {
+			fmt.Fprint(os.Stderr, cli.Redact(buf.String()))
+			return driftDetected, err
+		}
+		err = printHTMLDiff(os.Stdout, changeset, compareOptions)
+	} else if compareOptions.Output == "merge-patch" {
+		if err != nil {
+			fmt.Fprint(os.Stderr, cli.Redact(buf.String()))
+			return driftDetected, err
+		}
+		err = printMergePatches(os.Stdout, changeset, compareOptions.RevealSecrets)
+	} else if compareOptions.SummaryOnly {
+		if err != nil {
+			fmt.Fprint(os.Stderr, cli.Redact(buf.String()))
+			return driftDetected, err
+		}
+		printSummary(os.Stdout, changeset, compareOptions)
+	} else if compareOptions.Events {
+		if err != nil {
+			fmt.Fprint(os.Stderr, cli.Redact(buf.String()))
+			return driftDetected, err
+		}
+	} else {
+		fmt.Print(cli.Redact(buf.String()))
+	}
+	if err == nil && len(compareOptions.GitLabMRNote) > 0 {
+		// Stderr is used as STDOUT might be consumed (e.g. the HTML diff).
+		err = postGitLabMRNote(os.Stderr, changeset, compareOptions, compareOptions.GitLabMRNote == "required")
+	}
+	if err == nil && compareOptions.StrictOwnership {
+		err = checkOwnership(changeset)
+	}
 	return driftDetected, err
 }
 
-func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClient cli.ClientProcessorExporter) (bool, *openshift.Changeset, error) {
+// diffNamespaces compares the templates against each namespace given via
+// --compare-namespace, e.g. to verify that shared resources are identical
+// across a fleet of namespaces. Drift is reported per namespace.
+func diffNamespaces(w io.Writer, compareOptions *cli.CompareOptions, newClient func(namespace string) cli.ClientProcessorExporter) (bool, error) {
+	results := []namespaceResult{}
+	for _, namespace := range compareOptions.CompareNamespaces {
+		if err := cli.RunContextErr(); err != nil {
+			return true, err
+		}
+		namespaceOptions := *compareOptions
+		namespaceOptions.NamespaceOptions = &cli.NamespaceOptions{Namespace: namespace}
+		var buf, warnings bytes.Buffer
+		var warningsOutput io.Writer = &buf
+		if compareOptions.SummaryOnly {
+			warningsOutput = &warnings
+		}
+		_, changeset, err := calculateChangeset(&buf, warningsOutput, &namespaceOptions, newClient(namespace))
+		if compareOptions.SummaryOnly {
+			fmt.Fprint(os.Stderr, cli.Redact(warnings.String()))
+			if err != nil {
+				fmt.Fprint(os.Stderr, cli.Redact(buf.String()))
+				return true, err
+			}
+			fmt.Fprintf(w, "namespace=%s ", namespace)
+			printSummary(w, changeset, compareOptions)
+		} else {
+			fmt.Fprint(w, cli.Redact(buf.String()))
+		}
+		if err == nil && compareOptions.StrictOwnership {
+			err = checkOwnership(changeset)
+		}
+		// Machine-readable output stops at the first failure, whereas the
+		// text output continues so that the roll-up covers all namespaces.
+		if err != nil && compareOptions.SummaryOnly {
+			return true, fmt.Errorf("Namespace %s: %s", namespace, err)
+		}
+		result := namespaceResult{namespace: namespace, err: err}
+		if err == nil {
+			result.changes = len(changeset.Create) + len(changeset.Update) + len(changeset.Delete)
+		}
+		results = append(results, result)
+	}
+
+	driftedNamespaces := []string{}
+	failedNamespaces := []string{}
+	for _, r := range results {
+		if r.err != nil {
+			failedNamespaces = append(failedNamespaces, r.namespace)
+		} else if r.changes > 0 {
+			driftedNamespaces = append(driftedNamespaces, r.namespace)
+		}
+	}
+	if compareOptions.SummaryOnly {
+		return len(driftedNamespaces) > 0, nil
+	}
+
+	printNamespaceResults(w, results)
+	if len(driftedNamespaces) > 0 {
+		cli.FprintYellowf(
+			w,
+			"Drift detected in %d of %d namespaces: %s\n",
+			len(driftedNamespaces),
+			len(compareOptions.CompareNamespaces),
+			strings.Join(driftedNamespaces, ", "),
+		)
+	} else if len(failedNamespaces) == 0 {
+		fmt.Fprintf(w, "All %d namespaces are in sync.\n", len(compareOptions.CompareNamespaces))
+	}
+	if len(failedNamespaces) > 0 {
+		return true, fmt.Errorf(
+			"Diff failed in %d of %d namespaces: %s",
+			len(failedNamespaces),
+			len(compareOptions.CompareNamespaces),
+			strings.Join(failedNamespaces, ", "),
+		)
+	}
+	return len(driftedNamespaces) > 0, nil
+}
+
+// namespaceResult is the outcome of comparing one of multiple namespaces.
+type namespaceResult struct {
+	namespace string
+	changes   int
+	err       error
+}
+
+// printNamespaceResults prints one line per namespace with its result (in
+// sync, number of changes or error), so that the outcome of comparing many
+// namespaces can be seen at a glance.
+func printNamespaceResults(w io.Writer, results []namespaceResult) {
+	width := 0
+	for _, r := range results {
+		if len(r.namespace) > width {
+			width = len(r.namespace)
+		}
+	}
+	fmt.Fprintln(w, "Result per namespace:")
+	for _, r := range results {
+		fmt.Fprintf(w, "  %-*s  ", width, r.namespace)
+		if r.err != nil {
+			cli.FprintRedf(w, "error: %s\n", strings.SplitN(r.err.Error(), "\n", 2)[0])
+		} else if r.changes == 0 {
+			cli.FprintGreenf(w, "in sync\n")
+		} else {
+			changesWord := "changes"
+			if r.changes == 1 {
+				changesWord = "change"
+			}
+			cli.FprintYellowf(w, "%d %s\n", r.changes, changesWord)
+		}
+	}
+	fmt.Fprintln(w, "")
+}
+
+// printSummary prints the number of changes per action as key=value pairs
+// on one line, which is easy to parse in scripts.
+// Masked Secret changes are reported as one additional count.
+func printSummary(w io.Writer, changeset *openshift.Changeset, compareOptions *cli.CompareOptions) {
+	printed, maskedSecrets := maskSecrets(changeset, compareOptions)
+	fmt.Fprintf(
+		w,
+		"create=%d update=%d delete=%d noop=%d",
+		len(printed.Create),
+		len(printed.Update),
+		len(printed.Delete),
+		len(printed.Noop),
+	)
+	if pruned := printed.PrunedDeletions(); pruned > 0 {
+		fmt.Fprintf(w, " pruned=%d", pruned)
+	}
+	if maskedSecrets > 0 {
+		fmt.Fprintf(w, " masked-secrets=%d", maskedSecrets)
+	}
+	fmt.Fprintln(w)
+}
+
+// UnmanagedResourcesError is returned by Diff with --strict-ownership if
+// resources exist in the cluster which are not defined in the templates.
+type UnmanagedResourcesError struct {
+	Resources []string
+}
+
+func (e *UnmanagedResourcesError) Error() string {
+	return fmt.Sprintf(
+		"Found %d resource(s) in the cluster which are not defined in the templates:\n* %s",
+		len(e.Resources),
+		strings.Join(e.Resources, "\n* "),
+	)
+}
+
+// checkOwnership returns an error listing all resources which exist in the
+// cluster but are not defined in the templates. Ownership is decided by
+// matching the current against the desired state, so deletions which are part
+// of a recreation or caused by --prune do not count, whereas resources which
+// are not deleted (e.g. because of --upsert-only) do.
+func checkOwnership(changeset *openshift.Changeset) error {
+	if len(changeset.Unmanaged) == 0 {
+		return nil
+	}
+	return &UnmanagedResourcesError{Resources: changeset.Unmanaged}
+}
+
+// calculateChangeset compares the templates with the current state, and
+// prints the drift to w. Warnings are printed to warnings, which is usually
+// the same writer as w.
+func calculateChangeset(w io.Writer, warnings io.Writer, compareOptions *cli.CompareOptions, ocClient cli.ClientProcessorExporter) (bool, *openshift.Changeset, error) {
 	updateRequired := false
 
 	where := compareOptions.TemplateDir
 
-	fmt.Fprintf(w,
-		"Comparing templates in %s with OCP namespace %s.\n",
-		where,
-		compareOptions.Namespace,
-	)
+	if len(compareOptions.RemoteFile) > 0 {
+		fmt.Fprintf(w,
+			"Comparing templates in %s with resources in %s (namespace %s).\n",
+			where,
+			compareOptions.RemoteFile,
+			compareOptions.Namespace,
+		)
+	} else if len(compareOptions.RemoteCmd) > 0 {
+		fmt.Fprintf(w,
+			"Comparing templates in %s with resources printed by '%s' (namespace %s).\n",
+			where,
+			compareOptions.RemoteCmd,
+			compareOptions.Namespace,
+		)
+	} else {
+		fmt.Fprintf(w,
+			"Comparing templates in %s with OCP namespace %s.\n",
+			where,
+			compareOptions.Namespace,
+		)
+	}
+
+	if len(compareOptions.ContextNamespace) > 0 {
+		cli.FprintYellowf(warnings,
+			"Warning: Target namespace %s differs from current oc project %s.\n",
+			compareOptions.Namespace,
+			compareOptions.ContextNamespace,
+		)
+	}
 
 	if len(compareOptions.Resource) > 0 && len(compareOptions.Selector) > 0 {
 		fmt.Fprintf(w,
@@ -50,9 +300,7 @@ func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClien
 		)
 	}
 
-	resource := compareOptions.Resource
-
-	filter, err := openshift.NewResourceFilter(resource, compareOptions.Selector, compareOptions.Excludes)
+	filter, err := newResourceFilter(compareOptions)
 	if err != nil {
 		return updateRequired, &openshift.Changeset{}, err
 	}
@@ -66,11 +314,50 @@ func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClien
 		return updateRequired, &openshift.Changeset{}, err
 	}
 
-	platformBasedList, err := assemblePlatformBasedResourceList(filter, compareOptions, ocClient)
+	if compareOptions.ServerDefaults {
+		cli.DebugMsg("Applying server defaults to desired state")
+		err = templateBasedList.ApplyServerDefaults(ocClient)
+		if err != nil {
+			return updateRequired, &openshift.Changeset{}, err
+		}
+	}
+
+	for _, item := range templateBasedList.ItemsWithAPIVersion(compareOptions.APIVersionsToWarnAbout()) {
+		cli.FprintYellowf(warnings,
+			"Warning: %s uses deprecated apiVersion %s.\n",
+			item.ShortName(),
+			item.APIVersion(),
+		)
+	}
+
+	// The remote source is read once, even if further resources are looked
+	// up (e.g. for --adopt or --prune).
+	remote := newRemoteSource(compareOptions)
+	platformBasedList, err := assemblePlatformBasedResourceList(filter, compareOptions, remote, ocClient)
 	if err != nil {
 		return updateRequired, &openshift.Changeset{}, err
 	}
 
+	if compareOptions.Adopt && len(filter.Label) > 0 {
+		adopted, err := adoptUnselected(filter, platformBasedList, templateBasedList, compareOptions, remote, ocClient)
+		if err != nil {
+			return updateRequired, &openshift.Changeset{}, err
+		}
+		for _, item := range adopted {
+			fmt.Fprintf(w, "Adopting %s, which exists but is not selected by %s.\n", item.ShortName(), filter.Label)
+		}
+	}
+
+	if compareOptions.Prune && !compareOptions.UpsertOnly {
+		pruned, err := pruneOrphans(filter, platformBasedList, templateBasedList, compareOptions, remote, ocClient)
+		if err != nil {
+			return updateRequired, &openshift.Changeset{}, err
+		}
+		for _, item := range pruned {
+			fmt.Fprintf(w, "Pruning %s, which is selected by %s but not defined in any template.\n", item.ShortName(), compareOptions.PruneLabel)
+		}
+	}
+
 	platformResourcesWord := "resources"
 	if platformBasedList.Length() == 1 {
 		platformResourcesWord = "resource"
@@ -87,6 +374,15 @@ func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClien
 		templateResourcesWord,
 	)
 
+	if compareOptions.FailIfEmpty && templateBasedList.Length() == 0 && platformBasedList.Length() == 0 {
+		fmt.Fprintf(w,
+			"No items where found in current and desired state. "+
+				"Is the template directory (%s), the selector or the namespace wrong?\n",
+			compareOptions.TemplateDir,
+		)
+		return updateRequired, &openshift.Changeset{}, errors.New("Diff not performed as no resources were found (--fail-if-empty)")
+	}
+
 	if templateBasedList.Length() == 0 && !compareOptions.Force {
 		fmt.Fprint(w, "No items where found in desired state. ")
 		if len(compareOptions.Resource) == 0 && len(compareOptions.Selector) == 0 {
@@ -119,12 +415,11 @@ func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClien
 
 	changeset, err := compare(
 		w,
+		warnings,
 		platformBasedList,
 		templateBasedList,
-		compareOptions.UpsertOnly,
-		compareOptions.AllowRecreate,
-		compareOptions.RevealSecrets,
-		compareOptions.PathsToPreserve(),
+		lookupAutoscaledReplicasPaths(warnings, compareOptions, remote, ocClient),
+		compareOptions,
 	)
 	if err != nil {
 		return false, changeset, err
@@ -133,88 +428,541 @@ func calculateChangeset(w io.Writer, compareOptions *cli.CompareOptions, ocClien
 	return updateRequired, changeset, nil
 }
 
-func compare(w io.Writer, remoteResourceList *openshift.ResourceList, localResourceList *openshift.ResourceList, upsertOnly bool, allowRecreate bool, revealSecrets bool, preservePaths []string) (*openshift.Changeset, error) {
-	changeset, err := openshift.NewChangeset(remoteResourceList, localResourceList, upsertOnly, allowRecreate, preservePaths)
+// adoptUnselected adds resources to platformBasedList which are defined in the
+// templates and exist in the cluster, but are not selected by the label of
+// filter. Their changes are then calculated as updates instead of creations.
+func adoptUnselected(filter *openshift.ResourceFilter, platformBasedList *openshift.ResourceList, templateBasedList *openshift.ResourceList, compareOptions *cli.CompareOptions, remote *remoteSource, ocClient cli.OcClientExporter) ([]*openshift.ResourceItem, error) {
+	unselectedFilter := *filter
+	unselectedFilter.Label = ""
+	candidates, err := assemblePlatformBasedResourceList(&unselectedFilter, compareOptions, remote, ocClient)
+	if err != nil {
+		return nil, err
+	}
+	return platformBasedList.Adopt(templateBasedList, candidates), nil
+}
+
+// pruneOrphans adds all resources carrying the prune label to
+// platformBasedList which are not defined in any template, regardless of the
+// kinds and selector of filter. Their changes are then calculated as
+// deletions.
+func pruneOrphans(filter *openshift.ResourceFilter, platformBasedList *openshift.ResourceList, templateBasedList *openshift.ResourceList, compareOptions *cli.CompareOptions, remote *remoteSource, ocClient cli.ClientProcessorExporter) ([]*openshift.ResourceItem, error) {
+	pruneFilter, err := newResourceFilter(compareOptions)
+	if err != nil {
+		return nil, err
+	}
+	pruneFilter.Name = ""
+	pruneFilter.Kinds = []string{}
+	pruneFilter.Label = compareOptions.PruneLabel
+	candidates, err := assemblePlatformBasedResourceList(pruneFilter, compareOptions, remote, ocClient)
+	if err != nil {
+		return nil, err
+	}
+	// A narrowed down template list lacks resources which are defined in
+	// templates, so those need to be processed again without restriction.
+	allTemplatesList := templateBasedList
+	if len(filter.Name) > 0 || len(filter.Kinds) > 0 || len(filter.Label) > 0 {
+		allTemplatesFilter := *pruneFilter
+		allTemplatesFilter.Label = ""
+		allTemplatesList, err = assembleTemplateBasedResourceList(&allTemplatesFilter, compareOptions, ocClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return platformBasedList.Prune(allTemplatesList, candidates, compareOptions.Identities)
+}
+
+// lookupAutoscaledReplicasPaths returns preserve paths for the replicas of all
+// resources targeted by a HorizontalPodAutoscaler in the namespace. The
+// autoscalers are looked up regardless of the kinds and selector given, as
+// they are usually not defined in the templates, and not compared by default.
+func lookupAutoscaledReplicasPaths(w io.Writer, compareOptions *cli.CompareOptions, remote *remoteSource, ocClient cli.OcClientExporter) []string {
+	filter := &openshift.ResourceFilter{Kinds: []string{"HorizontalPodAutoscaler"}}
+	hpaList, err := assemblePlatformBasedResourceList(filter, compareOptions, remote, ocClient)
+	if err != nil {
+		cli.FprintYellowf(w,
+			"Warning: Could not look up HorizontalPodAutoscalers, replicas of autoscaled resources are not preserved: %s\n",
+			err,
+		)
+		return []string{}
+	}
+	return hpaList.AutoscaledReplicasPaths()
+}
+
+// newResourceFilter creates a filter based on the resource, selector, kind
+// and API group options.
+func newResourceFilter(compareOptions *cli.CompareOptions) (*openshift.ResourceFilter, error) {
+	filter, err := openshift.NewResourceFilter(compareOptions.Resource, compareOptions.Selector, compareOptions.Excludes)
+	if err != nil {
+		return nil, err
+	}
+	filter.ExcludedFieldManagers = compareOptions.ExcludedFieldManagers
+	err = filter.RestrictAPIGroups(compareOptions.APIGroups, compareOptions.ExcludedAPIGroups)
+	if err != nil {
+		return nil, err
+	}
+	err = filter.RestrictKinds(compareOptions.OnlyKinds)
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// showDesired prints the desired state of the resource given via
+// --show-desired, as rendered from the templates.
+func showDesired(w io.Writer, compareOptions *cli.CompareOptions, ocClient cli.OcClientProcessor) error {
+	filter, err := openshift.NewResourceFilter(compareOptions.ShowDesired, "", compareOptions.Excludes)
+	if err != nil {
+		return err
+	}
+	templateBasedList, err := assembleTemplateBasedResourceList(filter, compareOptions, ocClient)
+	if err != nil {
+		return err
+	}
+	if templateBasedList.Length() == 0 {
+		return fmt.Errorf("No resource %s found in templates in %s", compareOptions.ShowDesired, compareOptions.TemplateDir)
+	}
+	item := templateBasedList.Items[0]
+	if item.Kind == "Secret" && !compareOptions.RevealSecrets {
+		return fmt.Errorf("Desired state of %s is hidden. Use --reveal-secrets to see details", item.ShortName())
+	}
+	desiredState, err := item.DesiredConfig()
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(w, cli.Redact(desiredState))
+	return nil
+}
+
+func compare(w io.Writer, warnings io.Writer, remoteResourceList *openshift.ResourceList, localResourceList *openshift.ResourceList, autoscaledPaths []string, compareOptions *cli.CompareOptions) (*openshift.Changeset, error) {
+	preservePaths, err := openshift.ResolvePreservePaths(compareOptions.PathsToPreserve(), compareOptions)
+	if err != nil {
+		return &openshift.Changeset{}, err
+	}
+	// Replicas of resources targeted by a HorizontalPodAutoscaler are managed
+	// by the autoscaler, therefore the current state is preserved.
+	preservePaths = append(preservePaths, autoscaledPaths...)
+	changeset, err := openshift.NewChangeset(
+		remoteResourceList,
+		localResourceList,
+		compareOptions.UpsertOnly,
+		compareOptions.OnImmutable,
+		compareOptions.IgnoreWhitespace,
+		preservePaths,
+		compareOptions.Identities,
+		compareOptions.ComparePolicies,
+		compareOptions.PruneAge,
+	)
 	if err != nil {
 		return changeset, err
 	}
 
-	for _, change := range changeset.Noop {
-		fmt.Fprintf(w, "* %s is in sync\n", change.ItemName())
+	err = changeset.MarkDiffOnly(compareOptions.DiffOnly)
+	if err != nil {
+		return changeset, err
+	}
+
+	if len(compareOptions.OnlyActions) > 0 {
+		skipped := changeset.Restrict(compareOptions.OnlyActions)
+		if skipped > 0 {
+			cli.FprintYellowf(
+				warnings, "Skipping %d change(s) not selected via --only %s\n\n",
+				skipped, strings.Join(compareOptions.OnlyActions, ","),
+			)
+		}
+	}
+
+	if (compareOptions.DiffFormat == "json" || compareOptions.DiffFormat == "both") && compareOptions.RevealSecrets {
+		for _, change := range changeset.Update {
+			if change.Kind == "Secret" {
+				cli.FprintYellowf(w, "Warning: JSON patches of Secret resources contain the (base64 encoded) secret values.\n\n")
+				break
+			}
+		}
+	}
+
+	printed, maskedSecrets := maskSecrets(changeset, compareOptions)
+	emitChangeEvents(compareOptions, printed)
+	shown := printed
+	hidden := 0
+	if len(compareOptions.DiffOnlyPaths) > 0 {
+		shown, hidden = printed.AtPaths(compareOptions.DiffOnlyPaths)
+	}
+
+	printInSync(w, printed.Noop, compareOptions.InSyncThreshold)
+	if maskedSecrets > 0 {
+		fmt.Fprintf(w, "* %d Secret resource(s) masked\n", maskedSecrets)
+	}
+	if hidden > 0 {
+		fmt.Fprintf(
+			w, "* %d change(s) without drift at %s hidden\n",
+			hidden, strings.Join(compareOptions.DiffOnlyPaths, ", "),
+		)
 	}
 
-	for _, change := range changeset.Delete {
-		printDeleteChange(w, change, revealSecrets)
+	for _, change := range shown.Delete {
+		printDeleteChange(w, change, compareOptions.RevealSecrets, compareOptions.Explain, compareOptions.DiffFormat)
 	}
 
-	for _, change := range changeset.Create {
-		printCreateChange(w, change, revealSecrets)
+	for _, change := range shown.Create {
+		printCreateChange(w, change, compareOptions.RevealSecrets, compareOptions.Explain, compareOptions.DiffFormat)
 	}
 
-	for _, change := range changeset.Update {
-		printUpdateChange(w, change, revealSecrets)
+	labelKeys := injectedLabelKeys(compareOptions.Labels)
+	for _, change := range shown.Update {
+		printUpdateChange(w, change, compareOptions.RevealSecrets, compareOptions.Explain, compareOptions.DiffFormat)
+		if change.OnlyTemplateHashChanged() {
+			cli.FprintYellowf(warnings, "! %s: template source changed, but rendered output is identical\n", change.ItemName())
+		}
+		for _, conflict := range change.LabelConflicts(labelKeys) {
+			cli.FprintRedf(warnings, "! %s: injected %s\n", change.ItemName(), conflict)
+		}
+		for _, path := range change.ImmutablePaths {
+			cli.FprintYellowf(warnings, "! %s: immutable field %s changed, leaving resource as-is\n", change.ItemName(), path)
+		}
 	}
 
-	fmt.Fprintf(w, "\nSummary: %d in sync, ", len(changeset.Noop))
-	cli.FprintGreenf(w, "%d to create", len(changeset.Create))
+	fmt.Fprintf(w, "\nSummary: %d in sync, ", len(printed.Noop))
+	cli.FprintGreenf(w, "%d to create", len(printed.Create))
 	fmt.Fprint(w, ", ")
-	cli.FprintYellowf(w, "%d to update", len(changeset.Update))
+	cli.FprintYellowf(w, "%d to update", len(printed.Update))
 	fmt.Fprint(w, ", ")
-	cli.FprintRedf(w, "%d to delete\n\n", len(changeset.Delete))
+	cli.FprintRedf(w, "%d to delete", len(printed.Delete))
+	if pruned := printed.PrunedDeletions(); pruned > 0 {
+		cli.FprintRedf(w, " (%d pruned)", pruned)
+	}
+	if maskedSecrets > 0 {
+		fmt.Fprintf(w, ", %d Secret(s) masked", maskedSecrets)
+	}
+	fmt.Fprint(w, "\n\n")
 
 	return changeset, nil
 }
 
-func printDeleteChange(w io.Writer, change *openshift.Change, revealSecrets bool) {
-	cli.FprintRedf(w, "- %s to delete\n", change.ItemName())
-	fmt.Fprint(w, change.Diff(revealSecrets))
+// maskedSecretName replaces the name of Secret resources in the output with
+// --mask-secrets.
+const maskedSecretName = "***"
+
+// maskSecrets returns the changeset to print. With --mask-secrets (and
+// without --reveal-secrets), Secret changes are removed from it and only
+// their number is returned, so that output does not hint at which secrets
+// exist or change.
+func maskSecrets(changeset *openshift.Changeset, compareOptions *cli.CompareOptions) (*openshift.Changeset, int) {
+	if !compareOptions.MaskSecrets || compareOptions.RevealSecrets {
+		return changeset, 0
+	}
+	return changeset.WithoutSecrets()
+}
+
+// isMaskedSecret returns true if change targets a Secret whose name must not
+// be printed because of --mask-secrets.
+func isMaskedSecret(change *openshift.Change, compareOptions *cli.CompareOptions) bool {
+	return change.Kind == "Secret" && compareOptions.MaskSecrets && !compareOptions.RevealSecrets
+}
+
+// printedItemName returns the name of the resource targeted by change as it
+// may be printed. Names of masked Secret resources are replaced.
+func printedItemName(change *openshift.Change, compareOptions *cli.CompareOptions) string {
+	if isMaskedSecret(change, compareOptions) {
+		return "secret/" + maskedSecretName
+	}
+	return change.ItemName()
+}
+
+// printInSync lists all in sync resources, unless there are more than
+// threshold, in which case only their number is printed.
+func printInSync(w io.Writer, noop []*openshift.Change, threshold int) {
+	if threshold > 0 && len(noop) > threshold {
+		fmt.Fprintf(w, "* %d resources in sync\n", len(noop))
+		return
+	}
+	for _, change := range noop {
+		fmt.Fprintf(w, "* %s is in sync\n", change.ItemName())
+	}
+}
+
+func printDeleteChange(w io.Writer, change *openshift.Change, revealSecrets bool, explain bool, diffFormat string) {
+	cli.FprintRedf(w, "- %s to delete%s\n", change.ItemName(), diffOnlyMarker(change))
+	printExplanation(w, change, explain)
+	printChangeDiff(w, change, revealSecrets, diffFormat)
+}
+
+func printCreateChange(w io.Writer, change *openshift.Change, revealSecrets bool, explain bool, diffFormat string) {
+	cli.FprintGreenf(w, "+ %s to create%s\n", change.ItemName(), diffOnlyMarker(change))
+	printExplanation(w, change, explain)
+	printChangeDiff(w, change, revealSecrets, diffFormat)
 }
 
-func printCreateChange(w io.Writer, change *openshift.Change, revealSecrets bool) {
-	cli.FprintGreenf(w, "+ %s to create\n", change.ItemName())
-	fmt.Fprint(w, change.Diff(revealSecrets))
+func printUpdateChange(w io.Writer, change *openshift.Change, revealSecrets bool, explain bool, diffFormat string) {
+	cli.FprintYellowf(w, "~ %s to update%s\n", change.ItemName(), diffOnlyMarker(change))
+	printExplanation(w, change, explain)
+	printChangeDiff(w, change, revealSecrets, diffFormat)
 }
 
-func printUpdateChange(w io.Writer, change *openshift.Change, revealSecrets bool) {
-	cli.FprintYellowf(w, "~ %s to update\n", change.ItemName())
-	fmt.Fprint(w, change.Diff(revealSecrets))
+// printChangeDiff prints the drift as text diff, as JSON patch (only
+// available for updates), or both, depending on diffFormat.
+func printChangeDiff(w io.Writer, change *openshift.Change, revealSecrets bool, diffFormat string) {
+	if diffFormat != "json" {
+		fmt.Fprint(w, change.Diff(revealSecrets))
+	}
+	if diffFormat == "json" || diffFormat == "both" {
+		fmt.Fprint(w, change.JSONPatches(revealSecrets))
+	}
+}
+
+// diffOnlyMarker returns a hint for changes which are never applied.
+func diffOnlyMarker(change *openshift.Change) string {
+	if change.DiffOnly {
+		return " (diff-only)"
+	}
+	return ""
+}
+
+// injectedLabelKeys returns the keys of the labels given via --labels,
+// e.g. "app" and "env" for "app=foo,env=${ENVIRONMENT}".
+func injectedLabelKeys(labels string) []string {
+	keys := []string{}
+	if len(labels) == 0 {
+		return keys
+	}
+	for _, label := range strings.Split(labels, ",") {
+		key := strings.TrimSpace(strings.SplitN(label, "=", 2)[0])
+		if len(key) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func printExplanation(w io.Writer, change *openshift.Change, explain bool) {
+	if explain {
+		fmt.Fprint(w, change.Explanation())
+	}
 }
 
 func assembleTemplateBasedResourceList(filter *openshift.ResourceFilter, compareOptions *cli.CompareOptions, ocClient cli.OcClientProcessor) (*openshift.ResourceList, error) {
-	var inputs [][]byte
+	list := &openshift.ResourceList{Filter: filter}
+
+	patches, err := openshift.ReadPatches(compareOptions.PatchDir, compareOptions.Namespace)
+	if err != nil {
+		return nil, err
+	}
 
-	files, err := ioutil.ReadDir(compareOptions.TemplateDir)
+	ignore, err := readIgnoreRules(compareOptions.TemplateDir, compareOptions.IgnoreFile)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := templateFiles(compareOptions.TemplateDir, compareOptions.Recursive, ignore)
 	if err != nil {
 		return nil, fmt.Errorf("Cannot get files in template directory '%s': %s", compareOptions.TemplateDir, err)
 	}
-	filePattern := ".*\\.ya?ml$"
-	re := regexp.MustCompile(filePattern)
-	for _, file := range files {
-		matched := re.MatchString(file.Name())
-		if !matched {
-			continue
+	for _, template := range templates {
+		if err := cli.RunContextErr(); err != nil {
+			return nil, err
 		}
-		cli.DebugMsg("Reading template", file.Name())
+		cli.DebugMsg("Reading template", template)
 		processedOut, err := openshift.ProcessTemplate(
 			compareOptions.TemplateDir,
-			file.Name(),
+			template,
 			compareOptions.ParamDir,
 			compareOptions,
 			ocClient,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("Could not process %s template: %s", file.Name(), err)
+			var processErr *openshift.TemplateProcessError
+			if errors.As(err, &processErr) {
+				return nil, err
+			}
+			return nil, &openshift.TemplateProcessError{Template: template, Err: err}
+		}
+		processedOut, err = openshift.ApplyPatches(processedOut, patches)
+		if err != nil {
+			return nil, &openshift.TemplateProcessError{Template: template, Err: err}
+		}
+		processedOut, err = openshift.RenameResources(processedOut, compareOptions.NamePrefix, compareOptions.NameSuffix)
+		if err != nil {
+			return nil, &openshift.TemplateProcessError{Template: template, Err: err}
+		}
+		templateList, err := openshift.NewTemplateBasedResourceList(filter, processedOut)
+		if err != nil {
+			if compareOptions.PrintProcessedOnError && compareOptions.Debug {
+				printProcessedOutput(template, processedOut)
+			}
+			return nil, &openshift.TemplateProcessError{Template: template, Err: err}
 		}
-		inputs = append(inputs, processedOut)
+		if templateList.Length() == 0 {
+			cli.DebugMsg("Template", template, "contributes no resources")
+		}
+		list.Items = append(list.Items, templateList.Items...)
+		emitEvent(compareOptions, Event{Type: EventTemplateProcessed, Template: template})
 	}
 
-	return openshift.NewTemplateBasedResourceList(filter, inputs...)
+	return list, nil
+}
+
+// runRemoteCmd executes command in a shell, exposing the namespace as
+// TAILOR_NAMESPACE, and returns its output. The command is killed once the
+// run context is done.
+func runRemoteCmd(command string, namespace string) ([]byte, error) {
+	cmd := exec.CommandContext(cli.RunContext(), "sh", "-c", command)
+	cmd.Env = append(os.Environ(), "TAILOR_NAMESPACE="+namespace)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if ctxErr := cli.RunContextErr(); ctxErr != nil {
+		return nil, fmt.Errorf("The remote command '%s' was aborted: %w", command, ctxErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("The remote command '%s' failed: %s", command, err)
+	}
+	return out, nil
 }
 
-func assemblePlatformBasedResourceList(filter *openshift.ResourceFilter, compareOptions *cli.CompareOptions, ocClient cli.OcClientExporter) (*openshift.ResourceList, error) {
+// templateFiles returns the paths of all templates (*.yml and *.yaml files)
+// in templateDir, relative to it. If recursive is set, subdirectories are
+// included, except hidden ones. Files and directories matched by ignore are
+// skipped.
+func templateFiles(templateDir string, recursive bool, ignore *ignoreRules) ([]string, error) {
+	re := regexp.MustCompile(".*\\.ya?ml$")
+	templates := []string{}
+	if !recursive {
+		files, err := ioutil.ReadDir(templateDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if !re.MatchString(file.Name()) {
+				continue
+			}
+			if ignore.ignored(file.Name(), false) {
+				cli.DebugMsg("Ignoring template", file.Name(), "as listed in", ignore.source)
+				continue
+			}
+			templates = append(templates, file.Name())
+		}
+		return templates, nil
+	}
+	err := filepath.Walk(templateDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == templateDir {
+			return nil
+		}
+		rel, err := filepath.Rel(templateDir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if ignore.ignored(rel, true) {
+				cli.DebugMsg("Ignoring directory", rel, "as listed in", ignore.source)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !re.MatchString(info.Name()) {
+			return nil
+		}
+		if ignore.ignored(rel, false) {
+			cli.DebugMsg("Ignoring template", rel, "as listed in", ignore.source)
+			return nil
+		}
+		templates = append(templates, rel)
+		return nil
+	})
+	return templates, err
+}
+
+// processedOutput receives the output of printProcessedOutput.
+var processedOutput io.Writer = os.Stdout
+
+// printProcessedOutput prints the raw output of processing given template.
+// As the output may contain secrets in clear text, it must only be called
+// in debug mode. Values of sensitive params are masked.
+func printProcessedOutput(template string, processed []byte) {
+	color.New(color.FgBlue).Fprintf(processedOutput, "--> Processed output of %s:\n", template)
+	fmt.Fprintln(processedOutput, cli.Redact(string(processed)))
+}
+
+// remoteSource provides the current state from --remote-file or
+// --remote-cmd instead of the cluster. The file is read (or the command run)
+// at most once.
+type remoteSource struct {
+	file    string
+	command string
+	// namespace is exposed to command.
+	namespace string
+	out       []byte
+}
+
+// newRemoteSource returns the configured remote source, or nil if the
+// current state is exported from the cluster.
+func newRemoteSource(compareOptions *cli.CompareOptions) *remoteSource {
+	if len(compareOptions.RemoteFile) == 0 && len(compareOptions.RemoteCmd) == 0 {
+		return nil
+	}
+	return &remoteSource{
+		file:      compareOptions.RemoteFile,
+		command:   compareOptions.RemoteCmd,
+		namespace: compareOptions.Namespace,
+	}
+}
+
+// list returns the resources of the remote source matching filter.
+func (s *remoteSource) list(filter *openshift.ResourceFilter) (*openshift.ResourceList, error) {
+	if s.out == nil {
+		out, err := s.read()
+		if err != nil {
+			return nil, err
+		}
+		s.out = out
+	}
+	return openshift.NewPlatformBasedResourceList(filter, s.out)
+}
+
+func (s *remoteSource) read() ([]byte, error) {
+	if len(s.file) > 0 {
+		cli.DebugMsg("Reading current state from", s.file)
+		b, err := ioutil.ReadFile(s.file)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read remote file '%s': %s", s.file, err)
+		}
+		b, err = openshift.DocumentsAsList(b)
+		if err != nil {
+			return nil, fmt.Errorf("Could not read remote file '%s': %s", s.file, err)
+		}
+		return b, nil
+	}
+	cli.DebugMsg("Reading current state from output of", s.command)
+	b, err := runRemoteCmd(s.command, s.namespace)
+	if err != nil {
+		return nil, err
+	}
+	b, err = openshift.DocumentsAsList(b)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read output of remote command '%s': %s", s.command, err)
+	}
+	return b, nil
+}
+
+// assemblePlatformBasedResourceList returns the current state of the
+// resources matching filter, either from remote (if not nil) or exported
+// from the cluster.
+func assemblePlatformBasedResourceList(filter *openshift.ResourceFilter, compareOptions *cli.CompareOptions, remote *remoteSource, ocClient cli.OcClientExporter) (*openshift.ResourceList, error) {
+	if remote != nil {
+		return remote.list(filter)
+	}
 	exportedOut, err := ocClient.Export(filter.ConvertToKinds(), filter.Label)
 	if err != nil {
-		return nil, fmt.Errorf("Could not export %s resources: %s", filter.String(), err)
+		return nil, &openshift.ExportError{Target: filter.String(), Err: err}
+	}
+	list, err := openshift.NewPlatformBasedResourceList(filter, exportedOut)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range list.Items {
+		emitEvent(compareOptions, Event{Type: EventResourceExported, Kind: item.Kind, Name: item.Name})
 	}
-	return openshift.NewPlatformBasedResourceList(filter, exportedOut)
+	return list, nil
 }
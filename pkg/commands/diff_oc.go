@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+// DiffOcBinaries renders the templates once with the configured oc binary
+// and once with given other oc binary, and prints the differences between
+// the two rendered outputs to STDOUT. This allows to catch changes in the
+// behaviour of "oc process" before upgrading oc. The cluster is not
+// contacted.
+func DiffOcBinaries(compareOptions *cli.CompareOptions, otherOcBinary string) (bool, error) {
+	if _, err := exec.LookPath(otherOcBinary); err != nil {
+		return false, fmt.Errorf("oc binary '%s' not found: %s", otherOcBinary, err)
+	}
+	return diffOcBinaries(
+		os.Stdout,
+		compareOptions,
+		compareOptions.OcBinary,
+		otherOcBinary,
+		cli.NewOcClient(compareOptions.Namespace),
+		cli.NewOcClientWithBinary(compareOptions.Namespace, otherOcBinary),
+	)
+}
+
+func diffOcBinaries(w io.Writer, compareOptions *cli.CompareOptions, ocBinaryA string, ocBinaryB string, ocClientA cli.OcClientProcessor, ocClientB cli.OcClientProcessor) (bool, error) {
+	filter, err := newResourceFilter(compareOptions)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Fprintf(w,
+		"Comparing templates in %s rendered with %s and with %s.\n\n",
+		compareOptions.TemplateDir,
+		ocBinaryA,
+		ocBinaryB,
+	)
+
+	listA, err := assembleTemplateBasedResourceList(filter, compareOptions, ocClientA)
+	if err != nil {
+		return false, fmt.Errorf("Rendering with %s failed: %s", ocBinaryA, err)
+	}
+	listB, err := assembleTemplateBasedResourceList(filter, compareOptions, ocClientB)
+	if err != nil {
+		return false, fmt.Errorf("Rendering with %s failed: %s", ocBinaryB, err)
+	}
+
+	return diffRenderings(w, listA, listB, ocBinaryA, ocBinaryB, compareOptions)
+}
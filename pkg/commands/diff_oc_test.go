@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestDiffOcBinaries(t *testing.T) {
+	tests := map[string]struct {
+		fixtureA        string
+		fixtureB        string
+		wantDifferences bool
+		wantOutput      []string
+	}{
+		"identical renderings": {
+			fixtureA:        "template-dir/desired-list.yml",
+			fixtureB:        "template-dir/desired-list.yml",
+			wantDifferences: false,
+			wantOutput:      []string{"rendered with oc and with oc-4.6", "Summary: 2 identical"},
+		},
+		"different renderings": {
+			fixtureA:        "current-list.yml",
+			fixtureB:        "template-dir/desired-list.yml",
+			wantDifferences: true,
+			wantOutput:      []string{"~ bc/foo differs"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    cli.InitGlobalOptions(&utils.OsFS{}),
+				NamespaceOptions: &cli.NamespaceOptions{},
+				TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:       []string{},
+			}
+			var buf bytes.Buffer
+			differences, err := diffOcBinaries(
+				&buf,
+				compareOptions,
+				"oc",
+				"oc-4.6",
+				&mockOcSequenceProcessClient{t: t, fixtures: []string{tc.fixtureA}},
+				&mockOcSequenceProcessClient{t: t, fixtures: []string{tc.fixtureB}},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if differences != tc.wantDifferences {
+				t.Fatalf("Want differences=%t, got %t:\n%s", tc.wantDifferences, differences, buf.String())
+			}
+			got := buf.String()
+			for _, want := range tc.wantOutput {
+				if !strings.Contains(got, want) {
+					t.Fatalf("Want output to contain '%s', got:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
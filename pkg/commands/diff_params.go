@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// DiffParams renders the templates once with each of the given param files,
+// and prints the differences between the two rendered outputs to STDOUT.
+// The cluster is not contacted.
+func DiffParams(compareOptions *cli.CompareOptions, paramFileA string, paramFileB string) (bool, error) {
+	ocClient := cli.NewOcClient(compareOptions.Namespace)
+	return diffParams(os.Stdout, compareOptions, paramFileA, paramFileB, ocClient)
+}
+
+func diffParams(w io.Writer, compareOptions *cli.CompareOptions, paramFileA string, paramFileB string, ocClient cli.OcClientProcessor) (bool, error) {
+	filter, err := newResourceFilter(compareOptions)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Fprintf(w,
+		"Comparing templates in %s rendered with %s and with %s.\n\n",
+		compareOptions.TemplateDir,
+		paramFileA,
+		paramFileB,
+	)
+
+	listA, err := assembleTemplateBasedResourceListWithParamFile(filter, compareOptions, paramFileA, ocClient)
+	if err != nil {
+		return false, err
+	}
+	listB, err := assembleTemplateBasedResourceListWithParamFile(filter, compareOptions, paramFileB, ocClient)
+	if err != nil {
+		return false, err
+	}
+
+	return diffRenderings(w, listA, listB, paramFileA, paramFileB, compareOptions)
+}
+
+// diffRenderings prints the differences between two renderings of the
+// templates, labeled a and b, and returns whether there are any.
+// As nothing is applied, differences in immutable fields (e.g. the host of a
+// route) are reported like any other difference.
+func diffRenderings(w io.Writer, listA *openshift.ResourceList, listB *openshift.ResourceList, a string, b string, compareOptions *cli.CompareOptions) (bool, error) {
+	changeset, err := openshift.NewChangeset(listA, listB, false, openshift.OnImmutableWarn, false, []string{}, []string{}, []string{}, 0)
+	if err != nil {
+		return false, err
+	}
+	changeset = recreationsAsUpdates(changeset)
+
+	printInSync(w, changeset.Noop, compareOptions.InSyncThreshold)
+	for _, change := range changeset.Delete {
+		cli.FprintRedf(w, "- %s only rendered with %s\n", change.ItemName(), a)
+	}
+	for _, change := range changeset.Create {
+		cli.FprintGreenf(w, "+ %s only rendered with %s\n", change.ItemName(), b)
+	}
+	for _, change := range changeset.Update {
+		cli.FprintYellowf(w, "~ %s differs\n", change.ItemName())
+		fmt.Fprint(w, change.Diff(compareOptions.RevealSecrets))
+	}
+
+	fmt.Fprintf(w, "\nSummary: %d identical, ", len(changeset.Noop))
+	cli.FprintYellowf(w, "%d different", len(changeset.Update))
+	fmt.Fprint(w, ", ")
+	cli.FprintRedf(w, "%d only in %s", len(changeset.Delete), a)
+	fmt.Fprint(w, ", ")
+	cli.FprintGreenf(w, "%d only in %s\n", len(changeset.Create), b)
+
+	return !changeset.Blank(), nil
+}
+
+// recreationsAsUpdates turns recreations (e.g. requested via annotation)
+// into updates, as the resource is rendered in both cases.
+func recreationsAsUpdates(changeset *openshift.Changeset) *openshift.Changeset {
+	deleted := map[string]*openshift.Change{}
+	for _, change := range changeset.Delete {
+		deleted[change.ItemName()] = change
+	}
+	recreated := map[string]bool{}
+	updates := []*openshift.Change{}
+	for _, change := range changeset.Create {
+		if d, ok := deleted[change.ItemName()]; ok {
+			recreated[change.ItemName()] = true
+			updates = append(updates, &openshift.Change{
+				Action:       "Update",
+				Kind:         change.Kind,
+				Name:         change.Name,
+				CurrentState: d.CurrentState,
+				DesiredState: change.DesiredState,
+				Reasons:      change.Reasons,
+			})
+		}
+	}
+	if len(recreated) == 0 {
+		return changeset
+	}
+	merged := &openshift.Changeset{Noop: changeset.Noop}
+	for _, change := range changeset.Delete {
+		if !recreated[change.ItemName()] {
+			merged.Delete = append(merged.Delete, change)
+		}
+	}
+	for _, change := range changeset.Create {
+		if !recreated[change.ItemName()] {
+			merged.Create = append(merged.Create, change)
+		}
+	}
+	merged.Update = append(changeset.Update, updates...)
+	return merged
+}
+
+// assembleTemplateBasedResourceListWithParamFile processes the templates
+// with given param file instead of the configured ones.
+func assembleTemplateBasedResourceListWithParamFile(filter *openshift.ResourceFilter, compareOptions *cli.CompareOptions, paramFile string, ocClient cli.OcClientProcessor) (*openshift.ResourceList, error) {
+	o := *compareOptions
+	o.ParamFiles = []string{paramFile}
+	return assembleTemplateBasedResourceList(filter, &o, ocClient)
+}
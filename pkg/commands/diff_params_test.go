@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/internal/test/helper"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+// mockOcSequenceProcessClient returns the given fixtures on subsequent
+// calls to Process.
+type mockOcSequenceProcessClient struct {
+	t        *testing.T
+	fixtures []string
+}
+
+func (c *mockOcSequenceProcessClient) Process(args []string) ([]byte, []byte, error) {
+	fixture := c.fixtures[0]
+	c.fixtures = c.fixtures[1:]
+	return helper.ReadFixtureFile(c.t, "command-apply/"+fixture), []byte(""), nil
+}
+
+func TestDiffParams(t *testing.T) {
+	tests := map[string]struct {
+		fixtures        []string
+		wantDifferences bool
+		wantOutput      []string
+	}{
+		"identical renderings": {
+			fixtures:        []string{"template-dir/desired-list.yml", "template-dir/desired-list.yml"},
+			wantDifferences: false,
+			wantOutput:      []string{"* bc/foo is in sync", "Summary: 2 identical"},
+		},
+		"different renderings": {
+			fixtures:        []string{"current-list.yml", "template-dir/desired-list.yml"},
+			wantDifferences: true,
+			wantOutput:      []string{"~ bc/foo differs"},
+		},
+		"immutable and recreated fields": {
+			fixtures:        []string{"../command-diff-params/route-dev.yml", "../command-diff-params/route-prod.yml"},
+			wantDifferences: true,
+			wantOutput: []string{
+				"~ route/foo differs",
+				"+  host: foo-prod.example.com",
+				"~ cm/bar differs",
+				"Summary: 0 identical, 2 different, 0 only in",
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    cli.InitGlobalOptions(&utils.OsFS{}),
+				NamespaceOptions: &cli.NamespaceOptions{},
+				TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:       []string{},
+			}
+			ocClient := &mockOcSequenceProcessClient{t: t, fixtures: tc.fixtures}
+			var buf bytes.Buffer
+			differences, err := diffParams(
+				&buf,
+				compareOptions,
+				"../../internal/test/fixtures/command-diff-params/dev.env",
+				"../../internal/test/fixtures/command-diff-params/prod.env",
+				ocClient,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if differences != tc.wantDifferences {
+				t.Fatalf("Want differences=%t, got %t:\n%s", tc.wantDifferences, differences, buf.String())
+			}
+			got := buf.String()
+			for _, want := range tc.wantOutput {
+				if !strings.Contains(got, want) {
+					t.Fatalf("Want output to contain '%s', got:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
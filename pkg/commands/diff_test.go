@@ -0,0 +1,653 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/internal/test/helper"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestShowDesired(t *testing.T) {
+	tests := map[string]struct {
+		showDesired  string
+		wantContains string
+		wantError    string
+	}{
+		"existing resource": {
+			showDesired:  "is/foo",
+			wantContains: "kind: ImageStream",
+		},
+		"missing resource": {
+			showDesired: "is/bar",
+			wantError:   "No resource is/bar found in templates in ../../internal/test/fixtures/command-apply/template-dir",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:       []string{},
+				ShowDesired:      tc.showDesired,
+			}
+			ocClient := &mockOcApplyClient{
+				t:              t,
+				desiredFixture: "template-dir/desired-list.yml",
+			}
+			var buf bytes.Buffer
+			err := showDesired(&buf, compareOptions, ocClient)
+			if len(tc.wantError) > 0 {
+				if err == nil || err.Error() != tc.wantError {
+					t.Fatalf("Want error '%s', got '%v'", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			if !strings.Contains(got, tc.wantContains) || strings.Contains(got, "kind: BuildConfig") {
+				t.Fatalf("Want only desired state of %s, got:\n%s", tc.showDesired, got)
+			}
+		})
+	}
+}
+
+func TestCheckOwnership(t *testing.T) {
+	tests := map[string]struct {
+		changeset *openshift.Changeset
+		wantError string
+	}{
+		"all resources managed": {
+			changeset: &openshift.Changeset{
+				Create: []*openshift.Change{{Action: "Create", Kind: "ConfigMap", Name: "foo"}},
+			},
+			wantError: "",
+		},
+		"recreated and pruned resources": {
+			changeset: &openshift.Changeset{
+				Delete: []*openshift.Change{
+					{Action: "Delete", Kind: "DeploymentConfig", Name: "foo"},
+					{Action: "Delete", Kind: "Service", Name: "bar", Pruned: true},
+				},
+				Create: []*openshift.Change{{Action: "Create", Kind: "DeploymentConfig", Name: "foo"}},
+			},
+			wantError: "",
+		},
+		"unmanaged resources": {
+			changeset: &openshift.Changeset{
+				Unmanaged: []string{"dc/foo", "svc/bar"},
+			},
+			wantError: "Found 2 resource(s) in the cluster which are not defined in the templates:\n* dc/foo\n* svc/bar",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkOwnership(tc.changeset)
+			if len(tc.wantError) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var unmanagedErr *UnmanagedResourcesError
+			if !errors.As(err, &unmanagedErr) {
+				t.Fatalf("Want UnmanagedResourcesError, got '%v'", err)
+			}
+			if err.Error() != tc.wantError {
+				t.Fatalf("Want error '%s', got '%v'", tc.wantError, err)
+			}
+		})
+	}
+}
+
+type mockOcOfflineClient struct {
+	mockOcApplyClient
+}
+
+func (c *mockOcOfflineClient) Export(target string, label string) ([]byte, error) {
+	c.t.Fatal("Export should not be called when comparing against a remote file or command")
+	return nil, nil
+}
+
+func TestCalculateChangesetWithRemoteFile(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:       []string{},
+		RemoteFile:       "../../internal/test/fixtures/command-apply/current-list.yml",
+	}
+	ocClient := &mockOcOfflineClient{
+		mockOcApplyClient{
+			t:              t,
+			desiredFixture: "template-dir/desired-list.yml",
+		},
+	}
+	var buf bytes.Buffer
+	drift, changeset, err := calculateChangeset(&buf, &buf, compareOptions, ocClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drift || changeset.Blank() {
+		t.Fatalf("Want drift against remote file, got:\n%s", buf.String())
+	}
+}
+
+func TestCalculateChangesetWithRemoteCmd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-remote-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runs := filepath.Join(dir, "runs")
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:       []string{},
+		RemoteCmd:        "echo run >> " + runs + " && cat ../../internal/test/fixtures/command-apply/current-list.yml",
+		Prune:            true,
+		PruneLabel:       "app=foo",
+	}
+	ocClient := &mockOcOfflineClient{
+		mockOcApplyClient{
+			t:              t,
+			desiredFixture: "template-dir/desired-list.yml",
+		},
+	}
+	var buf bytes.Buffer
+	drift, changeset, err := calculateChangeset(&buf, &buf, compareOptions, ocClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drift || changeset.Blank() {
+		t.Fatalf("Want drift against output of remote command, got:\n%s", buf.String())
+	}
+	// The command is run once, although autoscalers and prunable resources
+	// are looked up as well.
+	b, err := ioutil.ReadFile(runs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "run\n" {
+		t.Fatalf("Want remote command to run once, got runs:\n%s", string(b))
+	}
+}
+
+func TestCalculateChangesetWithServerDefaults(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:       []string{},
+		ServerDefaults:   true,
+	}
+	ocClient := &mockOcApplyClient{
+		t:              t,
+		currentFixture: "current-list.yml",
+		desiredFixture: "template-dir/desired-list.yml",
+	}
+	var buf bytes.Buffer
+	_, _, err := calculateChangeset(&buf, &buf, compareOptions, ocClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ocClient.defaulted) == 0 {
+		t.Fatal("Want desired state to be defaulted by the server")
+	}
+}
+
+func TestCalculateChangesetSeparatesWarnings(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:         globalOptions,
+		NamespaceOptions:      &cli.NamespaceOptions{Namespace: "foo", ContextNamespace: "bar"},
+		TemplateDir:           "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:            []string{},
+		DeprecatedAPIVersions: []string{"image.openshift.io/v1"},
+	}
+	ocClient := &mockOcApplyClient{
+		t:              t,
+		currentFixture: "current-list.yml",
+		desiredFixture: "template-dir/desired-list.yml",
+	}
+	var buf, warnings bytes.Buffer
+	_, _, err := calculateChangeset(&buf, &warnings, compareOptions, ocClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Target namespace foo differs from current oc project bar",
+		"is/foo uses deprecated apiVersion image.openshift.io/v1",
+	} {
+		if !strings.Contains(warnings.String(), want) {
+			t.Fatalf("Want warnings to contain '%s', got:\n%s", want, warnings.String())
+		}
+		if strings.Contains(buf.String(), want) {
+			t.Fatalf("Want '%s' only in warnings, got output:\n%s", want, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), "Summary:") {
+		t.Fatalf("Want diff in output, got:\n%s", buf.String())
+	}
+}
+
+type mockOcInvalidProcessClient struct {
+	processed string
+}
+
+func (c *mockOcInvalidProcessClient) Process(args []string) ([]byte, []byte, error) {
+	return []byte(c.processed), []byte(""), nil
+}
+
+func TestAssembleTemplateBasedResourceListPrintsProcessedOutput(t *testing.T) {
+	cli.AddRedactedValue("processed-s3cr3t")
+	ocClient := &mockOcInvalidProcessClient{
+		processed: "kind: List\nitems:\n- kind: Secret\n  stringData:\n    password: processed-s3cr3t\n  metadata: [\n",
+	}
+	tests := map[string]struct {
+		debug                 bool
+		printProcessedOnError bool
+		wantPrinted           bool
+	}{
+		"debug and flag": {
+			debug:                 true,
+			printProcessedOnError: true,
+			wantPrinted:           true,
+		},
+		"flag without debug": {
+			printProcessedOnError: true,
+		},
+		"debug without flag": {
+			debug: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			globalOptions.Debug = tc.debug
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:         globalOptions,
+				NamespaceOptions:      &cli.NamespaceOptions{Namespace: "foo"},
+				TemplateDir:           "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:            []string{},
+				PrintProcessedOnError: tc.printProcessedOnError,
+			}
+			var buf bytes.Buffer
+			stdout := processedOutput
+			processedOutput = &buf
+			defer func() { processedOutput = stdout }()
+			filter := &openshift.ResourceFilter{Kinds: []string{}}
+			_, err := assembleTemplateBasedResourceList(filter, compareOptions, ocClient)
+			if err == nil {
+				t.Fatal("Want error for invalid processed output")
+			}
+			printed := strings.Contains(buf.String(), "Processed output of")
+			if printed != tc.wantPrinted {
+				t.Fatalf("Want processed output printed=%t, got:\n%s", tc.wantPrinted, buf.String())
+			}
+			if strings.Contains(buf.String(), "processed-s3cr3t") {
+				t.Fatalf("Want sensitive values to be redacted, got:\n%s", buf.String())
+			}
+			if tc.wantPrinted && !strings.Contains(buf.String(), "password: ***") {
+				t.Fatalf("Want redacted processed output, got:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+type mockOcAutoscaledClient struct {
+	mockOcApplyClient
+	exported []string
+}
+
+func (c *mockOcAutoscaledClient) Export(target string, label string) ([]byte, error) {
+	c.exported = append(c.exported, target+"|"+label)
+	if target == "HorizontalPodAutoscaler" {
+		return helper.ReadFixtureFile(c.t, "command-apply/autoscaled/hpa-list.yml"), nil
+	}
+	return helper.ReadFixtureFile(c.t, "command-apply/autoscaled/current-list.yml"), nil
+}
+
+func TestCalculateChangesetPreservesAutoscaledReplicas(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    globalOptions,
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		TemplateDir:      "../../internal/test/fixtures/command-apply/autoscaled/template-dir",
+		ParamFiles:       []string{},
+		Resource:         "dc",
+		Selector:         "app=foo",
+	}
+	ocClient := &mockOcAutoscaledClient{
+		mockOcApplyClient: mockOcApplyClient{
+			t:              t,
+			desiredFixture: "autoscaled/template-dir/desired-list.yml",
+		},
+	}
+	var buf bytes.Buffer
+	drift, changeset, err := calculateChangeset(&buf, &buf, compareOptions, ocClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift || !changeset.Blank() {
+		t.Fatalf("Want replicas of autoscaled dc to be preserved, got:\n%s", buf.String())
+	}
+	if len(changeset.Delete) > 0 {
+		t.Fatal("HorizontalPodAutoscaler must not be compared")
+	}
+	if strings.Join(ocClient.exported, ",") != "DeploymentConfig|app=foo,HorizontalPodAutoscaler|" {
+		t.Fatalf("Want separate export of autoscalers, got: %v", ocClient.exported)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	changeset := &openshift.Changeset{
+		Create: []*openshift.Change{{Action: "Create", Kind: "ConfigMap", Name: "foo"}},
+		Update: []*openshift.Change{},
+		Delete: []*openshift.Change{{Action: "Delete", Kind: "ConfigMap", Name: "bar"}},
+		Noop: []*openshift.Change{
+			{Action: "Noop", Kind: "ConfigMap", Name: "baz"},
+			{Action: "Noop", Kind: "ConfigMap", Name: "qux"},
+		},
+	}
+	var buf bytes.Buffer
+	printSummary(&buf, changeset, &cli.CompareOptions{})
+	want := "create=1 update=0 delete=1 noop=2\n"
+	if buf.String() != want {
+		t.Fatalf("Want '%s', got '%s'", want, buf.String())
+	}
+}
+
+func TestPrintSummaryPruned(t *testing.T) {
+	changeset := &openshift.Changeset{
+		Create: []*openshift.Change{},
+		Update: []*openshift.Change{},
+		Delete: []*openshift.Change{
+			{Action: "Delete", Kind: "ConfigMap", Name: "bar"},
+			{Action: "Delete", Kind: "Service", Name: "bar", Pruned: true},
+		},
+		Noop: []*openshift.Change{},
+	}
+	var buf bytes.Buffer
+	printSummary(&buf, changeset, &cli.CompareOptions{})
+	want := "create=0 update=0 delete=2 noop=0 pruned=1\n"
+	if buf.String() != want {
+		t.Fatalf("Want '%s', got '%s'", want, buf.String())
+	}
+}
+
+func TestPrintSummaryMaskSecrets(t *testing.T) {
+	changeset := &openshift.Changeset{
+		Create: []*openshift.Change{{Action: "Create", Kind: "Secret", Name: "foo"}},
+		Update: []*openshift.Change{{Action: "Update", Kind: "ConfigMap", Name: "bar"}},
+		Delete: []*openshift.Change{},
+		Noop:   []*openshift.Change{{Action: "Noop", Kind: "Secret", Name: "baz"}},
+	}
+	tests := map[string]struct {
+		compareOptions *cli.CompareOptions
+		want           string
+	}{
+		"masked": {
+			compareOptions: &cli.CompareOptions{MaskSecrets: true},
+			want:           "create=0 update=1 delete=0 noop=0 masked-secrets=2\n",
+		},
+		"revealed": {
+			compareOptions: &cli.CompareOptions{MaskSecrets: true, RevealSecrets: true},
+			want:           "create=1 update=1 delete=0 noop=1\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printSummary(&buf, changeset, tc.compareOptions)
+			if buf.String() != tc.want {
+				t.Fatalf("Want '%s', got '%s'", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestPrintInSync(t *testing.T) {
+	noop := []*openshift.Change{
+		{Action: "Noop", Kind: "ConfigMap", Name: "foo"},
+		{Action: "Noop", Kind: "ConfigMap", Name: "bar"},
+		{Action: "Noop", Kind: "ConfigMap", Name: "baz"},
+	}
+	tests := map[string]struct {
+		threshold int
+		want      string
+	}{
+		"no threshold": {
+			threshold: 0,
+			want:      "* cm/foo is in sync\n* cm/bar is in sync\n* cm/baz is in sync\n",
+		},
+		"below threshold": {
+			threshold: 3,
+			want:      "* cm/foo is in sync\n* cm/bar is in sync\n* cm/baz is in sync\n",
+		},
+		"above threshold": {
+			threshold: 2,
+			want:      "* 3 resources in sync\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printInSync(&buf, noop, tc.threshold)
+			if buf.String() != tc.want {
+				t.Fatalf("Want '%s', got '%s'", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestCalculateChangesetFailIfEmpty(t *testing.T) {
+	templateDir, err := ioutil.TempDir("", "tailor-empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(templateDir)
+
+	tests := map[string]struct {
+		failIfEmpty bool
+		wantError   bool
+	}{
+		"empty state is accepted with --force": {
+			failIfEmpty: false,
+			wantError:   false,
+		},
+		"empty state fails with --fail-if-empty": {
+			failIfEmpty: true,
+			wantError:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			globalOptions.Force = true
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				TemplateDir:      templateDir,
+				ParamFiles:       []string{},
+				FailIfEmpty:      tc.failIfEmpty,
+			}
+			ocClient := &mockOcApplyClient{
+				t:              t,
+				currentFixture: "empty-list.yml",
+			}
+			var buf bytes.Buffer
+			_, _, err := calculateChangeset(&buf, &buf, compareOptions, ocClient)
+			if tc.wantError && err == nil {
+				t.Fatal("Want error, got none")
+			}
+			if !tc.wantError && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestDiffNamespaces(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:     globalOptions,
+		NamespaceOptions:  &cli.NamespaceOptions{},
+		TemplateDir:       "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:        []string{},
+		CompareNamespaces: []string{"foo-dev", "foo-test"},
+	}
+	currentFixtures := map[string]string{
+		"foo-dev":  "current-list.yml",
+		"foo-test": "template-dir/desired-list.yml",
+	}
+	var buf bytes.Buffer
+	drift, err := diffNamespaces(&buf, compareOptions, func(namespace string) cli.ClientProcessorExporter {
+		return &mockOcApplyClient{
+			t:              t,
+			currentFixture: currentFixtures[namespace],
+			desiredFixture: "template-dir/desired-list.yml",
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !drift {
+		t.Fatal("Want drift in foo-dev")
+	}
+	got := buf.String()
+	for _, want := range []string{"OCP namespace foo-dev", "OCP namespace foo-test", "Result per namespace:", "foo-test  in sync", "Drift detected in 1 of 2 namespaces: foo-dev"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("Want output to contain '%s', got:\n%s", want, got)
+		}
+	}
+}
+
+type mockOcFailingExportClient struct {
+	mockOcApplyClient
+}
+
+func (c *mockOcFailingExportClient) Export(target string, label string) ([]byte, error) {
+	return nil, errors.New("Failed to export all resources.\nerror: Unauthorized")
+}
+
+func TestDiffNamespacesContinuesOnError(t *testing.T) {
+	globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:     globalOptions,
+		NamespaceOptions:  &cli.NamespaceOptions{},
+		TemplateDir:       "../../internal/test/fixtures/command-apply/template-dir",
+		ParamFiles:        []string{},
+		CompareNamespaces: []string{"foo-dev", "foo-test"},
+	}
+	var buf bytes.Buffer
+	_, err := diffNamespaces(&buf, compareOptions, func(namespace string) cli.ClientProcessorExporter {
+		c := mockOcApplyClient{
+			t:              t,
+			currentFixture: "template-dir/desired-list.yml",
+			desiredFixture: "template-dir/desired-list.yml",
+		}
+		if namespace == "foo-dev" {
+			return &mockOcFailingExportClient{c}
+		}
+		return &c
+	})
+	if err == nil || !strings.Contains(err.Error(), "Diff failed in 1 of 2 namespaces: foo-dev") {
+		t.Fatalf("Want error for foo-dev, got: %v", err)
+	}
+	got := buf.String()
+	for _, want := range []string{"OCP namespace foo-test", "foo-dev   error: Could not export", "foo-test  in sync"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("Want output to contain '%s', got:\n%s", want, got)
+		}
+	}
+}
+
+func TestPrintChangeDiff(t *testing.T) {
+	change := &openshift.Change{
+		Action:       "Update",
+		Kind:         "ConfigMap",
+		Name:         "foo",
+		CurrentState: "data:\n  foo: bar\n",
+		DesiredState: "data:\n  foo: baz\n",
+		Patches:      []*openshift.JSONPatch{{Op: "replace", Path: "/data/foo", Value: "baz"}},
+	}
+	tests := map[string]struct {
+		diffFormat string
+		wantText   bool
+		wantJSON   bool
+	}{
+		"text": {diffFormat: "text", wantText: true},
+		"json": {diffFormat: "json", wantJSON: true},
+		"both": {diffFormat: "both", wantText: true, wantJSON: true},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printChangeDiff(&buf, change, false, tc.diffFormat)
+			got := buf.String()
+			if strings.Contains(got, "+++ Desired State") != tc.wantText {
+				t.Errorf("Want text diff: %t, got:\n%s", tc.wantText, got)
+			}
+			if strings.Contains(got, "--- JSON Patch") != tc.wantJSON {
+				t.Errorf("Want JSON patch: %t, got:\n%s", tc.wantJSON, got)
+			}
+		})
+	}
+}
+
+func TestTemplateFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, f := range []string{"a.yml", "b.txt", "build/c.yaml", "deploy/web/d.yml", ".git/e.yml", "deploy/.old/f.yml"} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]struct {
+		recursive bool
+		want      []string
+	}{
+		"top-level only": {
+			recursive: false,
+			want:      []string{"a.yml"},
+		},
+		"recursive": {
+			recursive: true,
+			want:      []string{"a.yml", "build/c.yaml", "deploy/web/d.yml"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := templateFiles(dir, tc.recursive, &ignoreRules{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("Want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// Types of events emitted with --events.
+const (
+	EventTemplateProcessed = "template-processed"
+	EventResourceExported  = "resource-exported"
+	EventChangeDetected    = "change-detected"
+	EventApplied           = "applied"
+)
+
+// eventsOutput receives the events. It refers to the original STDOUT, which
+// is still in place when the human-readable output is suppressed.
+var eventsOutput io.Writer = os.Stdout
+
+// Event is emitted as one line of JSON for each step Tailor performs, so
+// that tooling can render progress while Tailor is still working.
+type Event struct {
+	Type     string `json:"type"`
+	Template string `json:"template,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Name     string `json:"name,omitempty"`
+	ChangeID string `json:"changeId,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// emitEvent writes e to eventsOutput if --events is given.
+func emitEvent(compareOptions *cli.CompareOptions, e Event) {
+	if !compareOptions.Events {
+		return
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		cli.DebugMsg("Could not encode event:", err.Error())
+		return
+	}
+	fmt.Fprintln(eventsOutput, cli.Redact(string(b)))
+}
+
+// emitChangeEvents emits an event for each change of changeset, including
+// resources in sync.
+func emitChangeEvents(compareOptions *cli.CompareOptions, changeset *openshift.Changeset) {
+	for _, changes := range [][]*openshift.Change{changeset.Delete, changeset.Create, changeset.Update, changeset.Noop} {
+		for _, change := range changes {
+			emitEvent(compareOptions, Event{
+				Type:   EventChangeDetected,
+				Action: change.Action,
+				Kind:   change.Kind,
+				Name:   change.Name,
+			})
+		}
+	}
+}
+
+// suppressHumanOutput redirects STDOUT to the null device while events are
+// emitted. The returned function restores STDOUT.
+func suppressHumanOutput() (func(), error) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	stdout := os.Stdout
+	os.Stdout = devNull
+	return func() {
+		os.Stdout = stdout
+		devNull.Close()
+	}, nil
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+func TestEmitChangeEvents(t *testing.T) {
+	changeset := &openshift.Changeset{}
+	changeset.Add(
+		&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "foo"},
+		&openshift.Change{Action: "Delete", Kind: "Service", Name: "bar"},
+		&openshift.Change{Action: "Noop", Kind: "Route", Name: "baz"},
+	)
+	tests := map[string]struct {
+		events bool
+		want   string
+	}{
+		"enabled": {
+			events: true,
+			want: `{"type":"change-detected","action":"Delete","kind":"Service","name":"bar"}
+{"type":"change-detected","action":"Create","kind":"ConfigMap","name":"foo"}
+{"type":"change-detected","action":"Noop","kind":"Route","name":"baz"}
+`,
+		},
+		"disabled": {
+			events: false,
+			want:   "",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			stdout := eventsOutput
+			eventsOutput = &buf
+			defer func() { eventsOutput = stdout }()
+			emitChangeEvents(&cli.CompareOptions{Events: tc.events}, changeset)
+			if buf.String() != tc.want {
+				t.Fatalf("Want:\n%s\ngot:\n%s", tc.want, buf.String())
+			}
+		})
+	}
+}
@@ -2,6 +2,10 @@ package commands
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/openshift"
@@ -13,24 +17,165 @@ func Export(exportOptions *cli.ExportOptions) error {
 	if err != nil {
 		return err
 	}
+	filter.ExcludedFieldManagers = exportOptions.ExcludedFieldManagers
+	err = filter.RestrictAPIGroups(exportOptions.APIGroups, exportOptions.ExcludedAPIGroups)
+	if err != nil {
+		return err
+	}
+	err = filter.RestrictKinds(exportOptions.OnlyKinds)
+	if err != nil {
+		return err
+	}
 
 	c := cli.NewOcClient(exportOptions.Namespace)
-	out, err := openshift.ExportAsTemplateFile(
+	if len(exportOptions.GroupByLabel) > 0 {
+		return exportGroupedByLabel(exportOptions, filter, c)
+	}
+
+	var out string
+	if exportOptions.Format == "list" {
+		out, err = openshift.ExportAsList(
+			filter,
+			exportOptions.WithAnnotations,
+			exportOptions.Namespace,
+			exportOptions.TrimAnnotations,
+			exportOptions.AnnotationPrefixes,
+			exportOptions.StripPaths,
+			c,
+		)
+	} else {
+		out, err = openshift.ExportAsTemplateFile(
+			filter,
+			exportOptions.WithAnnotations,
+			exportOptions.Namespace,
+			exportOptions.WithHardcodedNamespace,
+			exportOptions.TrimAnnotations,
+			exportOptions.AnnotationPrefixes,
+			exportOptions.StripPaths,
+			c,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf(
+			"Could not export %s resources as %s: %w",
+			filter.String(),
+			exportOptions.Format,
+			err,
+		)
+	}
+
+	if !exportOptions.Write {
+		fmt.Println(out)
+		return nil
+	}
+
+	if len(out) == 0 {
+		fmt.Println("No resources found, nothing to write.")
+		return nil
+	}
+
+	return writeExport(exportOptions, exportFilename(filter), out)
+}
+
+// writeExport writes out into the file of given name in the template
+// directory. An existing file is only overwritten with --force.
+func writeExport(exportOptions *cli.ExportOptions, name string, out string) error {
+	filename := exportOptions.TemplateDir + string(os.PathSeparator) + name
+	if exportOptions.FileExists(filename) && !exportOptions.Force {
+		return fmt.Errorf("'%s' already exists. Refusing to overwrite without --force", filename)
+	}
+	return writeTemplate(exportOptions.TemplateDir, filename, out)
+}
+
+// exportGroupedByLabel exports one template per distinct value of the label
+// given via --group-by-label. Each template is named after the label value.
+func exportGroupedByLabel(exportOptions *cli.ExportOptions, filter *openshift.ResourceFilter, ocClient cli.OcClientExporter) error {
+	templates, err := openshift.ExportAsTemplateFilesByLabel(
+		exportOptions.GroupByLabel,
 		filter,
 		exportOptions.WithAnnotations,
 		exportOptions.Namespace,
 		exportOptions.WithHardcodedNamespace,
 		exportOptions.TrimAnnotations,
-		c,
+		exportOptions.AnnotationPrefixes,
+		exportOptions.StripPaths,
+		ocClient,
 	)
 	if err != nil {
 		return fmt.Errorf(
-			"Could not export %s resources as template: %s",
+			"Could not export %s resources grouped by label %s: %w",
 			filter.String(),
+			exportOptions.GroupByLabel,
 			err,
 		)
 	}
 
-	fmt.Println(out)
+	if len(templates) == 0 {
+		if exportOptions.Write {
+			fmt.Println("No resources found, nothing to write.")
+		}
+		return nil
+	}
+
+	groups := []string{}
+	for group := range templates {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	if !exportOptions.Write {
+		for _, group := range groups {
+			fmt.Printf("# %s.yml\n", group)
+			fmt.Println(templates[group])
+		}
+		return nil
+	}
+
+	// Check all files upfront so that nothing is written if any exists.
+	for _, group := range groups {
+		filename := exportOptions.TemplateDir + string(os.PathSeparator) + group + ".yml"
+		if exportOptions.FileExists(filename) && !exportOptions.Force {
+			return fmt.Errorf("'%s' already exists. Refusing to overwrite without --force", filename)
+		}
+	}
+	for _, group := range groups {
+		filename := exportOptions.TemplateDir + string(os.PathSeparator) + group + ".yml"
+		err := writeTemplate(exportOptions.TemplateDir, filename, templates[group])
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// writeTemplate writes the template into filename, creating templateDir if
+// necessary.
+func writeTemplate(templateDir string, filename string, template string) error {
+	err := os.MkdirAll(templateDir, 0755)
+	if err != nil {
+		return fmt.Errorf("Could not create template directory '%s': %s", templateDir, err)
+	}
+	err = ioutil.WriteFile(filename, []byte(template), 0644)
+	if err != nil {
+		return fmt.Errorf("Could not write template: %s", err)
+	}
+	fmt.Printf("Template written to %s.\n", filename)
+	return nil
+}
+
+// exportFilename returns the name of the file an export of the resources
+// targeted by filter is written to.
+func exportFilename(filter *openshift.ResourceFilter) string {
+	if len(filter.Name) > 0 {
+		nameParts := strings.Split(filter.Name, "/")
+		return nameParts[1] + ".yml"
+	}
+	if len(filter.Kinds) > 0 {
+		kinds := []string{}
+		for _, k := range filter.Kinds {
+			kinds = append(kinds, strings.ToLower(k))
+		}
+		return strings.Join(kinds, "-") + ".yml"
+	}
+	return "template.yml"
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestExportFilename(t *testing.T) {
+	tests := map[string]struct {
+		filter *openshift.ResourceFilter
+		want   string
+	}{
+		"single resource": {
+			filter: &openshift.ResourceFilter{Name: "DeploymentConfig/foo"},
+			want:   "foo.yml",
+		},
+		"single kind": {
+			filter: &openshift.ResourceFilter{Kinds: []string{"DeploymentConfig"}},
+			want:   "deploymentconfig.yml",
+		},
+		"several kinds": {
+			filter: &openshift.ResourceFilter{Kinds: []string{"DeploymentConfig", "Service"}},
+			want:   "deploymentconfig-service.yml",
+		},
+		"selector only": {
+			filter: &openshift.ResourceFilter{Label: "app=foo"},
+			want:   "template.yml",
+		},
+		"no restriction": {
+			filter: &openshift.ResourceFilter{},
+			want:   "template.yml",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := exportFilename(tc.filter)
+			if got != tc.want {
+				t.Fatalf("Want '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteExport(t *testing.T) {
+	tests := map[string]struct {
+		existing  bool
+		force     bool
+		wantError string
+		want      string
+	}{
+		"new file": {
+			want: "new",
+		},
+		"existing file": {
+			existing:  true,
+			wantError: "already exists. Refusing to overwrite without --force",
+			want:      "old",
+		},
+		"existing file with --force": {
+			existing: true,
+			force:    true,
+			want:     "new",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "tailor-export")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			filename := filepath.Join(dir, "foo.yml")
+			if tc.existing {
+				if err := ioutil.WriteFile(filename, []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			globalOptions.Force = tc.force
+			exportOptions := &cli.ExportOptions{
+				GlobalOptions: globalOptions,
+				TemplateDir:   dir,
+			}
+			err = writeExport(exportOptions, "foo.yml", "new")
+			if len(tc.wantError) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("Want error '%s', got: %v", tc.wantError, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tc.want {
+				t.Fatalf("Want content '%s', got '%s'", tc.want, string(b))
+			}
+		})
+	}
+}
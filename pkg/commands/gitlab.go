@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+var gitLabNoteTimeout = 10 * time.Second
+
+// gitLabMRNoteTarget identifies the merge request to post a note to. It is
+// taken from the variables GitLab CI defines for merge request pipelines,
+// with the token given via GITLAB_TOKEN.
+type gitLabMRNoteTarget struct {
+	APIURL         string
+	ProjectID      string
+	MergeRequestID string
+	Token          string
+}
+
+func gitLabMRNoteTargetFromEnv(getenv func(string) string) (*gitLabMRNoteTarget, error) {
+	t := &gitLabMRNoteTarget{
+		APIURL:         getenv("CI_API_V4_URL"),
+		ProjectID:      getenv("CI_PROJECT_ID"),
+		MergeRequestID: getenv("CI_MERGE_REQUEST_IID"),
+		Token:          getenv("GITLAB_TOKEN"),
+	}
+	if len(t.APIURL) == 0 {
+		t.APIURL = "https://gitlab.com/api/v4"
+	}
+	missing := []string{}
+	if len(t.ProjectID) == 0 {
+		missing = append(missing, "CI_PROJECT_ID")
+	}
+	if len(t.MergeRequestID) == 0 {
+		missing = append(missing, "CI_MERGE_REQUEST_IID")
+	}
+	if len(t.Token) == 0 {
+		missing = append(missing, "GITLAB_TOKEN")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Environment variable(s) %s not set", strings.Join(missing, ", "))
+	}
+	return t, nil
+}
+
+// postGitLabMRNote posts a summary of changeset as note to the merge request
+// given via the environment. Unless required is set, failures are only
+// reported on w, so that e.g. an expired token does not fail the diff.
+func postGitLabMRNote(w io.Writer, changeset *openshift.Changeset, compareOptions *cli.CompareOptions, required bool) error {
+	err := func() error {
+		target, err := gitLabMRNoteTargetFromEnv(os.Getenv)
+		if err != nil {
+			return err
+		}
+		return target.post(gitLabMRNoteBody(changeset, compareOptions))
+	}()
+	if err == nil {
+		fmt.Fprintln(w, "Posted drift summary to GitLab merge request.")
+		return nil
+	}
+	if required {
+		return fmt.Errorf("Could not post drift summary to GitLab merge request: %s", err)
+	}
+	cli.FprintYellowf(w, "Warning: Could not post drift summary to GitLab merge request: %s\n", err)
+	return nil
+}
+
+func (t *gitLabMRNoteTarget) post(body string) error {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf(
+		"%s/projects/%s/merge_requests/%s/notes",
+		strings.TrimSuffix(t.APIURL, "/"),
+		url.PathEscape(t.ProjectID),
+		url.PathEscape(t.MergeRequestID),
+	)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", t.Token)
+	client := &http.Client{Timeout: gitLabNoteTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return errors.New("GitLab responded with " + resp.Status)
+	}
+	return nil
+}
+
+// gitLabMRNoteBody renders the changeset as Markdown. Only resource names
+// are listed, never their configuration.
+func gitLabMRNoteBody(changeset *openshift.Changeset, compareOptions *cli.CompareOptions) string {
+	printed, maskedSecrets := maskSecrets(changeset, compareOptions)
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### Tailor drift in namespace `%s`\n\n", compareOptions.Namespace)
+	if printed.Blank() {
+		fmt.Fprintf(&b, "No drift, %d resource(s) in sync.\n", len(printed.Noop))
+	} else {
+		fmt.Fprintf(
+			&b,
+			"%d to create, %d to update, %d to delete, %d in sync.\n\n",
+			len(printed.Create),
+			len(printed.Update),
+			len(printed.Delete),
+			len(printed.Noop),
+		)
+		fmt.Fprintln(&b, "| Action | Resource |")
+		fmt.Fprintln(&b, "| ------ | -------- |")
+		for _, changes := range [][]*openshift.Change{printed.Delete, printed.Create, printed.Update} {
+			for _, change := range changes {
+				fmt.Fprintf(&b, "| %s | `%s`%s |\n", change.Action, change.ItemName(), diffOnlyMarker(change))
+			}
+		}
+	}
+	if maskedSecrets > 0 {
+		fmt.Fprintf(&b, "\n%d Secret(s) masked.\n", maskedSecrets)
+	}
+	return cli.Redact(b.String())
+}
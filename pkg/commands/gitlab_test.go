@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestGitLabMRNoteTargetFromEnv(t *testing.T) {
+	tests := map[string]struct {
+		env        map[string]string
+		wantAPIURL string
+		wantErr    string
+	}{
+		"complete": {
+			env: map[string]string{
+				"CI_API_V4_URL":        "https://gitlab.example.com/api/v4",
+				"CI_PROJECT_ID":        "42",
+				"CI_MERGE_REQUEST_IID": "7",
+				"GITLAB_TOKEN":         "s3cr3t",
+			},
+			wantAPIURL: "https://gitlab.example.com/api/v4",
+		},
+		"default API URL": {
+			env: map[string]string{
+				"CI_PROJECT_ID":        "42",
+				"CI_MERGE_REQUEST_IID": "7",
+				"GITLAB_TOKEN":         "s3cr3t",
+			},
+			wantAPIURL: "https://gitlab.com/api/v4",
+		},
+		"not in merge request pipeline": {
+			env: map[string]string{
+				"CI_PROJECT_ID": "42",
+			},
+			wantErr: "Environment variable(s) CI_MERGE_REQUEST_IID, GITLAB_TOKEN not set",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := gitLabMRNoteTargetFromEnv(func(key string) string { return tc.env[key] })
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.APIURL != tc.wantAPIURL {
+				t.Fatalf("Want API URL %s, got %s", tc.wantAPIURL, got.APIURL)
+			}
+		})
+	}
+}
+
+func TestGitLabMRNotePost(t *testing.T) {
+	var gotPath, gotToken, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotToken = r.Header.Get("PRIVATE-TOKEN")
+		var payload map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		gotBody = payload["body"]
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	changeset := &openshift.Changeset{}
+	changeset.Add(
+		&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "foo"},
+		&openshift.Change{Action: "Noop", Kind: "Service", Name: "foo"},
+	)
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    cli.InitGlobalOptions(&utils.OsFS{}),
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+	}
+	target := &gitLabMRNoteTarget{
+		APIURL:         server.URL + "/",
+		ProjectID:      "group/project",
+		MergeRequestID: "7",
+		Token:          "s3cr3t",
+	}
+	err := target.post(gitLabMRNoteBody(changeset, compareOptions))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/projects/group%2Fproject/merge_requests/7/notes" {
+		t.Fatalf("Unexpected path %s", gotPath)
+	}
+	if gotToken != "s3cr3t" {
+		t.Fatalf("Unexpected token %s", gotToken)
+	}
+	for _, want := range []string{"namespace `foo`", "1 to create, 0 to update, 0 to delete, 1 in sync.", "| Create | `cm/foo` |"} {
+		if !strings.Contains(gotBody, want) {
+			t.Fatalf("Want note to contain '%s', got:\n%s", want, gotBody)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+var htmlDiffTemplate = template.Must(template.New("diff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Tailor diff of {{.Namespace}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+summary { cursor: pointer; font-family: monospace; font-size: 1.1em; padding: 0.2em 0; }
+pre { background: #f6f8fa; padding: 1em; overflow-x: auto; }
+.create { color: #22863a; }
+.update { color: #b08800; }
+.delete { color: #cb2431; }
+.added { color: #22863a; background: #f0fff4; }
+.removed { color: #cb2431; background: #ffeef0; }
+.hunk { color: #6f42c1; }
+</style>
+</head>
+<body>
+<h1>Tailor diff of {{.Namespace}}</h1>
+<p>Summary: {{.InSync}} in sync, <span class="create">{{len .Create}} to create</span>, <span class="update">{{len .Update}} to update</span>, <span class="delete">{{len .Delete}} to delete</span>{{if .MaskedSecrets}}, {{.MaskedSecrets}} Secret(s) masked{{end}}</p>
+{{range .Sections}}{{$section := .}}{{range .Changes}}<details>
+<summary class="{{$section.Class}}">{{$section.Symbol}} {{.ItemName}} to {{$section.Class}}</summary>
+<pre>{{range .Lines}}<span class="{{.Class}}">{{.Text}}</span>
+{{end}}</pre>
+</details>
+{{end}}{{end}}</body>
+</html>
+`))
+
+type htmlDiffLine struct {
+	Class string
+	Text  string
+}
+
+type htmlDiffChange struct {
+	ItemName string
+	Lines    []htmlDiffLine
+}
+
+type htmlDiffSection struct {
+	Class   string
+	Symbol  string
+	Changes []htmlDiffChange
+}
+
+// printHTMLDiff renders the changeset as a standalone HTML page. Secret
+// resources are left out with --mask-secrets.
+func printHTMLDiff(w io.Writer, changeset *openshift.Changeset, compareOptions *cli.CompareOptions) error {
+	changeset, maskedSecrets := maskSecrets(changeset, compareOptions)
+	revealSecrets := compareOptions.RevealSecrets
+	sections := []htmlDiffSection{
+		{Class: "delete", Symbol: "-", Changes: htmlDiffChanges(changeset.Delete, revealSecrets)},
+		{Class: "create", Symbol: "+", Changes: htmlDiffChanges(changeset.Create, revealSecrets)},
+		{Class: "update", Symbol: "~", Changes: htmlDiffChanges(changeset.Update, revealSecrets)},
+	}
+	return htmlDiffTemplate.Execute(w, map[string]interface{}{
+		"Namespace":     compareOptions.Namespace,
+		"InSync":        len(changeset.Noop),
+		"Create":        changeset.Create,
+		"Update":        changeset.Update,
+		"Delete":        changeset.Delete,
+		"MaskedSecrets": maskedSecrets,
+		"Sections":      sections,
+	})
+}
+
+func htmlDiffChanges(changes []*openshift.Change, revealSecrets bool) []htmlDiffChange {
+	htmlChanges := []htmlDiffChange{}
+	for _, change := range changes {
+		lines := []htmlDiffLine{}
+		diff := strings.TrimSuffix(change.Diff(revealSecrets), "\n")
+		for _, line := range strings.Split(diff, "\n") {
+			class := ""
+			if strings.HasPrefix(line, "@@") {
+				class = "hunk"
+			} else if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
+				class = "added"
+			} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+				class = "removed"
+			}
+			lines = append(lines, htmlDiffLine{Class: class, Text: line})
+		}
+		htmlChanges = append(htmlChanges, htmlDiffChange{
+			ItemName: change.ItemName(),
+			Lines:    lines,
+		})
+	}
+	return htmlChanges
+}
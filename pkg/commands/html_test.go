@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+func TestPrintHTMLDiff(t *testing.T) {
+	changeset := &openshift.Changeset{
+		Create: []*openshift.Change{
+			{
+				Action:       "Create",
+				Kind:         "ConfigMap",
+				Name:         "foo",
+				DesiredState: "kind: ConfigMap\ndata:\n  foo: <bar>\n",
+			},
+		},
+		Update: []*openshift.Change{
+			{
+				Action:       "Update",
+				Kind:         "Secret",
+				Name:         "baz",
+				CurrentState: "kind: Secret\ndata:\n  token: old\n",
+				DesiredState: "kind: Secret\ndata:\n  token: new\n",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    &cli.GlobalOptions{},
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo-dev"},
+	}
+	err := printHTMLDiff(&buf, changeset, compareOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"<title>Tailor diff of foo-dev</title>",
+		"cm/foo to create</summary>",
+		"<summary class=\"update\">~ secret/baz to update</summary>",
+		"foo: &lt;bar&gt;",
+		"Secret drift is hidden",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("Want HTML to contain '%s', got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "token: new") {
+		t.Fatalf("Secret content should be masked, got:\n%s", got)
+	}
+}
+
+func TestPrintHTMLDiffMaskSecrets(t *testing.T) {
+	changeset := &openshift.Changeset{}
+	changeset.Add(
+		&openshift.Change{Action: "Create", Kind: "ConfigMap", Name: "foo", DesiredState: "kind: ConfigMap\n"},
+		&openshift.Change{Action: "Create", Kind: "Secret", Name: "baz", DesiredState: "kind: Secret\n"},
+	)
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    &cli.GlobalOptions{},
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo-dev"},
+		MaskSecrets:      true,
+	}
+
+	var buf bytes.Buffer
+	err := printHTMLDiff(&buf, changeset, compareOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, "1 to create</span>") || !strings.Contains(got, "1 Secret(s) masked") {
+		t.Fatalf("Want Secret to be counted as masked, got:\n%s", got)
+	}
+	if strings.Contains(got, "baz") {
+		t.Fatalf("Want Secret name to be masked, got:\n%s", got)
+	}
+}
@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+// defaultIgnoreFile is looked up in the template dir if no ignore file is
+// given explicitly.
+const defaultIgnoreFile = ".tailorignore"
+
+// ignoreRule is one pattern of an ignore file.
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreRules decide which files in the template dir are not processed. The
+// syntax follows .gitignore: one glob pattern per line, "#" starts a comment,
+// "!" re-includes, a trailing "/" matches directories only and a pattern
+// containing "/" is matched against the path relative to the template dir
+// instead of the file name. Glob patterns are interpreted by path.Match, so
+// "**" is not supported.
+type ignoreRules struct {
+	source string
+	rules  []ignoreRule
+}
+
+// readIgnoreRules reads the rules from ignoreFile, or from the .tailorignore
+// file in templateDir if ignoreFile is blank. A missing .tailorignore file
+// results in no rules.
+func readIgnoreRules(templateDir string, ignoreFile string) (*ignoreRules, error) {
+	filename := ignoreFile
+	if len(filename) == 0 {
+		filename = filepath.Join(templateDir, defaultIgnoreFile)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return &ignoreRules{}, nil
+		}
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read ignore file '%s': %s", filename, err)
+	}
+	cli.DebugMsg("Reading ignore rules from", filename)
+	return parseIgnoreRules(filename, b)
+}
+
+func parseIgnoreRules(source string, b []byte) (*ignoreRules, error) {
+	r := &ignoreRules{source: source}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("Invalid pattern '%s' in ignore file '%s': %s", line, source, err)
+		}
+		rule.pattern = line
+		r.rules = append(r.rules, rule)
+	}
+	return r, scanner.Err()
+}
+
+// ignored returns true if rel (relative to the template dir) is to be
+// skipped. The last matching rule wins.
+func (r *ignoreRules) ignored(rel string, isDir bool) bool {
+	rel = filepath.ToSlash(rel)
+	ignored := false
+	for _, rule := range r.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if !rule.anchored {
+			target = path.Base(rel)
+		}
+		if matched, _ := path.Match(rule.pattern, target); matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules, err := parseIgnoreRules(".tailorignore", []byte(`# fragments
+*-fragment.yml
+docs/
+/deploy/legacy-*.yml
+!keep-fragment.yml
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		"unmatched file":              {rel: "foo.yml", want: false},
+		"file matched by name":        {rel: "foo-fragment.yml", want: true},
+		"nested file matched by name": {rel: "build/foo-fragment.yml", want: true},
+		"re-included file":            {rel: "keep-fragment.yml", want: false},
+		"directory":                   {rel: "docs", isDir: true, want: true},
+		"file named like directory":   {rel: "docs", isDir: false, want: false},
+		"anchored pattern":            {rel: "deploy/legacy-foo.yml", want: true},
+		"anchored pattern elsewhere":  {rel: "build/deploy/legacy-foo.yml", want: false},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := rules.ignored(tc.rel, tc.isDir)
+			if got != tc.want {
+				t.Fatalf("Want ignored=%t for %s, got %t", tc.want, tc.rel, got)
+			}
+		})
+	}
+}
+
+func TestIgnoreRulesInvalidPattern(t *testing.T) {
+	_, err := parseIgnoreRules(".tailorignore", []byte("foo[.yml\n"))
+	if err == nil || !strings.Contains(err.Error(), "Invalid pattern 'foo[.yml'") {
+		t.Fatalf("Want invalid pattern error, got: %v", err)
+	}
+}
+
+func TestTemplateFilesWithTailorignore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"a.yml":             "",
+		"b-fragment.yml":    "",
+		"docs/c.yml":        "",
+		"deploy/d.yml":      "",
+		".tailorignore":     "*-fragment.yml\ndocs/\n",
+		"other/ignore-list": "deploy/\n",
+	}
+	for f, content := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]struct {
+		ignoreFile string
+		recursive  bool
+		want       []string
+	}{
+		"no recursion": {
+			want: []string{"a.yml"},
+		},
+		"recursive": {
+			recursive: true,
+			want:      []string{"a.yml", "deploy/d.yml"},
+		},
+		"override": {
+			ignoreFile: filepath.Join(dir, "other/ignore-list"),
+			recursive:  true,
+			want:       []string{"a.yml", "b-fragment.yml", "docs/c.yml"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ignore, err := readIgnoreRules(dir, tc.ignoreFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := templateFiles(dir, tc.recursive, ignore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("Want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+type managedResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// List prints the resources which the templates would manage (kind/name),
+// either as plain text (one resource per line) or as JSON. The cluster is
+// not contacted.
+func List(compareOptions *cli.CompareOptions, format string) error {
+	ocClient := cli.NewOcClient(compareOptions.Namespace)
+	return listManagedResources(os.Stdout, compareOptions, format, ocClient)
+}
+
+func listManagedResources(w io.Writer, compareOptions *cli.CompareOptions, format string, ocClient cli.OcClientProcessor) error {
+	filter, err := newResourceFilter(compareOptions)
+	if err != nil {
+		return err
+	}
+	templateBasedList, err := assembleTemplateBasedResourceList(filter, compareOptions, ocClient)
+	if err != nil {
+		return err
+	}
+
+	resources := []managedResource{}
+	for _, item := range templateBasedList.Items {
+		resources = append(resources, managedResource{Kind: item.Kind, Name: item.Name})
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if resources[i].Kind != resources[j].Kind {
+			return resources[i].Kind < resources[j].Kind
+		}
+		return resources[i].Name < resources[j].Name
+	})
+
+	if format == "json" {
+		b, err := json.MarshalIndent(resources, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(b))
+		return nil
+	}
+	for _, r := range resources {
+		fmt.Fprintf(w, "%s/%s\n", r.Kind, r.Name)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+func TestListManagedResources(t *testing.T) {
+	tests := map[string]struct {
+		format   string
+		resource string
+		want     string
+	}{
+		"text": {
+			format: "text",
+			want:   "BuildConfig/foo\nImageStream/foo\n",
+		},
+		"json": {
+			format: "json",
+			want: `[
+  {
+    "kind": "BuildConfig",
+    "name": "foo"
+  },
+  {
+    "kind": "ImageStream",
+    "name": "foo"
+  }
+]
+`,
+		},
+		"limited to kind": {
+			format:   "text",
+			resource: "is",
+			want:     "ImageStream/foo\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    cli.InitGlobalOptions(&utils.OsFS{}),
+				NamespaceOptions: &cli.NamespaceOptions{},
+				TemplateDir:      "../../internal/test/fixtures/command-apply/template-dir",
+				ParamFiles:       []string{},
+				Resource:         tc.resource,
+			}
+			ocClient := &mockOcOfflineClient{
+				mockOcApplyClient{
+					t:              t,
+					desiredFixture: "template-dir/desired-list.yml",
+				},
+			}
+			var buf bytes.Buffer
+			err := listManagedResources(&buf, compareOptions, tc.format, ocClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, buf.String()); diff != "" {
+				t.Fatalf("Output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
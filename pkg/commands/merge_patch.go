@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// mergePatchDocument describes one update as JSON merge patch, which can be
+// applied e.g. via "oc patch <kind> <name> --type merge -p <patch>".
+type mergePatchDocument struct {
+	Kind  string                 `json:"kind"`
+	Name  string                 `json:"name"`
+	Type  string                 `json:"type"`
+	Patch map[string]interface{} `json:"patch"`
+}
+
+// printMergePatches prints a JSON list with the merge patch of each update.
+// Creations and deletions affect whole resources and are not included.
+// Diff-only updates (e.g. drift of immutable fields with --on-immutable=warn)
+// must never be applied, so they are omitted as well. Updates of Secret
+// resources are omitted unless revealSecrets is true, as
+// their patches contain the secret values. Values of sensitive params are
+// masked.
+func printMergePatches(w io.Writer, changeset *openshift.Changeset, revealSecrets bool) error {
+	documents := []mergePatchDocument{}
+	for _, change := range changeset.Update {
+		if change.DiffOnly {
+			cli.FprintYellowf(os.Stderr, "Omitting patch of %s as it is diff-only.\n", change.ItemName())
+			continue
+		}
+		if change.Kind == "Secret" && !revealSecrets {
+			cli.FprintYellowf(os.Stderr, "Omitting patch of %s. Use --reveal-secrets to include it.\n", change.ItemName())
+			continue
+		}
+		patch, err := change.MergePatch()
+		if err != nil {
+			return err
+		}
+		documents = append(documents, mergePatchDocument{
+			Kind:  change.Kind,
+			Name:  change.Name,
+			Type:  "merge",
+			Patch: patch,
+		})
+	}
+	b, err := json.MarshalIndent(documents, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Could not render merge patches: %s", err)
+	}
+	fmt.Fprintln(w, cli.Redact(string(b)))
+	return nil
+}
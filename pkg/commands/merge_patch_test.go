@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+func TestPrintMergePatchesRedactsSensitiveParams(t *testing.T) {
+	cli.AddRedactedValue("merge-patch-s3cr3t")
+	changeset := &openshift.Changeset{}
+	changeset.Add(&openshift.Change{
+		Action:       "Update",
+		Kind:         "ConfigMap",
+		Name:         "foo",
+		CurrentState: "data:\n  password: old\n",
+		DesiredState: "data:\n  password: merge-patch-s3cr3t\n",
+	})
+	var buf bytes.Buffer
+	err := printMergePatches(&buf, changeset, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if strings.Contains(got, "merge-patch-s3cr3t") {
+		t.Fatalf("Want sensitive param to be masked, got:\n%s", got)
+	}
+	if !strings.Contains(got, `"password": "***"`) {
+		t.Fatalf("Want masked value in patch, got:\n%s", got)
+	}
+}
+
+func TestPrintMergePatchesOmitsDiffOnly(t *testing.T) {
+	changeset := &openshift.Changeset{}
+	changeset.Add(
+		&openshift.Change{
+			Action:       "Update",
+			Kind:         "ConfigMap",
+			Name:         "foo",
+			CurrentState: "data:\n  foo: old\n",
+			DesiredState: "data:\n  foo: new\n",
+		},
+		&openshift.Change{
+			Action:         "Update",
+			Kind:           "Route",
+			Name:           "bar",
+			CurrentState:   "spec:\n  host: old.example.com\n",
+			DesiredState:   "spec:\n  host: new.example.com\n",
+			DiffOnly:       true,
+			ImmutablePaths: []string{"/spec/host"},
+		},
+	)
+	var buf bytes.Buffer
+	err := printMergePatches(&buf, changeset, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, `"name": "foo"`) {
+		t.Fatalf("Want patch of foo, got:\n%s", got)
+	}
+	if strings.Contains(got, `"name": "bar"`) || strings.Contains(got, "new.example.com") {
+		t.Fatalf("Want diff-only patch to be omitted, got:\n%s", got)
+	}
+}
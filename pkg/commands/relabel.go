@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/openshift"
+)
+
+// Relabel moves all resources carrying the --from label to the --to label.
+// If both labels share the same key, the value is overwritten, otherwise the
+// old label is removed. A failing resource does not stop the remaining ones
+// from being relabeled.
+func Relabel(relabelOptions *cli.RelabelOptions, ocClient cli.ClientRelabeler, stdin io.Reader) error {
+	filter, err := openshift.NewResourceFilter(relabelOptions.Resource, relabelOptions.From, relabelOptions.Excludes)
+	if err != nil {
+		return err
+	}
+	exportedOut, err := ocClient.Export(filter.ConvertToKinds(), filter.Label)
+	if err != nil {
+		return err
+	}
+	list, err := openshift.NewPlatformBasedResourceList(filter, exportedOut)
+	if err != nil {
+		return err
+	}
+	if list.Length() == 0 {
+		fmt.Printf("No resources labeled %s found in namespace %s, nothing to relabel.\n", relabelOptions.From, relabelOptions.Namespace)
+		return nil
+	}
+
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.FullName())
+	}
+	sort.Strings(names)
+
+	fmt.Printf("Relabeling %d resource(s) in namespace %s from %s to %s:\n", len(names), relabelOptions.Namespace, relabelOptions.From, relabelOptions.To)
+	for _, name := range names {
+		fmt.Printf("* %s\n", name)
+	}
+	fmt.Println("")
+
+	if !relabelOptions.NonInteractive {
+		a, err := cli.AskForAction("Relabel all resources?", []string{"y=yes", "n=no"}, bufio.NewReader(stdin))
+		if err != nil {
+			return err
+		}
+		if a != "y" {
+			return errors.New("Relabel aborted")
+		}
+		fmt.Println("")
+	}
+
+	labels := relabelArgs(relabelOptions.From, relabelOptions.To)
+	failed := 0
+	for _, name := range names {
+		fmt.Printf("Relabeling %s ... ", name)
+		parts := strings.SplitN(name, "/", 2)
+		errBytes, err := ocClient.Label(parts[0], parts[1], labels)
+		if err != nil {
+			fmt.Println("failed")
+			cli.FprintRedf(os.Stdout, "%s\n", strings.TrimSpace(string(errBytes)))
+			failed++
+			continue
+		}
+		fmt.Println("done")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d resource(s) could not be relabeled", failed, len(names))
+	}
+	return nil
+}
+
+// relabelArgs returns the "oc label" arguments to move a resource from label
+// from to label to (both in the form key=value).
+func relabelArgs(from string, to string) []string {
+	args := []string{to}
+	fromKey := strings.SplitN(from, "=", 2)[0]
+	toKey := strings.SplitN(to, "=", 2)[0]
+	if fromKey != toKey {
+		args = append(args, fromKey+"-")
+	}
+	return args
+}
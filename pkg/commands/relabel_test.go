@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/internal/test/helper"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+type mockOcRelabelClient struct {
+	t       *testing.T
+	labeled []string
+	labels  [][]string
+}
+
+func (c *mockOcRelabelClient) Export(target string, label string) ([]byte, error) {
+	return helper.ReadFixtureFile(c.t, "command-relabel/current-list.yml"), nil
+}
+
+func (c *mockOcRelabelClient) Label(kind string, name string, labels []string) ([]byte, error) {
+	c.labeled = append(c.labeled, kind+"/"+name)
+	c.labels = append(c.labels, labels)
+	return []byte(""), nil
+}
+
+func TestRelabel(t *testing.T) {
+	tests := map[string]struct {
+		nonInteractive bool
+		stdinInput     string
+		from           string
+		to             string
+		wantLabeled    []string
+		wantLabels     []string
+		wantErr        bool
+	}{
+		"non-interactively": {
+			nonInteractive: true,
+			from:           "app=foo",
+			to:             "app.example.com/name=foo",
+			wantLabeled:    []string{"ImageStream/foo", "Service/foo"},
+			wantLabels:     []string{"app.example.com/name=foo", "app-"},
+		},
+		"same key": {
+			stdinInput:  "y\n",
+			from:        "app=foo",
+			to:          "app=bar",
+			wantLabeled: []string{"ImageStream/foo", "Service/foo"},
+			wantLabels:  []string{"app=bar"},
+		},
+		"interactively aborted": {
+			stdinInput: "n\n",
+			from:       "app=foo",
+			to:         "app=bar",
+			wantErr:    true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			globalOptions := cli.InitGlobalOptions(&utils.OsFS{})
+			globalOptions.NonInteractive = tc.nonInteractive
+			relabelOptions := &cli.RelabelOptions{
+				GlobalOptions:    globalOptions,
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				From:             tc.from,
+				To:               tc.to,
+				Excludes:         []string{},
+			}
+			ocClient := &mockOcRelabelClient{t: t}
+			var stdin bytes.Buffer
+			stdin.Write([]byte(tc.stdinInput))
+			err := Relabel(relabelOptions, ocClient, &stdin)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Want error, got none")
+				}
+				if len(ocClient.labeled) > 0 {
+					t.Fatalf("Want no relabeled resources, got %v", ocClient.labeled)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantLabeled, ocClient.labeled); diff != "" {
+				t.Fatalf("Relabeled resources mismatch (-want +got):\n%s", diff)
+			}
+			for _, labels := range ocClient.labels {
+				if diff := cmp.Diff(tc.wantLabels, labels); diff != "" {
+					t.Fatalf("Labels mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package commands
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/openshift"
 	"github.com/opendevstack/tailor/pkg/utils"
@@ -43,8 +48,13 @@ func GenerateKey(secretsOptions *cli.SecretsOptions, email, name string) error {
 	return nil
 }
 
-// Reveal prints the clear-text of an encrypted file to STDOUT.
-func Reveal(secretsOptions *cli.SecretsOptions, filename string) error {
+// revealMaskVisibleChars is the number of trailing characters shown by
+// Reveal when values are masked.
+const revealMaskVisibleChars = 4
+
+// Reveal prints the clear-text of an encrypted file to STDOUT. If mask is
+// true, all but the last few characters of each value are masked.
+func Reveal(secretsOptions *cli.SecretsOptions, filename string, mask bool, output string) error {
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		return fmt.Errorf("'%s' does not exist", filename)
 	}
@@ -52,7 +62,8 @@ func Reveal(secretsOptions *cli.SecretsOptions, filename string) error {
 	if err != nil {
 		return fmt.Errorf("Could not read file: %s", err)
 	}
-	decryptedContent, err := openshift.DecryptedParams(
+	decryptedContent, err := decryptedParams(
+		filename,
 		encryptedContent,
 		secretsOptions.PrivateKey,
 		secretsOptions.Passphrase,
@@ -60,39 +71,244 @@ func Reveal(secretsOptions *cli.SecretsOptions, filename string) error {
 	if err != nil {
 		return fmt.Errorf("Could not decrypt file: %s", err)
 	}
+	if mask {
+		maskParams := openshift.MaskedParams
+		if openshift.IsYAMLParamFile(filename) {
+			maskParams = openshift.MaskedYAMLParams
+		}
+		decryptedContent, err = maskParams(decryptedContent, revealMaskVisibleChars)
+		if err != nil {
+			return fmt.Errorf("Could not mask values: %s", err)
+		}
+	}
+	if output == "json" {
+		return printParamsAsJSON(os.Stdout, filename, decryptedContent)
+	}
 	fmt.Println(decryptedContent)
 	return nil
 }
 
+// printParamsAsJSON prints the params in content as a JSON object. Params of
+// ".env" files are mapped from key to value, YAML param files keep their
+// structure.
+func printParamsAsJSON(w io.Writer, filename string, content string) error {
+	var b []byte
+	var err error
+	if openshift.IsYAMLParamFile(filename) {
+		b, err = yaml.YAMLToJSON([]byte(content))
+		if err != nil {
+			return fmt.Errorf("Could not convert params to JSON: %s", err)
+		}
+		if string(b) == "null" {
+			b = []byte("{}")
+		}
+		var out bytes.Buffer
+		err = json.Indent(&out, b, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Could not convert params to JSON: %s", err)
+		}
+		b = out.Bytes()
+	} else {
+		params, err := openshift.ParamsMap(content)
+		if err != nil {
+			return fmt.Errorf("Could not parse params: %s", err)
+		}
+		b, err = json.MarshalIndent(params, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Could not convert params to JSON: %s", err)
+		}
+	}
+	fmt.Fprintln(w, string(b))
+	return nil
+}
+
+// DiffSecrets prints which params differ between two encrypted files.
+// Values are only shown when reveal is true. It returns true if any
+// difference was found.
+func DiffSecrets(secretsOptions *cli.SecretsOptions, fromFilename, toFilename string, reveal bool) (bool, error) {
+	decrypted := []string{}
+	for _, filename := range []string{fromFilename, toFilename} {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			return false, fmt.Errorf("'%s' does not exist", filename)
+		}
+		encryptedContent, err := utils.ReadFile(filename)
+		if err != nil {
+			return false, fmt.Errorf("Could not read file: %s", err)
+		}
+		decryptedContent, err := decryptedParams(
+			filename,
+			encryptedContent,
+			secretsOptions.PrivateKey,
+			secretsOptions.Passphrase,
+		)
+		if err != nil {
+			return false, fmt.Errorf("Could not decrypt file '%s': %s", filename, err)
+		}
+		if openshift.IsYAMLParamFile(filename) {
+			decryptedContent, err = openshift.YAMLSecretsAsEnv(decryptedContent)
+			if err != nil {
+				return false, fmt.Errorf("Could not read file '%s': %s", filename, err)
+			}
+		}
+		decrypted = append(decrypted, decryptedContent)
+	}
+
+	d, err := openshift.NewParamsDiff(decrypted[0], decrypted[1])
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Printf("Comparing %s with %s.\n\n", fromFilename, toFilename)
+	for _, key := range d.Removed {
+		if reveal {
+			cli.FprintRedf(os.Stdout, "- %s=%s\n", key, d.From[key])
+		} else {
+			cli.FprintRedf(os.Stdout, "- %s\n", key)
+		}
+	}
+	for _, key := range d.Added {
+		if reveal {
+			cli.FprintGreenf(os.Stdout, "+ %s=%s\n", key, d.To[key])
+		} else {
+			cli.FprintGreenf(os.Stdout, "+ %s\n", key)
+		}
+	}
+	for _, key := range d.Changed {
+		if reveal {
+			cli.FprintYellowf(os.Stdout, "~ %s=%s -> %s\n", key, d.From[key], d.To[key])
+		} else {
+			cli.FprintYellowf(os.Stdout, "~ %s\n", key)
+		}
+	}
+	if d.Blank() {
+		fmt.Println("No differences found.")
+	} else if !reveal {
+		fmt.Println("\nValues are hidden. Use --reveal to see details.")
+	}
+	return !d.Blank(), nil
+}
+
 // ReEncrypt decrypts given file(s) and encrypts all params again.
-// This allows to share the secrets with a new keypair.
-func ReEncrypt(secretsOptions *cli.SecretsOptions, filename string) error {
-	if len(filename) > 0 {
-		err := reEncrypt(filename, secretsOptions.PrivateKey, secretsOptions.Passphrase, secretsOptions.PublicKeyDir)
+// This allows to share the secrets with a new keypair. If the recipients
+// change, they are shown and confirmation is asked for (unless running
+// non-interactively).
+func ReEncrypt(secretsOptions *cli.SecretsOptions, filename string, stdin io.Reader) error {
+	stdinReader := bufio.NewReader(stdin)
+	filenames, err := encryptedParamFiles(secretsOptions, filename)
+	if err != nil {
+		return err
+	}
+	for _, f := range filenames {
+		confirmed, err := confirmRecipientsChange(secretsOptions, f, stdinReader)
 		if err != nil {
 			return err
 		}
-	} else {
-		paramDir := secretsOptions.ParamDir
-		files, err := ioutil.ReadDir(paramDir)
+		if !confirmed {
+			fmt.Printf("Skipping %s.\n", f)
+			continue
+		}
+		err = reEncrypt(f, secretsOptions.PrivateKey, secretsOptions.Passphrase, secretsOptions.PublicKeyDir)
 		if err != nil {
 			return err
 		}
-		filePattern := ".*\\.env.enc$"
-		re := regexp.MustCompile(filePattern)
-		for _, file := range files {
-			matched := re.MatchString(file.Name())
-			if !matched {
-				continue
-			}
-			filename := paramDir + string(os.PathSeparator) + file.Name()
-			err := reEncrypt(filename, secretsOptions.PrivateKey, secretsOptions.Passphrase, secretsOptions.PublicKeyDir)
+	}
+	return nil
+}
+
+// confirmRecipientsChange prints which recipients would gain or lose access
+// to the params in filename, and asks whether to continue.
+func confirmRecipientsChange(secretsOptions *cli.SecretsOptions, filename string, stdinReader *bufio.Reader) (bool, error) {
+	encryptedContent, err := utils.ReadFile(filename)
+	if err != nil {
+		return false, fmt.Errorf("Could not read file: %s", err)
+	}
+	if openshift.IsYAMLParamFile(filename) {
+		encryptedContent, err = openshift.YAMLSecretsAsEnv(encryptedContent)
+		if err != nil {
+			return false, fmt.Errorf("Could not read file '%s': %s", filename, err)
+		}
+	}
+	d, err := openshift.NewRecipientsDiff(encryptedContent, secretsOptions.PublicKeyDir)
+	if err != nil {
+		return false, fmt.Errorf("Could not check file '%s': %s", filename, err)
+	}
+	if d.Blank() {
+		return true, nil
+	}
+	fmt.Printf("Recipients of %s will change:\n", filename)
+	for _, r := range d.Gained {
+		cli.FprintGreenf(os.Stdout, "+ %s will gain access\n", r)
+	}
+	for _, r := range d.Lost {
+		cli.FprintRedf(os.Stdout, "- key %s will lose access\n", r)
+	}
+	if secretsOptions.NonInteractive {
+		return true, nil
+	}
+	a, err := cli.AskForAction(
+		fmt.Sprintf("Re-encrypt %s?", filename),
+		[]string{"y=yes", "n=no"},
+		stdinReader,
+	)
+	return a == "y", err
+}
+
+// CheckReEncrypt reports for given file(s) whether re-encryption would change
+// the recipients of the params, without writing anything. It returns true if
+// any file would change.
+func CheckReEncrypt(secretsOptions *cli.SecretsOptions, filename string) (bool, error) {
+	filenames, err := encryptedParamFiles(secretsOptions, filename)
+	if err != nil {
+		return false, err
+	}
+	anyChanged := false
+	for _, f := range filenames {
+		encryptedContent, err := utils.ReadFile(f)
+		if err != nil {
+			return anyChanged, fmt.Errorf("Could not read file: %s", err)
+		}
+		if openshift.IsYAMLParamFile(f) {
+			encryptedContent, err = openshift.YAMLSecretsAsEnv(encryptedContent)
 			if err != nil {
-				return err
+				return anyChanged, fmt.Errorf("Could not read file '%s': %s", f, err)
 			}
 		}
+		changed, err := openshift.RecipientsChanged(encryptedContent, secretsOptions.PublicKeyDir)
+		if err != nil {
+			return anyChanged, fmt.Errorf("Could not check file '%s': %s", f, err)
+		}
+		if changed {
+			anyChanged = true
+			cli.FprintYellowf(os.Stdout, "~ %s would change\n", f)
+		} else {
+			fmt.Printf("* %s is up-to-date\n", f)
+		}
 	}
-	return nil
+	return anyChanged, nil
+}
+
+// encryptedParamFiles returns given filename, or, if blank, all files with
+// encrypted params in the param dir.
+func encryptedParamFiles(secretsOptions *cli.SecretsOptions, filename string) ([]string, error) {
+	if len(filename) > 0 {
+		return []string{filename}, nil
+	}
+	filenames := []string{}
+	paramDir := secretsOptions.ParamDir
+	files, err := ioutil.ReadDir(paramDir)
+	if err != nil {
+		return filenames, err
+	}
+	filePattern := ".*\\.(env|ya?ml)\\.enc$"
+	re := regexp.MustCompile(filePattern)
+	for _, file := range files {
+		matched := re.MatchString(file.Name())
+		if !matched {
+			continue
+		}
+		filenames = append(filenames, paramDir+string(os.PathSeparator)+file.Name())
+	}
+	return filenames, nil
 }
 
 // Edit opens given filen in cleartext in $EDITOR, then encrypts the content on save.
@@ -106,7 +322,8 @@ func Edit(secretsOptions *cli.SecretsOptions, filename string) error {
 		}
 	}
 
-	cleartextContent, err := openshift.DecryptedParams(
+	cleartextContent, err := decryptedParams(
+		filename,
 		encryptedContent,
 		secretsOptions.PrivateKey,
 		secretsOptions.Passphrase,
@@ -140,7 +357,8 @@ func reEncrypt(filename, privateKey, passphrase, publicKeyDir string) error {
 		return fmt.Errorf("Could not read file: %s", err)
 	}
 
-	cleartextContent, err := openshift.DecryptedParams(
+	cleartextContent, err := decryptedParams(
+		filename,
 		encryptedContent,
 		privateKey,
 		passphrase,
@@ -160,7 +378,11 @@ func reEncrypt(filename, privateKey, passphrase, publicKeyDir string) error {
 }
 
 func writeEncryptedContent(filename, newContent, previousContent, privateKey, passphrase, publicKeyDir string) error {
-	updatedContent, err := openshift.EncryptedParams(
+	encrypt := openshift.EncryptedParams
+	if openshift.IsYAMLParamFile(filename) {
+		encrypt = openshift.EncryptedYAMLParams
+	}
+	updatedContent, err := encrypt(
 		newContent,
 		previousContent,
 		publicKeyDir,
@@ -177,3 +399,12 @@ func writeEncryptedContent(filename, newContent, previousContent, privateKey, pa
 	}
 	return nil
 }
+
+// decryptedParams decrypts content of filename, which is either in ".env" or
+// in YAML format.
+func decryptedParams(filename, content, privateKey, passphrase string) (string, error) {
+	if openshift.IsYAMLParamFile(filename) {
+		return openshift.DecryptedYAMLParams(content, privateKey, passphrase)
+	}
+	return openshift.DecryptedParams(content, privateKey, passphrase)
+}
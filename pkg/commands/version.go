@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	latestReleaseURL    = "https://api.github.com/repos/opendevstack/tailor/releases/latest"
+	versionCheckTimeout = 5 * time.Second
+)
+
+// CheckVersion queries GitHub for the latest release of Tailor and reports
+// whether it is newer than given current version. As this is merely
+// informational, failures (e.g. when offline) are reported but do not
+// result in an error.
+func CheckVersion(w io.Writer, current string) {
+	latest, err := latestRelease()
+	if err != nil {
+		fmt.Fprintf(w, "Could not check for a newer version: %s\n", err)
+		return
+	}
+	if compareVersions(latest, current) > 0 {
+		fmt.Fprintf(w, "A newer version is available: %s (https://github.com/opendevstack/tailor/releases).\n", latest)
+	} else {
+		fmt.Fprintln(w, "You are using the latest version.")
+	}
+}
+
+func latestRelease() (string, error) {
+	client := &http.Client{Timeout: versionCheckTimeout}
+	resp, err := client.Get(latestReleaseURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub responded with %s", resp.Status)
+	}
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&release)
+	if err != nil {
+		return "", err
+	}
+	if len(release.TagName) == 0 {
+		return "", errors.New("No release found")
+	}
+	return strings.TrimPrefix(release.TagName, "v"), nil
+}
+
+// compareVersions compares two semantic versions such as "1.2.3" and returns
+// -1, 0 or 1 if a is lower than, equal to or greater than b. Build metadata
+// (e.g. "+master") is ignored, and pre-releases (e.g. "1.2.3-rc1") are lower
+// than the release they precede.
+func compareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	for i := 0; i < 3; i++ {
+		if aCore[i] != bCore[i] {
+			if aCore[i] < bCore[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case len(aPre) == 0:
+		return 1
+	case len(bPre) == 0:
+		return -1
+	case aPre < bPre:
+		return -1
+	default:
+		return 1
+	}
+}
+
+func splitVersion(v string) ([3]int, string) {
+	v = strings.TrimPrefix(v, "v")
+	v = strings.SplitN(v, "+", 2)[0]
+	pre := ""
+	if parts := strings.SplitN(v, "-", 2); len(parts) == 2 {
+		v, pre = parts[0], parts[1]
+	}
+	core := [3]int{}
+	for i, p := range strings.SplitN(v, ".", 3) {
+		n, _ := strconv.Atoi(p)
+		core[i] = n
+	}
+	return core, pre
+}
@@ -0,0 +1,78 @@
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := map[string]struct {
+		a    string
+		b    string
+		want int
+	}{
+		"equal":                    {a: "1.1.4", b: "1.1.4", want: 0},
+		"build metadata ignored":   {a: "1.1.4", b: "1.1.4+master", want: 0},
+		"newer patch":              {a: "1.1.5", b: "1.1.4+master", want: 1},
+		"numeric comparison":       {a: "1.10.0", b: "1.9.0", want: 1},
+		"older minor":              {a: "1.0.9", b: "1.1.0", want: -1},
+		"pre-release before final": {a: "1.2.0-rc1", b: "1.2.0", want: -1},
+		"v prefix":                 {a: "v2.0.0", b: "1.1.4", want: 1},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := compareVersions(tc.a, tc.b)
+			if got != tc.want {
+				t.Fatalf("Want %d, got %d", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	tests := map[string]struct {
+		status   int
+		body     string
+		current  string
+		wantText string
+	}{
+		"newer version available": {
+			status:   http.StatusOK,
+			body:     `{"tag_name": "v1.2.0"}`,
+			current:  "1.1.4+master",
+			wantText: "A newer version is available: 1.2.0",
+		},
+		"latest version": {
+			status:   http.StatusOK,
+			body:     `{"tag_name": "v1.1.4"}`,
+			current:  "1.1.4+master",
+			wantText: "You are using the latest version.",
+		},
+		"failing API": {
+			status:   http.StatusForbidden,
+			body:     `{}`,
+			current:  "1.1.4+master",
+			wantText: "Could not check for a newer version",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+			defer func(url string) { latestReleaseURL = url }(latestReleaseURL)
+			latestReleaseURL = server.URL
+
+			var buf bytes.Buffer
+			CheckVersion(&buf, tc.current)
+			if !strings.Contains(buf.String(), tc.wantText) {
+				t.Fatalf("Want output to contain '%s', got: %s", tc.wantText, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+const (
+	// watchInterval is how often watched directories are checked for changes.
+	watchInterval = 500 * time.Millisecond
+	// watchDebounce is how long files must be unchanged before a re-run is
+	// triggered, so that rapid saves (e.g. of several files) cause one run.
+	watchDebounce = 300 * time.Millisecond
+	// clearScreen moves the cursor to the top left and clears the terminal.
+	clearScreen = "\033[H\033[2J"
+)
+
+// fileSnapshot maps file paths to their modification time and size.
+type fileSnapshot map[string]string
+
+// WatchDiff runs Diff, and re-runs it whenever a file in the template, param
+// or patch directory changes. It only returns if the watched directories
+// cannot be read or the run context is done.
+func WatchDiff(compareOptions *cli.CompareOptions) error {
+	dirs := watchedDirs(compareOptions)
+	for {
+		if err := cli.RunContextErr(); err != nil {
+			return err
+		}
+		snapshot, err := takeSnapshot(dirs)
+		if err != nil {
+			return err
+		}
+		fmt.Print(clearScreen)
+		_, err = Diff(compareOptions)
+		if err != nil {
+			cli.FprintRedf(os.Stdout, "%s\n", err)
+		}
+		fmt.Printf("\nWatching %s for changes ...\n", strings.Join(dirs, ", "))
+		err = waitForChange(dirs, snapshot, watchInterval, watchDebounce)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// watchedDirs returns the distinct directories which influence the outcome
+// of a diff.
+func watchedDirs(compareOptions *cli.CompareOptions) []string {
+	dirs := []string{}
+	seen := map[string]bool{}
+	for _, d := range []string{compareOptions.TemplateDir, compareOptions.ParamDir, compareOptions.PatchDir} {
+		if len(d) == 0 || seen[d] {
+			continue
+		}
+		seen[d] = true
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// waitForChange blocks until the files in dirs differ from given snapshot,
+// and then until they did not change for the debounce duration.
+func waitForChange(dirs []string, snapshot fileSnapshot, interval time.Duration, debounce time.Duration) error {
+	for {
+		time.Sleep(interval)
+		if err := cli.RunContextErr(); err != nil {
+			return err
+		}
+		current, err := takeSnapshot(dirs)
+		if err != nil {
+			return err
+		}
+		if !current.equal(snapshot) {
+			snapshot = current
+			break
+		}
+	}
+	for {
+		time.Sleep(debounce)
+		if err := cli.RunContextErr(); err != nil {
+			return err
+		}
+		current, err := takeSnapshot(dirs)
+		if err != nil {
+			return err
+		}
+		if current.equal(snapshot) {
+			return nil
+		}
+		snapshot = current
+	}
+}
+
+// takeSnapshot records all files below dirs. Hidden directories (such as
+// .git) are skipped. Directories which do not exist are ignored.
+func takeSnapshot(dirs []string) (fileSnapshot, error) {
+	snapshot := fileSnapshot{}
+	for _, dir := range dirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path != dir && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			snapshot[path] = fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Could not watch '%s': %s", dir, err)
+		}
+	}
+	return snapshot, nil
+}
+
+func (s fileSnapshot) equal(other fileSnapshot) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for path, stamp := range s {
+		if other[path] != stamp {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+func TestWatchedDirs(t *testing.T) {
+	compareOptions := &cli.CompareOptions{TemplateDir: ".", ParamDir: ".", PatchDir: "patches"}
+	got := watchedDirs(compareOptions)
+	if len(got) != 2 || got[0] != "." || got[1] != "patches" {
+		t.Fatalf("Want [. patches], got %v", got)
+	}
+}
+
+func TestWaitForChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tailor-watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = os.Mkdir(filepath.Join(dir, ".git"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirs := []string{dir}
+	snapshot, err := takeSnapshot(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files in hidden directories are not watched.
+	err = ioutil.WriteFile(filepath.Join(dir, ".git", "index"), []byte("foo"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := takeSnapshot(dirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !current.equal(snapshot) {
+		t.Fatal("Want changes in hidden directories to be ignored")
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- waitForChange(dirs, snapshot, 10*time.Millisecond, 50*time.Millisecond)
+	}()
+	err = ioutil.WriteFile(filepath.Join(dir, "template.yml"), []byte("foo"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Want change to be detected")
+	}
+}
@@ -1,47 +1,81 @@
 package openshift
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ghodss/yaml"
+	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/opendevstack/tailor/pkg/utils"
 	"github.com/pmezard/go-difflib/difflib"
+	"github.com/xeipuuv/gojsonpointer"
 )
 
 var (
 	kindToShortMapping = map[string]string{
-		"Service":               "svc",
-		"Route":                 "route",
-		"DeploymentConfig":      "dc",
-		"Deployment":            "deployment",
-		"BuildConfig":           "bc",
-		"ImageStream":           "is",
-		"PersistentVolumeClaim": "pvc",
-		"Template":              "template",
-		"ConfigMap":             "cm",
-		"Secret":                "secret",
-		"RoleBinding":           "rolebinding",
-		"ServiceAccount":        "serviceaccount",
-		"CronJob":               "cronjob",
-		"Job":                   "job",
-		"LimitRange":            "limitrange",
-		"ResourceQuota":         "quota",
+		"Service":                 "svc",
+		"Route":                   "route",
+		"DeploymentConfig":        "dc",
+		"Deployment":              "deployment",
+		"BuildConfig":             "bc",
+		"ImageStream":             "is",
+		"PersistentVolumeClaim":   "pvc",
+		"Template":                "template",
+		"ConfigMap":               "cm",
+		"Secret":                  "secret",
+		"RoleBinding":             "rolebinding",
+		"ServiceAccount":          "serviceaccount",
+		"CronJob":                 "cronjob",
+		"Job":                     "job",
+		"LimitRange":              "limitrange",
+		"ResourceQuota":           "quota",
+		"HorizontalPodAutoscaler": "hpa",
+		"Endpoints":               "endpoints",
 	}
 )
 
 // Change is a description of a drift between current and desired state, and
 // the required patches to bring them back in sync.
 type Change struct {
-	Action       string
-	Kind         string
-	Name         string
-	CurrentState string
-	DesiredState string
+	Action          string
+	Kind            string
+	Name            string
+	CurrentState    string
+	DesiredState    string
+	Reasons         []string
+	ResourceVersion string
+	// Patches describe an update as JSON patch (RFC 6902) operations.
+	Patches []*JSONPatch
+	// DiffOnly is true if the change is shown but must never be applied.
+	DiffOnly bool
+	// ImmutablePaths lists immutable fields which drifted, but were left
+	// as-is because of --on-immutable=warn.
+	ImmutablePaths []string
+	// Pruned is true if the deleted resource is not defined in any template,
+	// and was only found via --prune.
+	Pruned bool
+}
+
+// JSONPatch is a single operation of a JSON patch (RFC 6902).
+type JSONPatch struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
 }
 
 // NewChange creates a new change for given template/platform item.
 func NewChange(templateItem *ResourceItem, platformItem *ResourceItem) *Change {
 	c := &Change{
-		Kind:         templateItem.Kind,
-		Name:         templateItem.Name,
-		CurrentState: platformItem.YamlConfig(),
-		DesiredState: templateItem.YamlConfig(),
+		Kind:            templateItem.Kind,
+		Name:            templateItem.Name,
+		CurrentState:    platformItem.YamlConfig(),
+		DesiredState:    templateItem.YamlConfig(),
+		ResourceVersion: platformItem.ResourceVersion,
 	}
 
 	if platformItem.YamlConfig() != templateItem.YamlConfig() {
@@ -63,28 +97,399 @@ func (c *Change) Diff(revealSecrets bool) string {
 	if c.isSecret() && !revealSecrets {
 		return "Secret drift is hidden. Use --reveal-secrets to see details.\n"
 	}
+	currentState, desiredState := c.CurrentState, c.DesiredState
+	note := ""
+	if c.isSecret() {
+		var currentDecoded, desiredDecoded bool
+		currentState, currentDecoded = decodeSecretData(currentState)
+		desiredState, desiredDecoded = decodeSecretData(desiredState)
+		if currentDecoded || desiredDecoded {
+			note = "Secret data is shown base64-decoded.\n"
+		}
+	}
 	diff := difflib.UnifiedDiff{
-		A:        difflib.SplitLines(c.CurrentState),
-		B:        difflib.SplitLines(c.DesiredState),
+		A:        difflib.SplitLines(currentState),
+		B:        difflib.SplitLines(desiredState),
 		FromFile: "Current State (OpenShift cluster)",
 		ToFile:   "Desired State (Processed template)",
 		Context:  3,
 	}
 	text, _ := difflib.GetUnifiedDiffString(diff)
+	return cli.Redact(note + text)
+}
+
+// decodeSecretData returns state with all values of the data field
+// base64-decoded, so that drift of Secret resources is legible. Values which
+// do not decode to text are kept as-is, as is stringData. The returned bool
+// is true if any value was decoded.
+func decodeSecretData(state string) (string, bool) {
+	if len(state) == 0 {
+		return state, false
+	}
+	var config map[string]interface{}
+	err := yaml.Unmarshal([]byte(state), &config)
+	if err != nil {
+		cli.DebugMsg("Could not decode Secret data:", err.Error())
+		return state, false
+	}
+	data, ok := config["data"].(map[string]interface{})
+	if !ok {
+		return state, false
+	}
+	decoded := false
+	for key, val := range data {
+		encoded, ok := val.(string)
+		if !ok {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || !utf8.Valid(b) {
+			continue
+		}
+		data[key] = string(b)
+		decoded = true
+	}
+	if !decoded {
+		return state, false
+	}
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		cli.DebugMsg("Could not decode Secret data:", err.Error())
+		return state, false
+	}
+	return string(b), true
+}
+
+// JSONPatches returns the operations of an update as JSON patch. Creations
+// and deletions affect the whole resource and have no patch.
+func (c *Change) JSONPatches(revealSecrets bool) string {
+	if c.Action != "Update" || len(c.Patches) == 0 {
+		return ""
+	}
+	if c.isSecret() && !revealSecrets {
+		return "Secret JSON patch is hidden. Use --reveal-secrets to see details.\n"
+	}
+	b, err := json.MarshalIndent(c.Patches, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("Could not render JSON patch: %s\n", err)
+	}
+	return cli.Redact("--- JSON Patch\n" + string(b) + "\n")
+}
+
+// AtPaths returns a copy of the change which only covers given paths (JSON
+// pointers), so that its diff focuses on them. The states of the copy map each
+// path to the value found there. The returned bool is false if the change
+// does not touch any of the paths.
+func (c *Change) AtPaths(paths []string) (*Change, bool) {
+	focused := *c
+	focused.CurrentState = statesAtPaths(c.CurrentState, paths)
+	focused.DesiredState = statesAtPaths(c.DesiredState, paths)
+	focused.Patches = []*JSONPatch{}
+	for _, p := range c.Patches {
+		for _, path := range paths {
+			if p.Path == path || strings.HasPrefix(p.Path, strings.TrimSuffix(path, "/")+"/") {
+				focused.Patches = append(focused.Patches, p)
+				break
+			}
+		}
+	}
+	return &focused, focused.CurrentState != focused.DesiredState
+}
+
+// statesAtPaths returns a YAML document mapping each path to its value in
+// given state. Paths which do not exist are omitted.
+func statesAtPaths(state string, paths []string) string {
+	if len(state) == 0 {
+		return ""
+	}
+	var config map[string]interface{}
+	err := yaml.Unmarshal([]byte(state), &config)
+	if err != nil {
+		cli.DebugMsg("Could not parse state:", err.Error())
+		return ""
+	}
+	values := map[string]interface{}{}
+	for _, path := range paths {
+		pointer, err := gojsonpointer.NewJsonPointer(path)
+		if err != nil {
+			continue
+		}
+		val, _, err := pointer.Get(config)
+		if err != nil {
+			continue
+		}
+		values[path] = val
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		cli.DebugMsg("Could not marshal state:", err.Error())
+		return ""
+	}
+	return string(b)
+}
+
+// MergePatch returns the JSON merge patch (RFC 7386) which turns the current
+// state of an update into the desired state. Lists are replaced as a whole,
+// and removed fields are set to null.
+func (c *Change) MergePatch() (map[string]interface{}, error) {
+	if c.Action != "Update" {
+		return nil, fmt.Errorf("%s is not an update", c.ItemName())
+	}
+	var current, desired map[string]interface{}
+	err := yaml.Unmarshal([]byte(c.CurrentState), &current)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse current state of %s: %s", c.ItemName(), err)
+	}
+	err = yaml.Unmarshal([]byte(c.DesiredState), &desired)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse desired state of %s: %s", c.ItemName(), err)
+	}
+	return createMergePatch(current, desired), nil
+}
+
+// createMergePatch returns the fields which differ between current and
+// desired, recursing into nested objects. It is the inverse of mergePatch.
+func createMergePatch(current, desired map[string]interface{}) map[string]interface{} {
+	patch := map[string]interface{}{}
+	for key, desiredVal := range desired {
+		currentVal, ok := current[key]
+		if !ok {
+			patch[key] = desiredVal
+			continue
+		}
+		currentMap, currentIsMap := currentVal.(map[string]interface{})
+		desiredMap, desiredIsMap := desiredVal.(map[string]interface{})
+		if currentIsMap && desiredIsMap {
+			if nested := createMergePatch(currentMap, desiredMap); len(nested) > 0 {
+				patch[key] = nested
+			}
+			continue
+		}
+		if !reflect.DeepEqual(currentVal, desiredVal) {
+			patch[key] = desiredVal
+		}
+	}
+	for key := range current {
+		if _, ok := desired[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
+
+// Explanation returns a text describing why the change was detected.
+func (c *Change) Explanation() string {
+	if len(c.Reasons) == 0 {
+		return "Reason: configuration differs after normalisation\n"
+	}
+	text := "Reasons:\n"
+	for _, r := range c.Reasons {
+		text = text + "  * " + r + "\n"
+	}
 	return text
 }
 
+// LabelConflicts describes labels with one of the given keys which exist in
+// the current state but would be changed to a different value by the desired
+// state. This is used to detect labels injected via --labels which overwrite
+// existing values, e.g. because two templates fight over a label.
+func (c *Change) LabelConflicts(keys []string) []string {
+	conflicts := []string{}
+	if c.Action != "Update" || len(keys) == 0 {
+		return conflicts
+	}
+	currentLabels := labelsOfState(c.CurrentState)
+	desiredLabels := labelsOfState(c.DesiredState)
+	for _, key := range keys {
+		currentVal, currentOk := currentLabels[key]
+		desiredVal, desiredOk := desiredLabels[key]
+		if currentOk && desiredOk && currentVal != desiredVal {
+			conflicts = append(conflicts, fmt.Sprintf(
+				"label %s would change from '%s' to '%s'", key, currentVal, desiredVal,
+			))
+		}
+	}
+	return conflicts
+}
+
+// OnlyTemplateHashChanged returns true if the template hash annotation is
+// the only difference, meaning the template source changed but the rendered
+// output did not.
+func (c *Change) OnlyTemplateHashChanged() bool {
+	if c.Action != "Update" || len(c.Reasons) == 0 {
+		return false
+	}
+	path := "/metadata/annotations/" + utils.JSONPointerPath(TemplateHashAnnotation)
+	for _, r := range c.Reasons {
+		if !strings.HasPrefix(r, path+" ") {
+			return false
+		}
+	}
+	return true
+}
+
+// labelsOfState extracts the labels from given YAML configuration.
+func labelsOfState(state string) map[string]string {
+	var config struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	err := yaml.Unmarshal([]byte(state), &config)
+	if err != nil {
+		cli.DebugMsg("Could not extract labels:", err.Error())
+	}
+	return config.Metadata.Labels
+}
+
 func (c *Change) isSecret() bool {
 	return kindToShortMapping[c.Kind] == "secret"
 }
 
-func recreateChanges(templateItem, platformItem *ResourceItem) []*Change {
+// RecreateAnnotation can be set to "true" on a resource in the template to
+// recreate (delete and create) the resource instead of updating it whenever
+// there is drift, e.g. to reset a Job.
+const RecreateAnnotation = "tailor.opendevstack.org/recreate"
+
+// DiffOnlyAnnotation can be set to "true" on a resource (in the template or
+// in the cluster) to report its drift without ever applying it, e.g. while
+// the resource is still managed manually.
+const DiffOnlyAnnotation = "tailor.opendevstack.org/diff-only"
+
+// ApplyVerbAnnotation can be set on a resource in the template to choose the
+// oc verb used to push it to the cluster, e.g. "create" for resources which
+// "oc apply" cannot handle. It takes precedence over --apply-verb.
+const ApplyVerbAnnotation = "tailor.opendevstack.org/apply-verb"
+
+// WaitTimeoutAnnotation can be set on a resource to override --wait-for-delete
+// for that resource, e.g. "5m" for a resource with long-running finalizers.
+// A value of "0s" disables waiting for the resource.
+const WaitTimeoutAnnotation = "tailor.opendevstack.org/wait-timeout"
+
+const (
+	// ApplyVerbApply pushes resources via "oc apply" (default).
+	ApplyVerbApply = "apply"
+	// ApplyVerbCreate pushes resources via "oc create".
+	ApplyVerbCreate = "create"
+	// ApplyVerbReplace pushes resources via "oc replace".
+	ApplyVerbReplace = "replace"
+)
+
+// ParseApplyVerbs turns verbs of the form "kind:verb" into a map of kind to
+// verb.
+func ParseApplyVerbs(verbs []string) (map[string]string, error) {
+	kindVerbs := map[string]string{}
+	for _, verb := range verbs {
+		parts := strings.Split(verb, ":")
+		if len(parts) != 2 {
+			return kindVerbs, fmt.Errorf(
+				"%s is not a valid apply verb argument",
+				verb,
+			)
+		}
+		kind, ok := KindMapping[strings.ToLower(parts[0])]
+		if !ok {
+			return kindVerbs, fmt.Errorf(
+				"Unknown resource kind in apply verb argument: %s",
+				parts[0],
+			)
+		}
+		if !validApplyVerb(parts[1]) {
+			return kindVerbs, fmt.Errorf(
+				"Unknown apply verb '%s' for %s, must be one of %s, %s or %s",
+				parts[1],
+				parts[0],
+				ApplyVerbApply,
+				ApplyVerbCreate,
+				ApplyVerbReplace,
+			)
+		}
+		kindVerbs[kind] = parts[1]
+	}
+	return kindVerbs, nil
+}
+
+// ApplyVerb returns the oc verb used to push the desired state of the change
+// to the cluster. The annotation of the resource wins over the verb
+// configured for its kind.
+func (c *Change) ApplyVerb(kindVerbs map[string]string) (string, error) {
+	if verb, ok := annotationsOfState(c.DesiredState)[ApplyVerbAnnotation]; ok {
+		if !validApplyVerb(verb) {
+			return "", fmt.Errorf(
+				"Unknown apply verb '%s' in annotation %s of %s, must be one of %s, %s or %s",
+				verb,
+				ApplyVerbAnnotation,
+				c.ItemName(),
+				ApplyVerbApply,
+				ApplyVerbCreate,
+				ApplyVerbReplace,
+			)
+		}
+		return verb, nil
+	}
+	if verb, ok := kindVerbs[c.Kind]; ok {
+		return verb, nil
+	}
+	return ApplyVerbApply, nil
+}
+
+// WaitTimeout returns how long to wait for the resource of the change, which
+// is defaultTimeout unless overridden by WaitTimeoutAnnotation. The annotation
+// in the desired state wins over the one in the current state.
+func (c *Change) WaitTimeout(defaultTimeout time.Duration) (time.Duration, error) {
+	for _, state := range []string{c.DesiredState, c.CurrentState} {
+		val, ok := annotationsOfState(state)[WaitTimeoutAnnotation]
+		if !ok {
+			continue
+		}
+		timeout, err := time.ParseDuration(val)
+		if err != nil || timeout < 0 {
+			return 0, fmt.Errorf(
+				"Invalid duration '%s' in annotation %s of %s",
+				val,
+				WaitTimeoutAnnotation,
+				c.ItemName(),
+			)
+		}
+		return timeout, nil
+	}
+	return defaultTimeout, nil
+}
+
+func validApplyVerb(verb string) bool {
+	switch verb {
+	case ApplyVerbApply, ApplyVerbCreate, ApplyVerbReplace:
+		return true
+	}
+	return false
+}
+
+// annotationsOfState extracts the annotations from given YAML configuration.
+func annotationsOfState(state string) map[string]string {
+	var config struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}
+	err := yaml.Unmarshal([]byte(state), &config)
+	if err != nil {
+		cli.DebugMsg("Could not extract annotations:", err.Error())
+	}
+	return config.Metadata.Annotations
+}
+
+func recreateChanges(templateItem, platformItem *ResourceItem, reason string) []*Change {
 	deleteChange := &Change{
-		Action:       "Delete",
-		Kind:         templateItem.Kind,
-		Name:         templateItem.Name,
-		CurrentState: platformItem.YamlConfig(),
-		DesiredState: "",
+		Action:          "Delete",
+		Kind:            templateItem.Kind,
+		Name:            templateItem.Name,
+		CurrentState:    platformItem.YamlConfig(),
+		DesiredState:    "",
+		Reasons:         []string{reason},
+		ResourceVersion: platformItem.ResourceVersion,
 	}
 	createChange := &Change{
 		Action:       "Create",
@@ -92,6 +497,7 @@ func recreateChanges(templateItem, platformItem *ResourceItem) []*Change {
 		Name:         templateItem.Name,
 		CurrentState: "",
 		DesiredState: templateItem.YamlConfig(),
+		Reasons:      []string{reason},
 	}
 	return []*Change{deleteChange, createChange}
 }
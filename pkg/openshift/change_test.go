@@ -2,7 +2,13 @@ package openshift
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestDiff(t *testing.T) {
@@ -152,7 +158,7 @@ func TestDiff(t *testing.T) {
 				getConfigMapForDiff(tt.desiredAnnotations, tt.desiredData),
 				"template",
 			)
-			changes, err := calculateChanges(desiredItem, currentItem, []string{}, true)
+			changes, err := calculateChanges(desiredItem, currentItem, []string{}, OnImmutableRecreate, false)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -169,6 +175,46 @@ func TestDiff(t *testing.T) {
 	}
 }
 
+func TestDiffDecodesSecretData(t *testing.T) {
+	secret := `apiVersion: v1
+data:
+  binary: /w==
+  password: %s
+kind: Secret
+metadata:
+  name: foo
+stringData:
+  user: admin
+type: Opaque
+`
+	change := &Change{
+		Action:       "Update",
+		Kind:         "Secret",
+		Name:         "foo",
+		CurrentState: fmt.Sprintf(secret, "b2xk"),
+		DesiredState: fmt.Sprintf(secret, "bmV3"),
+	}
+	want := `Secret data is shown base64-decoded.
+--- Current State (OpenShift cluster)
++++ Desired State (Processed template)
+@@ -1,7 +1,7 @@
+ apiVersion: v1
+ data:
+   binary: /w==
+-  password: old
++  password: new
+ kind: Secret
+ metadata:
+   name: foo
+`
+	if got := change.Diff(true); got != want {
+		t.Fatalf("Diff()\n===== expected =====\n%s\n===== actual =====\n%s", want, got)
+	}
+	if got := change.Diff(false); strings.Contains(got, "old") {
+		t.Fatalf("Secret drift should be hidden, got:\n%s", got)
+	}
+}
+
 func getConfigMapForDiff(annotations, data []byte) []byte {
 	config := []byte(
 		`apiVersion: v1
@@ -182,3 +228,214 @@ data: DATA`)
 	config = bytes.Replace(config, []byte("ANNOTATIONS"), annotations, -1)
 	return bytes.Replace(config, []byte("DATA"), data, -1)
 }
+
+func TestLabelConflicts(t *testing.T) {
+	current := "kind: ConfigMap\nmetadata:\n  labels:\n    app: foo\n    env: dev\n  name: foo\n"
+	tests := map[string]struct {
+		desired string
+		keys    []string
+		want    []string
+	}{
+		"conflicting injected label": {
+			desired: "kind: ConfigMap\nmetadata:\n  labels:\n    app: bar\n    env: dev\n  name: foo\n",
+			keys:    []string{"app", "env"},
+			want:    []string{"label app would change from 'foo' to 'bar'"},
+		},
+		"conflicting label which is not injected": {
+			desired: "kind: ConfigMap\nmetadata:\n  labels:\n    app: bar\n    env: dev\n  name: foo\n",
+			keys:    []string{"env"},
+			want:    []string{},
+		},
+		"label added": {
+			desired: "kind: ConfigMap\nmetadata:\n  labels:\n    app: foo\n    env: dev\n    team: x\n  name: foo\n",
+			keys:    []string{"team"},
+			want:    []string{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Change{Action: "Update", CurrentState: current, DesiredState: tc.desired}
+			got := c.LabelConflicts(tc.keys)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Label conflicts mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseApplyVerbs(t *testing.T) {
+	tests := map[string]struct {
+		verbs   []string
+		want    map[string]string
+		wantErr string
+	}{
+		"valid": {
+			verbs: []string{"job:create", "cm:replace"},
+			want:  map[string]string{"Job": "create", "ConfigMap": "replace"},
+		},
+		"unknown verb": {
+			verbs:   []string{"job:patch"},
+			wantErr: "Unknown apply verb 'patch' for job, must be one of apply, create or replace",
+		},
+		"unknown kind": {
+			verbs:   []string{"foo:create"},
+			wantErr: "Unknown resource kind in apply verb argument: foo",
+		},
+		"invalid": {
+			verbs:   []string{"create"},
+			wantErr: "create is not a valid apply verb argument",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseApplyVerbs(tc.verbs)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Apply verbs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChangeWaitTimeout(t *testing.T) {
+	annotated := func(timeout string) string {
+		return "kind: ConfigMap\nmetadata:\n  annotations:\n    tailor.opendevstack.org/wait-timeout: " + timeout + "\n  name: foo\n"
+	}
+	tests := map[string]struct {
+		currentState string
+		desiredState string
+		want         time.Duration
+		wantErr      string
+	}{
+		"not annotated": {
+			currentState: "kind: ConfigMap\nmetadata:\n  name: foo\n",
+			want:         time.Minute,
+		},
+		"annotated in current state": {
+			currentState: annotated("5m"),
+			want:         5 * time.Minute,
+		},
+		"annotated in desired state": {
+			currentState: annotated("5m"),
+			desiredState: annotated("10s"),
+			want:         10 * time.Second,
+		},
+		"waiting disabled": {
+			currentState: annotated("0s"),
+			want:         0,
+		},
+		"invalid": {
+			currentState: annotated("soon"),
+			wantErr:      "Invalid duration 'soon' in annotation tailor.opendevstack.org/wait-timeout of cm/foo",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Change{
+				Action:       "Delete",
+				Kind:         "ConfigMap",
+				Name:         "foo",
+				CurrentState: tc.currentState,
+				DesiredState: tc.desiredState,
+			}
+			got, err := c.WaitTimeout(time.Minute)
+			if len(tc.wantErr) > 0 {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("Want error '%s', got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("Want timeout %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestChangeAtPaths(t *testing.T) {
+	current := "kind: Deployment\nmetadata:\n  labels:\n    app: foo\n  name: foo\nspec:\n  replicas: 1\n  template:\n    spec:\n      containers:\n      - image: foo:1\n"
+	tests := map[string]struct {
+		desired     string
+		paths       []string
+		wantTouched bool
+		wantDesired string
+	}{
+		"drift at path": {
+			desired:     "kind: Deployment\nmetadata:\n  labels:\n    app: foo\n  name: foo\nspec:\n  replicas: 1\n  template:\n    spec:\n      containers:\n      - image: foo:2\n",
+			paths:       []string{"/spec/template/spec/containers"},
+			wantTouched: true,
+			wantDesired: "/spec/template/spec/containers:\n- image: foo:2\n",
+		},
+		"drift elsewhere": {
+			desired:     "kind: Deployment\nmetadata:\n  labels:\n    app: bar\n  name: foo\nspec:\n  replicas: 2\n  template:\n    spec:\n      containers:\n      - image: foo:1\n",
+			paths:       []string{"/spec/template/spec/containers"},
+			wantTouched: false,
+			wantDesired: "/spec/template/spec/containers:\n- image: foo:1\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Change{
+				Action:       "Update",
+				CurrentState: current,
+				DesiredState: tc.desired,
+				Patches: []*JSONPatch{
+					{Op: "replace", Path: "/spec/template/spec/containers/0/image", Value: "foo:2"},
+					{Op: "replace", Path: "/spec/replicas", Value: 2},
+				},
+			}
+			got, touched := c.AtPaths(tc.paths)
+			if touched != tc.wantTouched {
+				t.Fatalf("Want touched=%t, got %t", tc.wantTouched, touched)
+			}
+			if diff := cmp.Diff(tc.wantDesired, got.DesiredState); diff != "" {
+				t.Fatalf("Desired state mismatch (-want +got):\n%s", diff)
+			}
+			if len(got.Patches) != 1 || got.Patches[0].Path != "/spec/template/spec/containers/0/image" {
+				t.Fatalf("Want only patch at path, got %v", got.Patches)
+			}
+		})
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	current := "kind: Deployment\nmetadata:\n  annotations:\n    foo: bar\n  name: foo\nspec:\n  replicas: 1\n  template:\n    spec:\n      containers:\n      - image: foo:1\n"
+	desired := "kind: Deployment\nmetadata:\n  name: foo\nspec:\n  replicas: 1\n  template:\n    spec:\n      containers:\n      - image: foo:2\n"
+	c := &Change{Action: "Update", Kind: "Deployment", Name: "foo", CurrentState: current, DesiredState: desired}
+	got, err := c.MergePatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": nil},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{"image": "foo:2"}},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Merge patch mismatch (-want +got):\n%s", diff)
+	}
+
+	// Applying the patch to the current state yields the desired state.
+	var currentConfig, desiredConfig map[string]interface{}
+	_ = yaml.Unmarshal([]byte(current), &currentConfig)
+	_ = yaml.Unmarshal([]byte(desired), &desiredConfig)
+	if diff := cmp.Diff(desiredConfig, mergePatch(currentConfig, got)); diff != "" {
+		t.Fatalf("Patched state mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/utils"
 	"github.com/xeipuuv/gojsonpointer"
 )
@@ -12,33 +14,59 @@ import (
 var (
 	// Resources with no dependencies go first
 	kindOrder = map[string]string{
-		"Template":              "a",
-		"ServiceAccount":        "b",
-		"RoleBinding":           "c",
-		"ConfigMap":             "d",
-		"Secret":                "e",
-		"LimitRange":            "f",
-		"ResourceQuota":         "g",
-		"PersistentVolumeClaim": "h",
-		"CronJob":               "i",
-		"Job":                   "j",
-		"ImageStream":           "k",
-		"BuildConfig":           "l",
-		"DeploymentConfig":      "m",
-		"Deployment":            "n",
-		"Service":               "o",
-		"Route":                 "p",
+		"Template":                "a",
+		"ServiceAccount":          "b",
+		"RoleBinding":             "c",
+		"ConfigMap":               "d",
+		"Secret":                  "e",
+		"LimitRange":              "f",
+		"ResourceQuota":           "g",
+		"PersistentVolumeClaim":   "h",
+		"CronJob":                 "i",
+		"Job":                     "j",
+		"ImageStream":             "k",
+		"BuildConfig":             "l",
+		"DeploymentConfig":        "m",
+		"Deployment":              "n",
+		"Service":                 "o",
+		"Route":                   "p",
+		"HorizontalPodAutoscaler": "q",
 	}
 )
 
+// Modes which define how drift of immutable fields is handled.
+const (
+	// OnImmutableError fails on drift of immutable fields (default).
+	OnImmutableError = "error"
+	// OnImmutableRecreate recreates resources with drift of immutable fields.
+	OnImmutableRecreate = "recreate"
+	// OnImmutableWarn reports drift of immutable fields, but leaves the
+	// resource as-is.
+	OnImmutableWarn = "warn"
+)
+
+// Policies which define how resources of a kind are compared.
+const (
+	// ComparePolicyIgnore excludes resources of a kind from the comparison.
+	ComparePolicyIgnore = "ignore"
+	// ComparePolicySpecOnly compares only the spec of resources of a kind.
+	ComparePolicySpecOnly = "spec-only"
+	// ComparePolicyStrict compares resources of a kind fully (default).
+	ComparePolicyStrict = "strict"
+)
+
 type Changeset struct {
 	Create []*Change
 	Update []*Change
 	Delete []*Change
 	Noop   []*Change
+	// Unmanaged lists the resources (e.g. "cm/foo") in the current state
+	// which are not defined in the desired state. It is independent of the
+	// changes, which might not delete them (e.g. in upsert-only mode).
+	Unmanaged []string
 }
 
-func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly bool, allowRecreate bool, preservePaths []string) (*Changeset, error) {
+func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly bool, onImmutable string, ignoreWhitespace bool, preservePaths []string, identities []string, comparePolicies []string, pruneAge time.Duration) (*Changeset, error) {
 	changeset := &Changeset{
 		Create: []*Change{},
 		Delete: []*Change{},
@@ -46,25 +74,59 @@ func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly
 		Noop:   []*Change{},
 	}
 
+	identityLabels, err := parseIdentities(identities)
+	if err != nil {
+		return changeset, err
+	}
+	kindPolicies, err := parseComparePolicies(comparePolicies)
+	if err != nil {
+		return changeset, err
+	}
+
 	// items to delete
-	if !upsertOnly {
-		for _, item := range platformBasedList.Items {
-			if _, err := templateBasedList.getItem(item.Kind, item.Name); err != nil {
-				change := &Change{
-					Action:       "Delete",
-					Kind:         item.Kind,
-					Name:         item.Name,
-					CurrentState: item.YamlConfig(),
-					DesiredState: "",
-				}
-				changeset.Add(change)
+	for _, item := range platformBasedList.Items {
+		if kindPolicies[item.Kind] == ComparePolicyIgnore {
+			continue
+		}
+		if _, err := templateBasedList.matchingItem(item, identityLabels); err != nil {
+			// Pruned items carry the prune label, so they are managed even
+			// though they are not defined in any template (any longer).
+			if !item.Pruned {
+				changeset.Unmanaged = append(changeset.Unmanaged, item.ShortName())
+			}
+			if upsertOnly {
+				continue
+			}
+			if pruneAge > 0 && !olderThan(item, pruneAge) {
+				cli.VerboseMsg(item.ShortName(), "is not older than", pruneAge.String(), "- not deleting it")
+				continue
+			}
+			reason := "missing in desired state"
+			if item.Pruned {
+				reason = "not defined in any template"
+			}
+			change := &Change{
+				Action:          "Delete",
+				Kind:            item.Kind,
+				Name:            item.Name,
+				CurrentState:    item.YamlConfig(),
+				DesiredState:    "",
+				Reasons:         []string{reason},
+				ResourceVersion: item.ResourceVersion,
+				DiffOnly:        item.diffOnly(),
+				Pruned:          item.Pruned,
 			}
+			changeset.Add(change)
 		}
 	}
 
 	// items to create
 	for _, item := range templateBasedList.Items {
-		if _, err := platformBasedList.getItem(item.Kind, item.Name); err != nil {
+		if kindPolicies[item.Kind] == ComparePolicyIgnore {
+			cli.VerboseMsg("Ignoring", item.ShortName(), "as its kind is ignored")
+			continue
+		}
+		if _, err := platformBasedList.matchingItem(item, identityLabels); err != nil {
 			desiredState, err := item.DesiredConfig()
 			if err != nil {
 				return changeset, err
@@ -75,6 +137,8 @@ func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly
 				Name:         item.Name,
 				CurrentState: "",
 				DesiredState: desiredState,
+				Reasons:      []string{"missing in current state"},
+				DiffOnly:     item.diffOnly(),
 			}
 			changeset.Add(change)
 		}
@@ -82,11 +146,20 @@ func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly
 
 	// items to update
 	for _, templateItem := range templateBasedList.Items {
-		platformItem, err := platformBasedList.getItem(
-			templateItem.Kind,
-			templateItem.Name,
-		)
+		if kindPolicies[templateItem.Kind] == ComparePolicyIgnore {
+			continue
+		}
+		platformItem, err := platformBasedList.matchingItem(templateItem, identityLabels)
 		if err == nil {
+			// When matched by identity, the names might differ (e.g. because
+			// the resource has a generated name). The remote name wins as
+			// the change needs to be applied to the existing resource.
+			if templateItem.Name != platformItem.Name {
+				err := templateItem.rename(platformItem.Name)
+				if err != nil {
+					return changeset, err
+				}
+			}
 			actualReservePaths := []string{}
 			for _, path := range preservePaths {
 				pathParts := strings.Split(path, ":")
@@ -108,14 +181,24 @@ func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly
 						templateItem.Name == strings.ToLower(pathParts[1])) {
 					// We only care about the last part (the JSON path) as we
 					// are already "inside" the item
-					actualReservePaths = append(actualReservePaths, pathParts[len(pathParts)-1])
+					actualReservePaths = append(
+						actualReservePaths,
+						expandPreservePath(pathParts[len(pathParts)-1], templateItem, platformItem)...,
+					)
 				}
 			}
 
-			changes, err := calculateChanges(templateItem, platformItem, actualReservePaths, allowRecreate)
+			if kindPolicies[templateItem.Kind] == ComparePolicySpecOnly {
+				actualReservePaths = append(actualReservePaths, nonSpecPaths(templateItem, platformItem)...)
+			}
+
+			changes, err := calculateChanges(templateItem, platformItem, actualReservePaths, onImmutable, ignoreWhitespace)
 			if err != nil {
 				return changeset, err
 			}
+			for _, c := range changes {
+				c.DiffOnly = templateItem.diffOnly() || platformItem.diffOnly()
+			}
 			changeset.Add(changes...)
 		}
 	}
@@ -123,7 +206,130 @@ func NewChangeset(platformBasedList, templateBasedList *ResourceList, upsertOnly
 	return changeset, nil
 }
 
-func calculateChanges(templateItem *ResourceItem, platformItem *ResourceItem, preservePaths []string, allowRecreate bool) ([]*Change, error) {
+// expandPreservePath resolves a path containing "*" segments (e.g.
+// /spec/containers/*/image) into all matching paths of the given items.
+// A "*" matches exactly one segment, e.g. an array index or a map key.
+// Paths without "*" are returned as-is.
+func expandPreservePath(path string, items ...*ResourceItem) []string {
+	if !strings.Contains(path, "*") {
+		return []string{path}
+	}
+	patternSegments := strings.Split(path, "/")
+	expanded := []string{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		for _, p := range item.Paths {
+			if seen[p] {
+				continue
+			}
+			segments := strings.Split(p, "/")
+			if len(segments) != len(patternSegments) {
+				continue
+			}
+			matched := true
+			for i, s := range patternSegments {
+				if s != "*" && s != segments[i] {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				seen[p] = true
+				expanded = append(expanded, p)
+			}
+		}
+	}
+	return expanded
+}
+
+// olderThan returns true if the item has been created longer than age ago.
+// Items without a known creation timestamp are never considered old.
+func olderThan(item *ResourceItem, age time.Duration) bool {
+	if item.CreationTimestamp.IsZero() {
+		return false
+	}
+	return time.Since(item.CreationTimestamp) > age
+}
+
+// parseIdentities turns identities of the form "kind:label" into a map of
+// kind to label.
+func parseIdentities(identities []string) (map[string]string, error) {
+	identityLabels := map[string]string{}
+	for _, identity := range identities {
+		parts := strings.Split(identity, ":")
+		if len(parts) != 2 || len(parts[1]) == 0 {
+			return identityLabels, fmt.Errorf(
+				"%s is not a valid identity argument",
+				identity,
+			)
+		}
+		kind, ok := KindMapping[strings.ToLower(parts[0])]
+		if !ok {
+			return identityLabels, fmt.Errorf(
+				"Unknown resource kind in identity argument: %s",
+				parts[0],
+			)
+		}
+		identityLabels[kind] = parts[1]
+	}
+	return identityLabels, nil
+}
+
+// parseComparePolicies turns policies of the form "kind:policy" into a map of
+// kind to policy.
+func parseComparePolicies(policies []string) (map[string]string, error) {
+	kindPolicies := map[string]string{}
+	for _, policy := range policies {
+		parts := strings.Split(policy, ":")
+		if len(parts) != 2 {
+			return kindPolicies, fmt.Errorf(
+				"%s is not a valid compare policy argument",
+				policy,
+			)
+		}
+		kind, ok := KindMapping[strings.ToLower(parts[0])]
+		if !ok {
+			return kindPolicies, fmt.Errorf(
+				"Unknown resource kind in compare policy argument: %s",
+				parts[0],
+			)
+		}
+		switch parts[1] {
+		case ComparePolicyIgnore, ComparePolicySpecOnly, ComparePolicyStrict:
+			kindPolicies[kind] = parts[1]
+		default:
+			return kindPolicies, fmt.Errorf(
+				"Unknown compare policy '%s' for %s, must be one of %s, %s or %s",
+				parts[1],
+				parts[0],
+				ComparePolicyIgnore,
+				ComparePolicySpecOnly,
+				ComparePolicyStrict,
+			)
+		}
+	}
+	return kindPolicies, nil
+}
+
+// nonSpecPaths returns all top-level paths of the items except /spec and
+// the paths identifying the resource type, so that only the spec is compared.
+func nonSpecPaths(items ...*ResourceItem) []string {
+	paths := []string{}
+	for _, item := range items {
+		for _, path := range item.Paths {
+			if strings.Count(path, "/") != 1 || utils.Includes(paths, path) {
+				continue
+			}
+			if path == "/spec" || path == "/kind" || path == "/apiVersion" {
+				continue
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func calculateChanges(templateItem *ResourceItem, platformItem *ResourceItem, preservePaths []string, onImmutable string, ignoreWhitespace bool) ([]*Change, error) {
 	err := templateItem.prepareForComparisonWithPlatformItem(platformItem, preservePaths)
 	if err != nil {
 		return nil, err
@@ -135,6 +341,8 @@ func calculateChanges(templateItem *ResourceItem, platformItem *ResourceItem, pr
 
 	comparedPaths := map[string]bool{}
 	addedPaths := []string{}
+	changedPaths := []string{}
+	immutablePaths := []string{}
 
 	for _, path := range templateItem.Paths {
 
@@ -156,12 +364,14 @@ func calculateChanges(templateItem *ResourceItem, platformItem *ResourceItem, pr
 		if err != nil {
 			// Pointer does not exist in platformItem
 			if templateItem.isImmutableField(path) {
-				if allowRecreate {
-					return recreateChanges(templateItem, platformItem), nil
-				} else {
+				switch onImmutable {
+				case OnImmutableRecreate:
+					return recreateChanges(templateItem, platformItem, immutableFieldReason(path)), nil
+				case OnImmutableWarn:
+					immutablePaths = append(immutablePaths, path)
+				default:
 					return nil, recreateProtectionError(path, platformItem.ShortName())
 				}
-
 			}
 			comparedPaths[path] = true
 
@@ -192,15 +402,27 @@ func calculateChanges(templateItem *ResourceItem, platformItem *ResourceItem, pr
 			default:
 				if templateItemVal == platformItemVal {
 					comparedPaths[path] = true
+				} else if ignoreWhitespace && equalIgnoringWhitespace(templateItemVal, platformItemVal) {
+					// Keep the current value so that the whitespace
+					// difference neither shows up in the diff nor is applied.
+					_, err := pathPointer.Set(templateItem.Config, platformItemVal)
+					if err != nil {
+						return nil, err
+					}
+					comparedPaths[path] = true
 				} else {
 					if templateItem.isImmutableField(path) {
-						if allowRecreate {
-							return recreateChanges(templateItem, platformItem), nil
-						} else {
+						switch onImmutable {
+						case OnImmutableRecreate:
+							return recreateChanges(templateItem, platformItem, immutableFieldReason(path)), nil
+						case OnImmutableWarn:
+							immutablePaths = append(immutablePaths, path)
+						default:
 							return nil, recreateProtectionError(path, platformItem.ShortName())
 						}
 					}
 					comparedPaths[path] = true
+					changedPaths = append(changedPaths, path)
 				}
 			}
 		}
@@ -275,45 +497,280 @@ func calculateChanges(templateItem *ResourceItem, platformItem *ResourceItem, pr
 	}
 
 	c := NewChange(templateItem, platformItem)
+	if c.Action == "Update" && templateItem.recreateRequested() {
+		return recreateChanges(
+			templateItem,
+			platformItem,
+			fmt.Sprintf("annotation %s requires recreation", RecreateAnnotation),
+		), nil
+	}
+	if c.Action == "Update" {
+		sort.Strings(changedPaths)
+		sort.Strings(addedPaths)
+		sort.Strings(deletedPaths)
+		for _, path := range changedPaths {
+			c.Reasons = append(c.Reasons, fmt.Sprintf("%s differs", path))
+		}
+		for _, path := range addedPaths {
+			c.Reasons = append(c.Reasons, fmt.Sprintf("%s is missing in current state", path))
+		}
+		for _, path := range deletedPaths {
+			c.Reasons = append(c.Reasons, fmt.Sprintf("%s is missing in desired state", path))
+		}
+		for _, path := range changedPaths {
+			c.Patches = append(c.Patches, newJSONPatch("replace", path, templateItem))
+		}
+		for _, path := range addedPaths {
+			c.Patches = append(c.Patches, newJSONPatch("add", path, templateItem))
+		}
+		for _, path := range deletedPaths {
+			c.Patches = append(c.Patches, &JSONPatch{Op: "remove", Path: path})
+		}
+		// Applying the change would fail, and recreating the resource is not
+		// permitted, so the resource is left as-is.
+		if len(immutablePaths) > 0 {
+			sort.Strings(immutablePaths)
+			c.ImmutablePaths = immutablePaths
+			c.DiffOnly = true
+		}
+	}
 
 	return []*Change{c}, nil
 }
 
+// equalIgnoringWhitespace is true when a and b are strings which only differ
+// in trailing whitespace (of any line) or line endings.
+func equalIgnoringWhitespace(a interface{}, b interface{}) bool {
+	aStr, ok := a.(string)
+	if !ok {
+		return false
+	}
+	bStr, ok := b.(string)
+	if !ok {
+		return false
+	}
+	return normalizeWhitespace(aStr) == normalizeWhitespace(bStr)
+}
+
+func normalizeWhitespace(s string) string {
+	lines := strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
 // Blank is true when there is no change across Create, Update, Delete.
 func (c *Changeset) Blank() bool {
 	return len(c.Create) == 0 && len(c.Update) == 0 && len(c.Delete) == 0
 }
 
+// PrunedDeletions returns the number of deletions found via --prune.
+func (c *Changeset) PrunedDeletions() int {
+	n := 0
+	for _, change := range c.Delete {
+		if change.Pruned {
+			n++
+		}
+	}
+	return n
+}
+
 // ExactlyOne is true when there is just a single change across Create, Update, Delete.
 func (c *Changeset) ExactlyOne() bool {
 	return len(c.Create)+len(c.Update)+len(c.Delete) == 1
 }
 
-// Add adds given changes to the changeset.
+// MarkDiffOnly marks all changes to given resources (of the form
+// "kind/name", e.g. "cm/foo") as diff-only, in addition to resources
+// annotated with DiffOnlyAnnotation.
+func (c *Changeset) MarkDiffOnly(resources []string) error {
+	diffOnly := map[string]bool{}
+	for _, resource := range resources {
+		parts := strings.Split(resource, "/")
+		if len(parts) != 2 || len(parts[1]) == 0 {
+			return fmt.Errorf("%s is not a valid diff-only argument", resource)
+		}
+		kind, ok := KindMapping[strings.ToLower(parts[0])]
+		if !ok {
+			return fmt.Errorf("Unknown resource kind in diff-only argument: %s", parts[0])
+		}
+		diffOnly[kind+"/"+parts[1]] = true
+	}
+	for _, changes := range [][]*Change{c.Create, c.Update, c.Delete} {
+		for _, change := range changes {
+			if diffOnly[change.Kind+"/"+change.Name] {
+				change.DiffOnly = true
+			}
+		}
+	}
+	return nil
+}
+
+// WithoutDiffOnly returns a changeset without diff-only changes, and the
+// diff-only changes which were left out.
+func (c *Changeset) WithoutDiffOnly() (*Changeset, []*Change) {
+	applicable := &Changeset{
+		Create: []*Change{},
+		Update: []*Change{},
+		Delete: []*Change{},
+		Noop:   c.Noop,
+	}
+	diffOnly := []*Change{}
+	for _, changes := range [][]*Change{c.Delete, c.Create, c.Update} {
+		for _, change := range changes {
+			if change.DiffOnly {
+				diffOnly = append(diffOnly, change)
+			} else {
+				applicable.Add(change)
+			}
+		}
+	}
+	return applicable, diffOnly
+}
+
+// AtPaths returns a changeset in which all changes only cover given paths
+// (see Change.AtPaths), dropping creations, updates and deletions which do
+// not touch any of the paths. The number of dropped changes is returned as
+// well.
+func (c *Changeset) AtPaths(paths []string) (*Changeset, int) {
+	focused := &Changeset{
+		Create: []*Change{},
+		Update: []*Change{},
+		Delete: []*Change{},
+		Noop:   c.Noop,
+	}
+	dropped := 0
+	for _, changes := range [][]*Change{c.Delete, c.Create, c.Update} {
+		for _, change := range changes {
+			if f, ok := change.AtPaths(paths); ok {
+				focused.Add(f)
+			} else {
+				dropped++
+			}
+		}
+	}
+	return focused, dropped
+}
+
+// WithoutSecrets returns a changeset without any changes of Secret resources,
+// and the number of Secret resources (of any action, including in sync ones)
+// which were removed.
+func (c *Changeset) WithoutSecrets() (*Changeset, int) {
+	masked := &Changeset{
+		Create: []*Change{},
+		Update: []*Change{},
+		Delete: []*Change{},
+		Noop:   []*Change{},
+	}
+	secrets := 0
+	for _, changes := range [][]*Change{c.Delete, c.Create, c.Update, c.Noop} {
+		for _, change := range changes {
+			if change.isSecret() {
+				secrets++
+			} else {
+				masked.Add(change)
+			}
+		}
+	}
+	return masked, secrets
+}
+
+// Restrict removes all changes whose action is not in actions (e.g.
+// "create"). Recreations (deletion and creation of the same resource) are
+// only kept if both actions are given, as applying only one half would fail
+// or leave the resource missing. It returns the number of removed changes.
+func (c *Changeset) Restrict(actions []string) int {
+	allowed := map[string]bool{}
+	for _, a := range actions {
+		allowed[strings.ToUpper(a[:1])+a[1:]] = true
+	}
+	deleted := map[string]bool{}
+	for _, change := range c.Delete {
+		deleted[change.ItemName()] = true
+	}
+	recreated := map[string]bool{}
+	for _, change := range c.Create {
+		if deleted[change.ItemName()] {
+			recreated[change.ItemName()] = true
+		}
+	}
+	keepRecreations := allowed["Create"] && allowed["Delete"]
+	removed := 0
+	restrict := func(changes []*Change) []*Change {
+		kept := []*Change{}
+		for _, change := range changes {
+			if !allowed[change.Action] || (recreated[change.ItemName()] && !keepRecreations) {
+				cli.VerboseMsg("Skipping", strings.ToLower(change.Action), "of", change.ItemName())
+				removed++
+				continue
+			}
+			kept = append(kept, change)
+		}
+		return kept
+	}
+	c.Create = restrict(c.Create)
+	c.Update = restrict(c.Update)
+	c.Delete = restrict(c.Delete)
+	return removed
+}
+
+// Add adds given changes to the changeset. Within each action, changes are
+// ordered by kind (in the order in which they need to be applied) and then
+// by name, so that output is stable across runs.
 func (c *Changeset) Add(changes ...*Change) {
 	for _, change := range changes {
 		switch change.Action {
 		case "Create":
 			c.Create = append(c.Create, change)
-			sort.Slice(c.Create, func(i, j int) bool {
-				return kindOrder[c.Create[i].Kind] < kindOrder[c.Create[j].Kind]
+			sort.SliceStable(c.Create, func(i, j int) bool {
+				return changeLess(c.Create[i], c.Create[j], false)
 			})
 		case "Update":
 			c.Update = append(c.Update, change)
-			sort.Slice(c.Update, func(i, j int) bool {
-				return kindOrder[c.Update[i].Kind] < kindOrder[c.Update[j].Kind]
+			sort.SliceStable(c.Update, func(i, j int) bool {
+				return changeLess(c.Update[i], c.Update[j], false)
 			})
 		case "Delete":
 			c.Delete = append(c.Delete, change)
-			sort.Slice(c.Delete, func(i, j int) bool {
-				return kindOrder[c.Delete[i].Kind] > kindOrder[c.Delete[j].Kind]
+			sort.SliceStable(c.Delete, func(i, j int) bool {
+				return changeLess(c.Delete[i], c.Delete[j], true)
 			})
 		case "Noop":
 			c.Noop = append(c.Noop, change)
+			sort.SliceStable(c.Noop, func(i, j int) bool {
+				return changeLess(c.Noop[i], c.Noop[j], false)
+			})
 		}
 	}
 }
 
+// changeLess orders changes by kind and then by name. If reverseKinds is
+// true, kinds are ordered the other way round (as needed for deletion).
+func changeLess(a, b *Change, reverseKinds bool) bool {
+	if kindOrder[a.Kind] != kindOrder[b.Kind] {
+		if reverseKinds {
+			return kindOrder[a.Kind] > kindOrder[b.Kind]
+		}
+		return kindOrder[a.Kind] < kindOrder[b.Kind]
+	}
+	if a.Kind != b.Kind {
+		return a.Kind < b.Kind
+	}
+	return a.Name < b.Name
+}
+
+// newJSONPatch creates a patch operation setting path to its value in item.
+func newJSONPatch(op string, path string, item *ResourceItem) *JSONPatch {
+	pointer, _ := gojsonpointer.NewJsonPointer(path)
+	val, _, _ := pointer.Get(item.Config)
+	return &JSONPatch{Op: op, Path: path, Value: val}
+}
+
+func immutableFieldReason(path string) string {
+	return fmt.Sprintf("immutable field %s changed, requiring recreation", path)
+}
+
 func recreateProtectionError(path string, itemName string) error {
 	return fmt.Errorf(
 		"Path '%s' of '%s' is immutable.\n"+
@@ -321,6 +778,7 @@ func recreateProtectionError(path string, itemName string) error {
 			"and re-create the whole resource, which Tailor prevents by default.\n\n"+
 			"You may pick one of the following options to resolve this:\n\n"+
 			"* pass --allow-recreate to give permission to recreate the resource\n"+
+			"* pass --on-immutable=warn to only report the drift and leave the resource as-is\n"+
 			"* use --preserve-immutable-fields to keep the cluster state for all immutable paths\n"+
 			"* change the template to be in sync with the cluster state\n"+
 			"* exclude the resource from comparison via --exclude %s",
@@ -1,8 +1,10 @@
 package openshift
 
 import (
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/opendevstack/tailor/internal/test/helper"
@@ -52,14 +54,17 @@ func TestNewChangesetCreationOfResources(t *testing.T) {
 				t.Fatal(err)
 			}
 			upsertOnly := false
-			allowRecreate := false
 			preservePaths := []string{}
 			cs, err := NewChangeset(
 				platformBasedList,
 				templateBasedList,
 				upsertOnly,
-				allowRecreate,
+				OnImmutableError,
+				false,
 				preservePaths,
+				[]string{},
+				[]string{},
+				0,
 			)
 			if err != nil {
 				t.Fatal(err)
@@ -143,7 +148,7 @@ func TestCalculateChangesManagedAnnotations(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			platformItem := getPlatformItem(t, "item-managed-annotations/"+tc.platformFixture+".yml")
 			templateItem := getTemplateItem(t, "item-managed-annotations/"+tc.templateFixture+".yml")
-			changes, err := calculateChanges(templateItem, platformItem, []string{}, true)
+			changes, err := calculateChanges(templateItem, platformItem, []string{}, OnImmutableRecreate, false)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -203,7 +208,7 @@ func TestCalculateChangesAppliedConfiguration(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			platformItem := getPlatformItem(t, "item-applied-config/"+tc.platformFixture+".yml")
 			templateItem := getTemplateItem(t, "item-applied-config/"+tc.templateFixture+".yml")
-			changes, err := calculateChanges(templateItem, platformItem, []string{}, true)
+			changes, err := calculateChanges(templateItem, platformItem, []string{}, OnImmutableRecreate, false)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -238,7 +243,7 @@ func TestCalculateChangesOmittedFields(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			platformItem := getPlatformItem(t, "item-omitted-fields/"+tc.platformFixture+".yml")
 			templateItem := getTemplateItem(t, "item-omitted-fields/"+tc.templateFixture+".yml")
-			changes, err := calculateChanges(templateItem, platformItem, []string{}, true)
+			changes, err := calculateChanges(templateItem, platformItem, []string{}, OnImmutableRecreate, false)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -283,7 +288,7 @@ func TestEmptyValuesDoNotCauseDrift(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			platformItem := getPlatformItem(t, "empty-values/"+tc.platformFixture)
 			templateItem := getTemplateItem(t, "empty-values/"+tc.templateFixture)
-			changes, err := calculateChanges(templateItem, platformItem, []string{}, true)
+			changes, err := calculateChanges(templateItem, platformItem, []string{}, OnImmutableRecreate, false)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -328,6 +333,116 @@ func TestAddDeleteOrder(t *testing.T) {
 	}
 }
 
+func TestAddOrderByName(t *testing.T) {
+	cs := &Changeset{}
+	cs.Add(
+		&Change{Action: "Update", Kind: "DeploymentConfig", Name: "foo"},
+		&Change{Action: "Update", Kind: "ConfigMap", Name: "foo"},
+		&Change{Action: "Update", Kind: "DeploymentConfig", Name: "bar"},
+		&Change{Action: "Delete", Kind: "ConfigMap", Name: "foo"},
+		&Change{Action: "Delete", Kind: "DeploymentConfig", Name: "foo"},
+		&Change{Action: "Delete", Kind: "ConfigMap", Name: "bar"},
+		&Change{Action: "Noop", Kind: "Service", Name: "foo"},
+		&Change{Action: "Noop", Kind: "ConfigMap", Name: "foo"},
+	)
+	got := map[string][]string{}
+	for action, changes := range map[string][]*Change{"Update": cs.Update, "Delete": cs.Delete, "Noop": cs.Noop} {
+		for _, c := range changes {
+			got[action] = append(got[action], c.ItemName())
+		}
+	}
+	want := map[string][]string{
+		"Update": {"cm/foo", "dc/bar", "dc/foo"},
+		"Delete": {"dc/foo", "cm/bar", "cm/foo"},
+		"Noop":   {"cm/foo", "svc/foo"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Order mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	tests := map[string]struct {
+		actions     []string
+		wantRemoved int
+		want        map[string][]string
+	}{
+		"only create": {
+			actions:     []string{"create"},
+			wantRemoved: 4,
+			want:        map[string][]string{"Create": {"cm/new"}},
+		},
+		"only update": {
+			actions:     []string{"update"},
+			wantRemoved: 4,
+			want:        map[string][]string{"Update": {"cm/changed"}},
+		},
+		"create and delete keeps recreations": {
+			actions:     []string{"create", "delete"},
+			wantRemoved: 1,
+			want: map[string][]string{
+				"Create": {"cm/new", "svc/recreated"},
+				"Delete": {"svc/recreated", "cm/old"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cs := &Changeset{}
+			cs.Add(
+				&Change{Action: "Create", Kind: "ConfigMap", Name: "new"},
+				&Change{Action: "Update", Kind: "ConfigMap", Name: "changed"},
+				&Change{Action: "Delete", Kind: "ConfigMap", Name: "old"},
+				&Change{Action: "Delete", Kind: "Service", Name: "recreated"},
+				&Change{Action: "Create", Kind: "Service", Name: "recreated"},
+			)
+			removed := cs.Restrict(tc.actions)
+			if removed != tc.wantRemoved {
+				t.Errorf("Want %d removed changes, got %d", tc.wantRemoved, removed)
+			}
+			got := map[string][]string{}
+			for action, changes := range map[string][]*Change{"Create": cs.Create, "Update": cs.Update, "Delete": cs.Delete} {
+				for _, c := range changes {
+					got[action] = append(got[action], c.ItemName())
+				}
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Changeset mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarkDiffOnly(t *testing.T) {
+	cs := &Changeset{}
+	cs.Add(
+		&Change{Action: "Create", Kind: "ConfigMap", Name: "foo"},
+		&Change{Action: "Update", Kind: "DeploymentConfig", Name: "foo"},
+		&Change{Action: "Delete", Kind: "ConfigMap", Name: "bar"},
+	)
+	err := cs.MarkDiffOnly([]string{"cm/foo", "configmap/bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	applicable, diffOnly := cs.WithoutDiffOnly()
+	got := []string{}
+	for _, c := range diffOnly {
+		got = append(got, c.ItemName())
+	}
+	if diff := cmp.Diff([]string{"cm/bar", "cm/foo"}, got); diff != "" {
+		t.Fatalf("Diff-only mismatch (-want +got):\n%s", diff)
+	}
+	if len(applicable.Update) != 1 || len(applicable.Create) != 0 || len(applicable.Delete) != 0 {
+		t.Fatalf("Want only the update to be applicable, got: %#v", applicable)
+	}
+
+	for _, invalid := range []string{"cm", "foo/bar", "cm/"} {
+		if err := cs.MarkDiffOnly([]string{invalid}); err == nil {
+			t.Errorf("Want error for '%s', got none", invalid)
+		}
+	}
+}
+
 func fillChangeset(action string) *Changeset {
 	cs := &Changeset{}
 	cDC := &Change{
@@ -454,6 +569,59 @@ items:
 	}
 }
 
+func TestConfigUpdateJSONPatches(t *testing.T) {
+	templateInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+  data:
+    changed: new
+    added: bar`)
+	platformInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+  data:
+    changed: old
+    removed: baz`)
+	filter := &ResourceFilter{
+		Kinds: []string{"ConfigMap"},
+	}
+	changeset := getChangeset(t, filter, platformInput, templateInput, false, false, []string{})
+	if len(changeset.Update) != 1 {
+		t.Fatalf("Changeset.Update has %d items instead of 1", len(changeset.Update))
+	}
+	want := `--- JSON Patch
+[
+  {
+    "op": "replace",
+    "path": "/data/changed",
+    "value": "new"
+  },
+  {
+    "op": "add",
+    "path": "/data/added",
+    "value": "bar"
+  },
+  {
+    "op": "remove",
+    "path": "/data/removed"
+  }
+]
+`
+	if diff := cmp.Diff(want, changeset.Update[0].JSONPatches(false)); diff != "" {
+		t.Fatalf("JSON patch mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestConfigPreservePaths(t *testing.T) {
 	templateInput := []byte(
 		`kind: List
@@ -527,6 +695,274 @@ items:
 	}
 }
 
+func TestConfigPreservePathPatterns(t *testing.T) {
+	templateInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: BuildConfig
+  metadata:
+    name: foo
+  spec:
+    runPolicy: Serial
+    triggers:
+    - generic:
+        secret: password
+      type: Generic
+    - generic:
+        secret: password
+      type: Generic`)
+
+	platformInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: BuildConfig
+  metadata:
+    name: foo
+  spec:
+    runPolicy: Serial
+    triggers:
+    - generic:
+        secret: abc
+      type: Generic
+    - generic:
+        secret: def
+      type: Generic`)
+
+	filter := &ResourceFilter{
+		Kinds: []string{"BuildConfig"},
+	}
+	tests := map[string]struct {
+		preservePaths   []string
+		expectedUpdates int
+	}{
+		"pattern matching all indices": {
+			preservePaths:   []string{"bc:/spec/triggers/*/generic/secret"},
+			expectedUpdates: 0,
+		},
+		"exact pointer matching one index": {
+			preservePaths:   []string{"bc:/spec/triggers/0/generic/secret"},
+			expectedUpdates: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			changeset := getChangeset(t, filter, platformInput, templateInput, false, true, tc.preservePaths)
+			actualUpdates := len(changeset.Update)
+			if actualUpdates != tc.expectedUpdates {
+				t.Errorf("Changeset.Update has %d items instead of %d", actualUpdates, tc.expectedUpdates)
+			}
+		})
+	}
+}
+
+func TestConfigComparePolicies(t *testing.T) {
+	templateInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: BuildConfig
+  metadata:
+    labels:
+      app: foo
+    name: foo
+  spec:
+    runPolicy: Serial
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: bar
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: Endpoints
+  metadata:
+    name: baz
+  subsets: []`)
+
+	platformInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: BuildConfig
+  metadata:
+    labels:
+      app: bar
+    name: foo
+  spec:
+    runPolicy: Serial
+- apiVersion: v1
+  kind: Endpoints
+  metadata:
+    name: baz
+  subsets:
+  - addresses:
+    - ip: 10.0.0.1`)
+
+	filter := &ResourceFilter{
+		Kinds: []string{"BuildConfig", "ConfigMap", "Endpoints"},
+	}
+	tests := map[string]struct {
+		comparePolicies []string
+		expectedCreates int
+		expectedUpdates int
+	}{
+		"strict by default": {
+			comparePolicies: []string{},
+			expectedCreates: 1,
+			expectedUpdates: 2,
+		},
+		"spec-only ignores metadata": {
+			comparePolicies: []string{"bc:spec-only", "endpoints:ignore"},
+			expectedCreates: 1,
+			expectedUpdates: 0,
+		},
+		"ignore excludes kind": {
+			comparePolicies: []string{"configmap:ignore", "bc:strict", "Endpoints:ignore"},
+			expectedCreates: 0,
+			expectedUpdates: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			platformBasedList, err := NewPlatformBasedResourceList(filter, platformInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			templateBasedList, err := NewTemplateBasedResourceList(filter, templateInput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			changeset, err := NewChangeset(platformBasedList, templateBasedList, false, OnImmutableError, false, []string{}, []string{}, tc.comparePolicies, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changeset.Create) != tc.expectedCreates {
+				t.Errorf("Changeset.Create has %d items instead of %d", len(changeset.Create), tc.expectedCreates)
+			}
+			if len(changeset.Update) != tc.expectedUpdates {
+				t.Errorf("Changeset.Update has %d items instead of %d", len(changeset.Update), tc.expectedUpdates)
+			}
+		})
+	}
+}
+
+func TestConfigRecreateAnnotation(t *testing.T) {
+	templateInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    annotations:
+      tailor.opendevstack.org/recreate: "true"
+    name: foo
+  data:
+    foo: baz`)
+
+	filter := &ResourceFilter{
+		Kinds: []string{"ConfigMap"},
+	}
+	tests := map[string]struct {
+		platformData    string
+		expectedCreates int
+		expectedUpdates int
+		expectedDeletes int
+		expectedNoops   int
+	}{
+		"drift leads to recreation": {
+			platformData:    "bar",
+			expectedCreates: 1,
+			expectedDeletes: 1,
+		},
+		"no drift leads to noop": {
+			platformData:  "baz",
+			expectedNoops: 1,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			platformInput := []byte(
+				`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    annotations:
+      tailor.opendevstack.org/recreate: "true"
+    name: foo
+  data:
+    foo: ` + tc.platformData)
+			changeset := getChangeset(t, filter, platformInput, templateInput, false, false, []string{})
+			if len(changeset.Create) != tc.expectedCreates ||
+				len(changeset.Update) != tc.expectedUpdates ||
+				len(changeset.Delete) != tc.expectedDeletes ||
+				len(changeset.Noop) != tc.expectedNoops {
+				t.Fatalf(
+					"Want %d/%d/%d/%d creates/updates/deletes/noops, got %d/%d/%d/%d",
+					tc.expectedCreates, tc.expectedUpdates, tc.expectedDeletes, tc.expectedNoops,
+					len(changeset.Create), len(changeset.Update), len(changeset.Delete), len(changeset.Noop),
+				)
+			}
+			if len(changeset.Unmanaged) > 0 {
+				t.Fatalf("Recreated resources should not be unmanaged, got: %v", changeset.Unmanaged)
+			}
+		})
+	}
+}
+
+func TestConfigDiffOnlyAnnotation(t *testing.T) {
+	templateInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    annotations:
+      tailor.opendevstack.org/diff-only: "true"
+    name: foo
+  data:
+    foo: baz`)
+	platformInput := []byte(
+		`kind: List
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    annotations:
+      tailor.opendevstack.org/diff-only: "true"
+    name: foo
+  data:
+    foo: bar
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    annotations:
+      tailor.opendevstack.org/diff-only: "true"
+    name: bar
+  data:
+    foo: bar`)
+	filter := &ResourceFilter{
+		Kinds: []string{"ConfigMap"},
+	}
+	changeset := getChangeset(t, filter, platformInput, templateInput, false, false, []string{})
+	if len(changeset.Update) != 1 || len(changeset.Delete) != 1 {
+		t.Fatalf("Want 1 update and 1 delete, got %d/%d", len(changeset.Update), len(changeset.Delete))
+	}
+	if !changeset.Update[0].DiffOnly || !changeset.Delete[0].DiffOnly {
+		t.Fatal("Want changes of annotated resources to be diff-only")
+	}
+}
+
 func TestConfigCreation(t *testing.T) {
 	templateInput := []byte(
 		`kind: List
@@ -602,12 +1038,222 @@ items:
 	if len(changeset.Delete) != 1 {
 		t.Errorf("Changeset.Delete is blank but should not be")
 	}
+	if strings.Join(changeset.Unmanaged, ",") != "pvc/foo" {
+		t.Errorf("Want pvc/foo to be unmanaged, got: %v", changeset.Unmanaged)
+	}
+
+	upsertOnlyChangeset := getChangeset(t, filter, platformInput, templateInput, true, true, []string{})
+	if len(upsertOnlyChangeset.Delete) != 0 {
+		t.Errorf("Changeset.Delete should be blank in upsert-only mode")
+	}
+	if strings.Join(upsertOnlyChangeset.Unmanaged, ",") != "pvc/foo" {
+		t.Errorf("Want pvc/foo to be unmanaged in upsert-only mode, got: %v", upsertOnlyChangeset.Unmanaged)
+	}
+}
+
+func TestConfigIdentity(t *testing.T) {
+	templateInput := []byte(
+		`kind: List
+metadata: {}
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    generateName: foo-
+    labels:
+      app: foo
+  data:
+    bar: baz`)
+
+	platformInput := []byte(
+		`kind: List
+metadata: {}
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo-x7k2p
+    generateName: foo-
+    labels:
+      app: foo
+  data:
+    bar: qux`)
+
+	filter := &ResourceFilter{
+		Kinds: []string{"ConfigMap"},
+	}
+	platformBasedList, err := NewPlatformBasedResourceList(filter, platformInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateBasedList, err := NewTemplateBasedResourceList(filter, templateInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeset, err := NewChangeset(platformBasedList, templateBasedList, false, OnImmutableError, false, []string{}, []string{"cm:app"}, []string{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changeset.Create) != 0 || len(changeset.Delete) != 0 {
+		t.Fatalf("Changeset should neither create nor delete, got %d creations and %d deletions", len(changeset.Create), len(changeset.Delete))
+	}
+	if len(changeset.Update) != 1 {
+		t.Fatalf("Changeset.Update has %d items instead of 1", len(changeset.Update))
+	}
+	if changeset.Update[0].Name != "foo-x7k2p" {
+		t.Fatalf("Update should target foo-x7k2p, got %s", changeset.Update[0].Name)
+	}
+	if !strings.Contains(changeset.Update[0].DesiredState, "name: foo-x7k2p") {
+		t.Fatalf("Desired state should contain remote name, got:\n%s", changeset.Update[0].DesiredState)
+	}
+}
+
+func TestCalculateChangesReasons(t *testing.T) {
+	platformItem := getItem(t, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  foo: bar
+  baz: qux`), "platform")
+	templateItem := getItem(t, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  foo: baz
+  new: value`), "template")
+	changes, err := calculateChanges(templateItem, platformItem, []string{}, OnImmutableError, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"/data/foo differs",
+		"/data/new is missing in current state",
+		"/data/baz is missing in desired state",
+	}
+	if diff := cmp.Diff(want, changes[0].Reasons); diff != "" {
+		t.Fatalf("Reasons mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCalculateChangesIgnoreWhitespace(t *testing.T) {
+	platformItem := getItem(t, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  conf: "a = 1\r\nb = 2\r\n"`), "platform")
+	tests := map[string]struct {
+		ignoreWhitespace bool
+		templateConf     string
+		wantAction       string
+	}{
+		"whitespace differs": {
+			ignoreWhitespace: false,
+			templateConf:     `a = 1  \nb = 2`,
+			wantAction:       "Update",
+		},
+		"whitespace differs and is ignored": {
+			ignoreWhitespace: true,
+			templateConf:     `a = 1  \nb = 2`,
+			wantAction:       "Noop",
+		},
+		"content differs and whitespace is ignored": {
+			ignoreWhitespace: true,
+			templateConf:     `a = 1\nb = 3`,
+			wantAction:       "Update",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			templateItem := getItem(t, []byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  conf: "`+tc.templateConf+`"`), "template")
+			changes, err := calculateChanges(templateItem, platformItem, []string{}, OnImmutableError, tc.ignoreWhitespace)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changes[0].Action != tc.wantAction {
+				t.Fatalf("Want action %s, got %s", tc.wantAction, changes[0].Action)
+			}
+		})
+	}
+}
+
+func TestConfigAutoscaledReplicas(t *testing.T) {
+	hpaInput := []byte(
+		`kind: List
+metadata: {}
+apiVersion: v1
+items:
+- apiVersion: autoscaling/v1
+  kind: HorizontalPodAutoscaler
+  metadata:
+    name: foo
+  spec:
+    maxReplicas: 5
+    minReplicas: 2
+    scaleTargetRef:
+      apiVersion: apps.openshift.io/v1
+      kind: DeploymentConfig
+      name: foo`)
+
+	templateInput := []byte(
+		`kind: List
+metadata: {}
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: DeploymentConfig
+  metadata:
+    name: foo
+  spec:
+    replicas: 1`)
+
+	platformInput := []byte(
+		`kind: List
+metadata: {}
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: DeploymentConfig
+  metadata:
+    name: foo
+  spec:
+    replicas: 3`)
+
+	hpaList, err := NewPlatformBasedResourceList(&ResourceFilter{Kinds: []string{"HorizontalPodAutoscaler"}}, hpaInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	preservePaths := hpaList.AutoscaledReplicasPaths()
+	if len(preservePaths) != 1 || preservePaths[0] != "dc:foo:/spec/replicas" {
+		t.Fatalf("Want preserve path for dc/foo, got: %v", preservePaths)
+	}
+
+	filter, err := NewResourceFilter("dc", "", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeset := getChangeset(t, filter, platformInput, templateInput, false, false, preservePaths)
+	if !changeset.Blank() {
+		t.Fatalf("Changeset should be blank, got %d updates", len(changeset.Update))
+	}
+	if len(changeset.Noop) != 1 {
+		t.Fatalf("Changeset.Noop has %d items instead of 1", len(changeset.Noop))
+	}
 }
 
 func TestCalculateChangesEqual(t *testing.T) {
 	currentItem := getItem(t, getBuildConfig(), "platform")
 	desiredItem := getItem(t, getBuildConfig(), "template")
-	_, err := calculateChanges(desiredItem, currentItem, []string{}, true)
+	_, err := calculateChanges(desiredItem, currentItem, []string{}, OnImmutableRecreate, false)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
@@ -617,7 +1263,7 @@ func TestCalculateChangesImmutableFields(t *testing.T) {
 	platformItem := getItem(t, getRoute([]byte("old.com")), "platform")
 
 	unchangedTemplateItem := getItem(t, getRoute([]byte("old.com")), "template")
-	changes, err := calculateChanges(unchangedTemplateItem, platformItem, []string{}, true)
+	changes, err := calculateChanges(unchangedTemplateItem, platformItem, []string{}, OnImmutableRecreate, false)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
@@ -626,13 +1272,29 @@ func TestCalculateChangesImmutableFields(t *testing.T) {
 	}
 
 	changedTemplateItem := getItem(t, getRoute([]byte("new.com")), "template")
-	changes, err = calculateChanges(changedTemplateItem, platformItem, []string{}, true)
+	changes, err = calculateChanges(changedTemplateItem, platformItem, []string{}, OnImmutableRecreate, false)
 	if err != nil {
 		t.Errorf(err.Error())
 	}
 	if len(changes) == 0 {
 		t.Errorf("Platform and template should have drift.")
 	}
+
+	_, err = calculateChanges(changedTemplateItem, platformItem, []string{}, OnImmutableError, false)
+	if err == nil {
+		t.Errorf("Drift of immutable field should be an error by default.")
+	}
+
+	changes, err = calculateChanges(changedTemplateItem, platformItem, []string{}, OnImmutableWarn, false)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	if len(changes) != 1 || changes[0].Action != "Update" || !changes[0].DiffOnly {
+		t.Fatalf("Immutable drift should be a diff-only update, got %d change(s): %v", len(changes), changes[0])
+	}
+	if len(changes[0].ImmutablePaths) != 1 || changes[0].ImmutablePaths[0] != "/spec/host" {
+		t.Errorf("Want /spec/host as immutable path, got %v", changes[0].ImmutablePaths)
+	}
 }
 
 func getChangeset(t *testing.T, filter *ResourceFilter, platformInput, templateInput []byte, upsertOnly bool, allowRecreate bool, preservePaths []string) *Changeset {
@@ -644,7 +1306,11 @@ func getChangeset(t *testing.T, filter *ResourceFilter, platformInput, templateI
 	if err != nil {
 		t.Error("Could not create template based list:", err)
 	}
-	changeset, err := NewChangeset(platformBasedList, templateBasedList, upsertOnly, allowRecreate, preservePaths)
+	onImmutable := OnImmutableError
+	if allowRecreate {
+		onImmutable = OnImmutableRecreate
+	}
+	changeset, err := NewChangeset(platformBasedList, templateBasedList, upsertOnly, onImmutable, false, preservePaths, []string{}, []string{}, 0)
 	if err != nil {
 		t.Error("Could not create changeset:", err)
 	}
@@ -655,3 +1321,59 @@ func getGoldenDiff(t *testing.T, folder string, filename string) string {
 	b := helper.ReadGoldenFile(t, folder+"/"+filename)
 	return string(b)
 }
+
+func TestConfigPruneAge(t *testing.T) {
+	now := time.Now().UTC()
+	platformInput := []byte(fmt.Sprintf(
+		`kind: List
+metadata: {}
+apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: old
+    creationTimestamp: %s
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: new
+    creationTimestamp: %s
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: unknown
+  data:
+    bar: baz`,
+		now.Add(-48*time.Hour).Format(time.RFC3339),
+		now.Add(-1*time.Hour).Format(time.RFC3339),
+	))
+	templateInput := []byte(
+		`kind: List
+metadata: {}
+apiVersion: v1
+items: []`)
+
+	filter := &ResourceFilter{
+		Kinds: []string{"ConfigMap"},
+	}
+	platformBasedList, err := NewPlatformBasedResourceList(filter, platformInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateBasedList, err := NewTemplateBasedResourceList(filter, templateInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeset, err := NewChangeset(platformBasedList, templateBasedList, false, OnImmutableError, false, []string{}, []string{}, []string{}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changeset.Delete) != 1 || changeset.Delete[0].Name != "old" {
+		t.Fatalf("Changeset should only delete cm/old, got %d deletions", len(changeset.Delete))
+	}
+}
@@ -0,0 +1,62 @@
+package openshift
+
+import (
+	"fmt"
+)
+
+// TemplateProcessError is returned when a template cannot be processed.
+type TemplateProcessError struct {
+	Template string
+	Err      error
+}
+
+func (e *TemplateProcessError) Error() string {
+	return fmt.Sprintf("Could not process %s template: %s", e.Template, e.Err)
+}
+
+func (e *TemplateProcessError) Unwrap() error {
+	return e.Err
+}
+
+// ExportError is returned when resources cannot be exported from the cluster.
+type ExportError struct {
+	Target string
+	Err    error
+}
+
+func (e *ExportError) Error() string {
+	return fmt.Sprintf("Could not export %s resources: %s", e.Target, e.Err)
+}
+
+func (e *ExportError) Unwrap() error {
+	return e.Err
+}
+
+// DecryptError is returned when encrypted params cannot be decrypted. Key is
+// empty if the failure is not related to a specific param (e.g. when the
+// private key cannot be read).
+type DecryptError struct {
+	Key string
+	Err error
+}
+
+func (e *DecryptError) Error() string {
+	if len(e.Key) == 0 {
+		return fmt.Sprintf("Could not decrypt params: %s", e.Err)
+	}
+	return fmt.Sprintf("Could not decrypt param %s: %s", e.Key, e.Err)
+}
+
+func (e *DecryptError) Unwrap() error {
+	return e.Err
+}
+
+// DriftDetectedError is returned when the current state differs from the
+// desired state (or, when comparing e.g. two param files, the renderings
+// differ from each other). It is not a failure of Tailor itself, but allows
+// callers to react on the drift, e.g. via the exit code.
+type DriftDetectedError struct{}
+
+func (e *DriftDetectedError) Error() string {
+	return "Drift detected"
+}
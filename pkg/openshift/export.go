@@ -8,6 +8,7 @@ import (
 
 	"github.com/ghodss/yaml"
 	"github.com/opendevstack/tailor/pkg/cli"
+	"github.com/xeipuuv/gojsonpointer"
 )
 
 var (
@@ -17,14 +18,110 @@ var (
 	}
 )
 
+// ungroupedTemplateName is the name of the template holding resources which
+// do not carry the label resources are grouped by.
+const ungroupedTemplateName = "ungrouped"
+
 // ExportAsTemplateFile exports resources in template format.
-func ExportAsTemplateFile(filter *ResourceFilter, withAnnotations bool, namespace string, withHardcodedNamespace bool, trimAnnotations []string, ocClient cli.OcClientExporter) (string, error) {
+func ExportAsTemplateFile(filter *ResourceFilter, withAnnotations bool, namespace string, withHardcodedNamespace bool, trimAnnotations []string, annotationPrefixes []string, stripPaths []string, ocClient cli.OcClientExporter) (string, error) {
+	objects, err := exportObjects(filter, withAnnotations, namespace, withHardcodedNamespace, trimAnnotations, annotationPrefixes, stripPaths, ocClient)
+	if err != nil || objects == nil {
+		return "", err
+	}
+	return marshalTemplate(objects, withHardcodedNamespace)
+}
+
+// ExportAsTemplateFilesByLabel exports resources in template format, with one
+// template per distinct value of given label. The returned map is keyed by
+// label value. Resources without the label are put into a template named
+// "ungrouped".
+func ExportAsTemplateFilesByLabel(label string, filter *ResourceFilter, withAnnotations bool, namespace string, withHardcodedNamespace bool, trimAnnotations []string, annotationPrefixes []string, stripPaths []string, ocClient cli.OcClientExporter) (map[string]string, error) {
+	objects, err := exportObjects(filter, withAnnotations, namespace, withHardcodedNamespace, trimAnnotations, annotationPrefixes, stripPaths, ocClient)
+	if err != nil || objects == nil {
+		return nil, err
+	}
+
+	labelPointer, _ := gojsonpointer.NewJsonPointer("/metadata/labels/" + strings.Replace(strings.Replace(label, "~", "~0", -1), "/", "~1", -1))
+	groups := map[string][]map[string]interface{}{}
+	for _, o := range objects {
+		group := ungroupedTemplateName
+		if val, _, err := labelPointer.Get(o); err == nil {
+			group = fmt.Sprintf("%v", val)
+		}
+		groups[group] = append(groups[group], o)
+	}
+
+	templates := map[string]string{}
+	for group, groupObjects := range groups {
+		t, err := marshalTemplate(groupObjects, withHardcodedNamespace)
+		if err != nil {
+			return nil, err
+		}
+		templates[group] = t
+	}
+	return templates, nil
+}
+
+// marshalTemplate returns a template containing given objects.
+func marshalTemplate(objects []map[string]interface{}, withHardcodedNamespace bool) (string, error) {
+	t := map[string]interface{}{
+		"apiVersion": "template.openshift.io/v1",
+		"kind":       "Template",
+		"objects":    objects,
+	}
+
+	if !withHardcodedNamespace {
+		parameters := []map[string]interface{}{
+			{
+				"name":     "TAILOR_NAMESPACE",
+				"required": true,
+			},
+		}
+		t["parameters"] = parameters
+	}
+
+	b, err := yaml.Marshal(t)
+	if err != nil {
+		return "", fmt.Errorf(
+			"Could not marshal template: %s", err,
+		)
+	}
+
+	return string(b), err
+}
+
+// ExportAsList exports resources as a multi-document YAML stream, with one
+// document per resource. As there is no way to supply parameters to plain
+// manifests, the namespace is kept as-is.
+func ExportAsList(filter *ResourceFilter, withAnnotations bool, namespace string, trimAnnotations []string, annotationPrefixes []string, stripPaths []string, ocClient cli.OcClientExporter) (string, error) {
+	objects, err := exportObjects(filter, withAnnotations, namespace, true, trimAnnotations, annotationPrefixes, stripPaths, ocClient)
+	if err != nil || len(objects) == 0 {
+		return "", err
+	}
+
+	documents := []string{}
+	for _, o := range objects {
+		b, err := yaml.Marshal(o)
+		if err != nil {
+			return "", fmt.Errorf(
+				"Could not marshal resource: %s", err,
+			)
+		}
+		documents = append(documents, string(b))
+	}
+
+	return "---\n" + strings.Join(documents, "---\n"), nil
+}
+
+// exportObjects returns the cleaned configuration of all resources matching
+// filter. If no resources are found, nil is returned.
+func exportObjects(filter *ResourceFilter, withAnnotations bool, namespace string, withHardcodedNamespace bool, trimAnnotations []string, annotationPrefixes []string, stripPaths []string, ocClient cli.OcClientExporter) ([]map[string]interface{}, error) {
 	outBytes, err := ocClient.Export(filter.ConvertToKinds(), filter.Label)
 	if err != nil {
-		return "", fmt.Errorf("Could not export %s resources: %s", filter.String(), err)
+		return nil, &ExportError{Target: filter.String(), Err: err}
 	}
 	if len(outBytes) == 0 {
-		return "", nil
+		return nil, nil
 	}
 
 	if !withHardcodedNamespace {
@@ -39,12 +136,19 @@ func ExportAsTemplateFile(filter *ResourceFilter, withAnnotations bool, namespac
 
 	list, err := NewPlatformBasedResourceList(filter, outBytes)
 	if err != nil {
-		return "", fmt.Errorf("Could not create resource list from export: %s", err)
+		return nil, fmt.Errorf("Could not create resource list from export: %s", err)
 	}
 
 	objects := []map[string]interface{}{}
 	for _, i := range list.Items {
-		if withAnnotations {
+		if len(annotationPrefixes) > 0 {
+			cli.DebugMsg("Keep only annotations matching", strings.Join(annotationPrefixes, ", "), "in template item")
+			for ia := range i.Annotations {
+				if !hasAnyPrefix(ia, annotationPrefixes) {
+					i.removeAnnotion(ia)
+				}
+			}
+		} else if withAnnotations {
 			cli.DebugMsg("All annotations will be kept in template item")
 		} else {
 			trimAnnotations = append(trimAnnotations, trimAnnotationsDefault...)
@@ -59,31 +163,51 @@ func ExportAsTemplateFile(filter *ResourceFilter, withAnnotations bool, namespac
 				}
 			}
 		}
+		err := i.stripPaths(stripPaths)
+		if err != nil {
+			return nil, err
+		}
 		objects = append(objects, i.Config)
 	}
 
-	t := map[string]interface{}{
-		"apiVersion": "template.openshift.io/v1",
-		"kind":       "Template",
-		"objects":    objects,
-	}
+	return objects, nil
+}
 
-	if !withHardcodedNamespace {
-		parameters := []map[string]interface{}{
-			{
-				"name":     "TAILOR_NAMESPACE",
-				"required": true,
-			},
+// hasAnyPrefix returns true if s starts with any of given prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
 		}
-		t["parameters"] = parameters
 	}
+	return false
+}
 
-	b, err := yaml.Marshal(t)
-	if err != nil {
-		return "", fmt.Errorf(
-			"Could not marshal template: %s", err,
-		)
+// stripPaths removes given paths from the item. Like preserved paths, they
+// can be given globally (e.g. /spec/host), per-kind (e.g. route:/spec/host)
+// or per-resource (e.g. route:foo:/spec/host), and may contain "*" segments.
+func (i *ResourceItem) stripPaths(stripPaths []string) error {
+	for _, stripPath := range stripPaths {
+		pathParts := strings.Split(stripPath, ":")
+		if len(pathParts) > 3 {
+			return fmt.Errorf("%s is not a valid strip-path argument", stripPath)
+		}
+		if len(pathParts) > 1 && i.Kind != KindMapping[strings.ToLower(pathParts[0])] {
+			continue
+		}
+		if len(pathParts) == 3 && i.Name != pathParts[1] {
+			continue
+		}
+		for _, path := range expandPreservePath(pathParts[len(pathParts)-1], i) {
+			pointer, err := gojsonpointer.NewJsonPointer(path)
+			if err != nil {
+				return fmt.Errorf("%s is not a valid strip-path argument: %s", stripPath, err)
+			}
+			_, err = pointer.Delete(i.Config)
+			if err != nil {
+				cli.DebugMsg("No such path", path, "in", i.ShortName())
+			}
+		}
 	}
-
-	return string(b), err
+	return nil
 }
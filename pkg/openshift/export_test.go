@@ -1,6 +1,7 @@
 package openshift
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -31,6 +32,8 @@ func TestExportAsTemplateFile(t *testing.T) {
 		filter                 *ResourceFilter
 		withAnnotations        bool
 		trimAnnotations        []string
+		annotationPrefixes     []string
+		stripPaths             []string
 		namespace              string
 		withHardcodedNamespace bool
 	}{
@@ -70,6 +73,16 @@ func TestExportAsTemplateFile(t *testing.T) {
 			namespace:              "foo",
 			withHardcodedNamespace: true,
 		},
+		"With annotation prefix": {
+			fixture:                "is.yml",
+			goldenTemplate:         "is-annotation-prefix.yml",
+			filter:                 newResourceFilterOrFatal(t, "is", "", []string{}),
+			withAnnotations:        false,
+			trimAnnotations:        []string{},
+			annotationPrefixes:     []string{"openshift.io/"},
+			namespace:              "foo",
+			withHardcodedNamespace: true,
+		},
 		"With TAILOR_NAMESPACE": {
 			fixture:                "bc.yml",
 			goldenTemplate:         "bc.yml",
@@ -79,6 +92,16 @@ func TestExportAsTemplateFile(t *testing.T) {
 			namespace:              "foo-dev",
 			withHardcodedNamespace: false,
 		},
+		"With stripped paths": {
+			fixture:                "bc.yml",
+			goldenTemplate:         "bc-stripped-paths.yml",
+			filter:                 newResourceFilterOrFatal(t, "bc", "", []string{}),
+			withAnnotations:        false,
+			trimAnnotations:        []string{},
+			stripPaths:             []string{"BuildConfig:/spec/postCommit", "bc:bar:/spec/strategy/*/from/namespace", "route:/spec/resources"},
+			namespace:              "foo-dev",
+			withHardcodedNamespace: false,
+		},
 		"Works with generateName": {
 			fixture:                "rolebinding-generate-name.yml",
 			goldenTemplate:         "rolebinding-generate-name.yml",
@@ -101,7 +124,7 @@ func TestExportAsTemplateFile(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			c := &mockOcExportClient{t: t, fixture: tc.fixture}
-			actual, err := ExportAsTemplateFile(tc.filter, tc.withAnnotations, tc.namespace, tc.withHardcodedNamespace, tc.trimAnnotations, c)
+			actual, err := ExportAsTemplateFile(tc.filter, tc.withAnnotations, tc.namespace, tc.withHardcodedNamespace, tc.trimAnnotations, tc.annotationPrefixes, tc.stripPaths, c)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -114,3 +137,73 @@ func TestExportAsTemplateFile(t *testing.T) {
 		})
 	}
 }
+
+func TestExportAsList(t *testing.T) {
+	tests := map[string]struct {
+		fixture    string
+		goldenList string
+		filter     *ResourceFilter
+		namespace  string
+	}{
+		"Single resource": {
+			fixture:    "is.yml",
+			goldenList: "is-list.yml",
+			filter:     newResourceFilterOrFatal(t, "is", "", []string{}),
+			namespace:  "foo",
+		},
+		"Respects filter": {
+			fixture:    "is.yml",
+			goldenList: "empty-list.yml",
+			filter:     newResourceFilterOrFatal(t, "bc", "", []string{}),
+			namespace:  "foo",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &mockOcExportClient{t: t, fixture: tc.fixture}
+			actual, err := ExportAsList(tc.filter, false, tc.namespace, []string{}, []string{}, []string{}, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := string(helper.ReadGoldenFile(t, "export/"+tc.goldenList))
+
+			if diff := cmp.Diff(expected, actual); diff != "" {
+				t.Fatalf("Expected list mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExportAsTemplateFilesByLabel(t *testing.T) {
+	c := &mockOcExportClient{t: t, fixture: "cm-labeled.yml"}
+	filter := newResourceFilterOrFatal(t, "cm", "", []string{})
+	templates, err := ExportAsTemplateFilesByLabel("app", filter, false, "myproject", false, []string{}, []string{}, []string{}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantNames := map[string][]string{
+		"foo":       {"name: foo\n", "name: foo-config\n"},
+		"bar":       {"name: bar\n"},
+		"ungrouped": {"name: shared\n"},
+	}
+	if len(templates) != len(wantNames) {
+		t.Fatalf("Want %d templates, got %d", len(wantNames), len(templates))
+	}
+	for group, names := range wantNames {
+		template, ok := templates[group]
+		if !ok {
+			t.Fatalf("Want template for group '%s'", group)
+		}
+		if got := strings.Count(template, "kind: ConfigMap"); got != len(names) {
+			t.Fatalf("Want %d resources in template '%s', got %d:\n%s", len(names), group, got, template)
+		}
+		for _, name := range names {
+			if !strings.Contains(template, name) {
+				t.Fatalf("Want template '%s' to contain '%s', got:\n%s", group, name, template)
+			}
+		}
+	}
+}
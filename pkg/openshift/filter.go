@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/opendevstack/tailor/pkg/cli"
 	"github.com/opendevstack/tailor/pkg/utils"
 )
 
@@ -28,6 +29,29 @@ var availableKinds = []string{
 	"quota",
 }
 
+// kindToAPIGroup maps kinds to the API group they belong to. The core group
+// is named "core".
+var kindToAPIGroup = map[string]string{
+	"Service":                 "core",
+	"Route":                   "route.openshift.io",
+	"DeploymentConfig":        "apps.openshift.io",
+	"Deployment":              "apps",
+	"BuildConfig":             "build.openshift.io",
+	"ImageStream":             "image.openshift.io",
+	"PersistentVolumeClaim":   "core",
+	"Template":                "template.openshift.io",
+	"ConfigMap":               "core",
+	"Secret":                  "core",
+	"RoleBinding":             "rbac.authorization.k8s.io",
+	"ServiceAccount":          "core",
+	"CronJob":                 "batch",
+	"Job":                     "batch",
+	"LimitRange":              "core",
+	"ResourceQuota":           "core",
+	"HorizontalPodAutoscaler": "autoscaling",
+	"Endpoints":               "core",
+}
+
 type ResourceFilter struct {
 	Kinds          []string
 	Name           string
@@ -35,6 +59,12 @@ type ResourceFilter struct {
 	ExcludedKinds  []string
 	ExcludedNames  []string
 	ExcludedLabels []string
+	// ExcludedFieldManagers excludes resources of which any field is managed
+	// by one of the given managers (as recorded in managedFields).
+	ExcludedFieldManagers []string
+	APIGroups             []string
+	ExcludedAPIGroups     []string
+	OnlyKinds             []string
 }
 
 // NewResourceFilter returns a filter based on kinds and flags.
@@ -119,6 +149,82 @@ func NewResourceFilter(kindArg string, selectorFlag string, excludes []string) (
 	return filter, nil
 }
 
+// RestrictAPIGroups limits the kinds which are considered when no kinds are
+// targeted explicitly ("all") to those of apiGroups (if any), minus those of
+// excludedAPIGroups.
+func (f *ResourceFilter) RestrictAPIGroups(apiGroups []string, excludedAPIGroups []string) error {
+	for _, g := range apiGroups {
+		f.APIGroups = append(f.APIGroups, normalizeAPIGroup(g))
+	}
+	for _, g := range excludedAPIGroups {
+		f.ExcludedAPIGroups = append(f.ExcludedAPIGroups, normalizeAPIGroup(g))
+	}
+	if len(f.allKinds()) == 0 {
+		return errors.New("No kinds left to consider after applying API group filters")
+	}
+	return nil
+}
+
+// RestrictKinds limits the kinds which are considered when no kinds are
+// targeted explicitly ("all") to onlyKinds (if any). This is the inverse of
+// excluding kinds.
+func (f *ResourceFilter) RestrictKinds(onlyKinds []string) error {
+	unknownKinds := []string{}
+	for _, k := range onlyKinds {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if _, ok := KindMapping[k]; !ok {
+			unknownKinds = append(unknownKinds, k)
+		} else if !utils.Includes(f.OnlyKinds, KindMapping[k]) {
+			f.OnlyKinds = append(f.OnlyKinds, KindMapping[k])
+		}
+	}
+	if len(unknownKinds) > 0 {
+		return fmt.Errorf(
+			"Unknown resource kinds to consider only: %s",
+			strings.Join(unknownKinds, ","),
+		)
+	}
+	if len(f.allKinds()) == 0 {
+		return errors.New("No kinds left to consider after applying kind filters")
+	}
+	return nil
+}
+
+func normalizeAPIGroup(apiGroup string) string {
+	apiGroup = strings.ToLower(strings.TrimSpace(apiGroup))
+	if len(apiGroup) == 0 || apiGroup == "v1" {
+		return "core"
+	}
+	return apiGroup
+}
+
+// allKinds returns the available kinds, restricted by API group and kind
+// filters.
+func (f *ResourceFilter) allKinds() []string {
+	kinds := []string{}
+	for _, k := range availableKinds {
+		if f.kindAllowed(KindMapping[k]) {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds
+}
+
+func (f *ResourceFilter) kindAllowed(kind string) bool {
+	if len(f.OnlyKinds) > 0 && !utils.Includes(f.OnlyKinds, kind) {
+		return false
+	}
+	return f.apiGroupAllowed(kind)
+}
+
+func (f *ResourceFilter) apiGroupAllowed(kind string) bool {
+	apiGroup := kindToAPIGroup[kind]
+	if len(f.APIGroups) > 0 && !utils.Includes(f.APIGroups, apiGroup) {
+		return false
+	}
+	return !utils.Includes(f.ExcludedAPIGroups, apiGroup)
+}
+
 func (f *ResourceFilter) String() string {
 	return fmt.Sprintf("Kinds: %s, Name: %s, Label: %s, ExcludedKinds: %s, ExcludedNames: %s, ExcludedLabels: %s", f.Kinds, f.Name, f.Label, f.ExcludedKinds, f.ExcludedNames, f.ExcludedLabels)
 }
@@ -132,6 +238,10 @@ func (f *ResourceFilter) SatisfiedBy(item *ResourceItem) bool {
 		return false
 	}
 
+	if len(f.Name) == 0 && len(f.Kinds) == 0 && !f.kindAllowed(item.Kind) {
+		return false
+	}
+
 	if len(f.Label) > 0 {
 		labels := strings.Split(f.Label, ",")
 		for _, label := range labels {
@@ -161,6 +271,13 @@ func (f *ResourceFilter) SatisfiedBy(item *ResourceItem) bool {
 		}
 	}
 
+	for _, manager := range item.FieldManagers {
+		if utils.Includes(f.ExcludedFieldManagers, manager) {
+			cli.DebugMsg("Excluding", item.ShortName(), "as it is managed by", manager)
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -170,7 +287,7 @@ func (f *ResourceFilter) ConvertToTarget() string {
 	}
 	kinds := f.Kinds
 	if len(kinds) == 0 {
-		kinds = availableKinds
+		kinds = f.allKinds()
 	}
 	return strings.Join(kinds, ",")
 }
@@ -182,7 +299,7 @@ func (f *ResourceFilter) ConvertToKinds() string {
 	}
 	kinds := f.Kinds
 	if len(kinds) == 0 {
-		kinds = availableKinds
+		kinds = f.allKinds()
 	}
 	return strings.Join(kinds, ",")
 }
@@ -194,3 +194,116 @@ func makeItem(config []byte) (*ResourceItem, error) {
 	m := f.(map[string]interface{})
 	return NewResourceItem(m, "template")
 }
+
+func TestRestrictAPIGroups(t *testing.T) {
+	tests := map[string]struct {
+		kindArg           string
+		apiGroups         []string
+		excludedAPIGroups []string
+		wantKinds         string
+		wantError         bool
+	}{
+		"no restriction": {
+			wantKinds: "svc,route,dc,deployment,bc,is,pvc,template,cm,secret,rolebinding,serviceaccount,cronjob,job,limitrange,quota",
+		},
+		"included groups": {
+			apiGroups: []string{"core", "apps"},
+			wantKinds: "svc,deployment,pvc,cm,secret,serviceaccount,limitrange,quota",
+		},
+		"excluded groups": {
+			excludedAPIGroups: []string{"core", "batch", "apps.openshift.io", "build.openshift.io"},
+			wantKinds:         "route,deployment,is,template,rolebinding",
+		},
+		"explicit kinds are not restricted": {
+			kindArg:           "bc",
+			excludedAPIGroups: []string{"build.openshift.io"},
+			wantKinds:         "BuildConfig",
+		},
+		"no kinds left": {
+			apiGroups: []string{"example.com"},
+			wantError: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter, err := NewResourceFilter(tc.kindArg, "", []string{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = filter.RestrictAPIGroups(tc.apiGroups, tc.excludedAPIGroups)
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("Want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filter.ConvertToKinds(); got != tc.wantKinds {
+				t.Fatalf("Want kinds '%s', got '%s'", tc.wantKinds, got)
+			}
+		})
+	}
+}
+
+func TestRestrictKinds(t *testing.T) {
+	tests := map[string]struct {
+		kindArg   string
+		onlyKinds []string
+		apiGroups []string
+		wantKinds string
+		wantError bool
+	}{
+		"no restriction": {
+			wantKinds: "svc,route,dc,deployment,bc,is,pvc,template,cm,secret,rolebinding,serviceaccount,cronjob,job,limitrange,quota",
+		},
+		"only given kinds": {
+			onlyKinds: []string{"dc", "svc", "route", "configmap"},
+			wantKinds: "svc,route,dc,cm",
+		},
+		"combined with API groups": {
+			onlyKinds: []string{"dc", "svc", "route", "cm"},
+			apiGroups: []string{"core"},
+			wantKinds: "svc,cm",
+		},
+		"explicit kinds are not restricted": {
+			kindArg:   "bc",
+			onlyKinds: []string{"dc"},
+			wantKinds: "BuildConfig",
+		},
+		"unknown kind": {
+			onlyKinds: []string{"foo"},
+			wantError: true,
+		},
+		"no kinds left": {
+			onlyKinds: []string{"bc"},
+			apiGroups: []string{"core"},
+			wantError: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter, err := NewResourceFilter(tc.kindArg, "", []string{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = filter.RestrictAPIGroups(tc.apiGroups, []string{})
+			if err == nil {
+				err = filter.RestrictKinds(tc.onlyKinds)
+			}
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("Want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := filter.ConvertToKinds(); got != tc.wantKinds {
+				t.Fatalf("Want kinds '%s', got '%s'", tc.wantKinds, got)
+			}
+		})
+	}
+}
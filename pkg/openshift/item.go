@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/opendevstack/tailor/pkg/cli"
@@ -31,6 +32,7 @@ var (
 		"/metadata/resourceVersion",
 		"/metadata/selfLink",
 		"/metadata/uid",
+		"/metadata/managedFields",
 		"/imagePullSecrets",
 		"/secrets",
 		"/spec/selector/matchLabels/controller-uid",
@@ -54,30 +56,34 @@ var (
 	}
 
 	KindMapping = map[string]string{
-		"svc":                   "Service",
-		"service":               "Service",
-		"route":                 "Route",
-		"dc":                    "DeploymentConfig",
-		"deploymentconfig":      "DeploymentConfig",
-		"deployment":            "Deployment",
-		"bc":                    "BuildConfig",
-		"buildconfig":           "BuildConfig",
-		"is":                    "ImageStream",
-		"imagestream":           "ImageStream",
-		"pvc":                   "PersistentVolumeClaim",
-		"persistentvolumeclaim": "PersistentVolumeClaim",
-		"template":              "Template",
-		"cm":                    "ConfigMap",
-		"configmap":             "ConfigMap",
-		"secret":                "Secret",
-		"rolebinding":           "RoleBinding",
-		"serviceaccount":        "ServiceAccount",
-		"cronjob":               "CronJob",
-		"cj":                    "CronJob",
-		"job":                   "Job",
-		"limitrange":            "LimitRange",
-		"resourcequota":         "ResourceQuota",
-		"quota":                 "ResourceQuota",
+		"svc":                     "Service",
+		"service":                 "Service",
+		"route":                   "Route",
+		"dc":                      "DeploymentConfig",
+		"deploymentconfig":        "DeploymentConfig",
+		"deployment":              "Deployment",
+		"bc":                      "BuildConfig",
+		"buildconfig":             "BuildConfig",
+		"is":                      "ImageStream",
+		"imagestream":             "ImageStream",
+		"pvc":                     "PersistentVolumeClaim",
+		"persistentvolumeclaim":   "PersistentVolumeClaim",
+		"template":                "Template",
+		"cm":                      "ConfigMap",
+		"configmap":               "ConfigMap",
+		"secret":                  "Secret",
+		"rolebinding":             "RoleBinding",
+		"serviceaccount":          "ServiceAccount",
+		"cronjob":                 "CronJob",
+		"cj":                      "CronJob",
+		"job":                     "Job",
+		"limitrange":              "LimitRange",
+		"resourcequota":           "ResourceQuota",
+		"quota":                   "ResourceQuota",
+		"hpa":                     "HorizontalPodAutoscaler",
+		"horizontalpodautoscaler": "HorizontalPodAutoscaler",
+		"ep":                      "Endpoints",
+		"endpoints":               "Endpoints",
 	}
 )
 
@@ -92,7 +98,12 @@ type ResourceItem struct {
 	AnnotationsPresent       bool
 	LastAppliedConfiguration map[string]interface{}
 	LastAppliedAnnotations   map[string]interface{}
+	ResourceVersion          string
+	CreationTimestamp        time.Time
+	FieldManagers            []string
 	Comparable               bool
+	// Pruned is true if the item was only found via --prune.
+	Pruned bool
 }
 
 func NewResourceItem(m map[string]interface{}, source string) (*ResourceItem, error) {
@@ -112,6 +123,14 @@ func (i *ResourceItem) ShortName() string {
 	return kindToShortMapping[i.Kind] + "/" + i.Name
 }
 
+// APIVersion returns the apiVersion of the item.
+func (i *ResourceItem) APIVersion() string {
+	if apiVersion, ok := i.Config["apiVersion"].(string); ok {
+		return apiVersion
+	}
+	return ""
+}
+
 func (i *ResourceItem) HasLabel(label string) bool {
 	labelParts := strings.Split(label, "=")
 	if _, ok := i.Labels[labelParts[0]]; !ok {
@@ -158,6 +177,44 @@ func (i *ResourceItem) parseConfig(m map[string]interface{}) error {
 		i.Name = generateName.(string)
 	}
 
+	// Extract resource version (before it is removed as platform-managed field)
+	resourceVersionPointer, _ := gojsonpointer.NewJsonPointer("/metadata/resourceVersion")
+	resourceVersion, _, err := resourceVersionPointer.Get(m)
+	if err == nil {
+		if rv, ok := resourceVersion.(string); ok {
+			i.ResourceVersion = rv
+		}
+	}
+
+	// Extract creation timestamp (before it is removed as platform-managed field)
+	creationTimestampPointer, _ := gojsonpointer.NewJsonPointer("/metadata/creationTimestamp")
+	creationTimestamp, _, err := creationTimestampPointer.Get(m)
+	if err == nil {
+		if ct, ok := creationTimestamp.(string); ok {
+			t, err := time.Parse(time.RFC3339, ct)
+			if err == nil {
+				i.CreationTimestamp = t
+			}
+		}
+	}
+
+	// Extract field managers (before managed fields are removed as
+	// platform-managed field)
+	i.FieldManagers = []string{}
+	managedFieldsPointer, _ := gojsonpointer.NewJsonPointer("/metadata/managedFields")
+	managedFields, _, err := managedFieldsPointer.Get(m)
+	if err == nil {
+		if entries, ok := managedFields.([]interface{}); ok {
+			for _, entry := range entries {
+				if e, ok := entry.(map[string]interface{}); ok {
+					if manager, ok := e["manager"].(string); ok && !utils.Includes(i.FieldManagers, manager) {
+						i.FieldManagers = append(i.FieldManagers, manager)
+					}
+				}
+			}
+		}
+	}
+
 	// Determine if item is comparable and therefore relevant for Tailor
 	i.Comparable = true
 	// Secrets of type "kubernetes.io/dockercfg" and
@@ -332,6 +389,39 @@ func (i *ResourceItem) parseConfig(m map[string]interface{}) error {
 	return nil
 }
 
+// withServerDefaults returns a new item based on the server-defaulted
+// configuration. Metadata is kept from the item, as the server adds fields
+// (e.g. uid) which are not part of the desired state.
+func (i *ResourceItem) withServerDefaults(defaulted []byte) (*ResourceItem, error) {
+	var d map[string]interface{}
+	err := yaml.Unmarshal(defaulted, &d)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	for k, v := range d {
+		if k != "metadata" && k != "status" {
+			m[k] = v
+		}
+	}
+	m["metadata"] = i.Config["metadata"]
+	return NewResourceItem(m, i.Source)
+}
+
+// rename sets the name of the item to given name.
+func (i *ResourceItem) rename(name string) error {
+	namePointer, _ := gojsonpointer.NewJsonPointer("/metadata/name")
+	_, err := namePointer.Set(i.Config, name)
+	if err != nil {
+		return fmt.Errorf("Could not rename %s to %s: %s", i.FullName(), name, err)
+	}
+	if !utils.Includes(i.Paths, "/metadata/name") {
+		i.Paths = append(i.Paths, "/metadata/name")
+	}
+	i.Name = name
+	return nil
+}
+
 func (i *ResourceItem) isImmutableField(field string) bool {
 	for _, key := range immutableFields[i.Kind] {
 		if key == field {
@@ -474,3 +564,15 @@ func (platformItem *ResourceItem) prepareForComparisonWithTemplateItem(templateI
 
 	return nil
 }
+
+// recreateRequested returns true if the item is annotated to be recreated
+// instead of updated.
+func (i *ResourceItem) recreateRequested() bool {
+	return fmt.Sprintf("%v", i.Annotations[RecreateAnnotation]) == "true"
+}
+
+// diffOnly returns true if the item is annotated to be reported but never
+// applied.
+func (i *ResourceItem) diffOnly() bool {
+	return fmt.Sprintf("%v", i.Annotations[DiffOnlyAnnotation]) == "true"
+}
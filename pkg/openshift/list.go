@@ -1,7 +1,11 @@
 package openshift
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/ghodss/yaml"
 	"github.com/opendevstack/tailor/pkg/cli"
@@ -31,6 +35,71 @@ func NewPlatformBasedResourceList(filter *ResourceFilter, inputs ...[]byte) (*Re
 	return list, err
 }
 
+// DocumentsAsList turns a stream of YAML documents (e.g. as rendered by
+// "helm get manifest" or "kustomize build") into a List, so that it can be
+// read like an export. Documents which are lists themselves contribute their
+// items, so an export is returned unchanged in substance.
+func DocumentsAsList(input []byte) ([]byte, error) {
+	items := []interface{}{}
+	for _, doc := range splitDocuments(input) {
+		var f interface{}
+		err := yaml.Unmarshal(doc, &f)
+		if err != nil {
+			return nil, utils.DisplaySyntaxError(doc, err)
+		}
+		m, ok := f.(map[string]interface{})
+		if !ok {
+			// Documents consisting only of comments are empty.
+			continue
+		}
+		if kind, _ := m["kind"].(string); strings.HasSuffix(kind, "List") {
+			if listItems, ok := m["items"].([]interface{}); ok {
+				items = append(items, listItems...)
+			}
+			continue
+		}
+		items = append(items, m)
+	}
+	return yaml.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      items,
+	})
+}
+
+// splitDocuments splits input at document separators ("---").
+func splitDocuments(input []byte) [][]byte {
+	docs := [][]byte{}
+	current := []string{}
+	for _, line := range strings.Split(string(input), "\n") {
+		if strings.TrimRight(line, " \t\r") == "---" {
+			docs = append(docs, []byte(strings.Join(current, "\n")))
+			current = []string{}
+			continue
+		}
+		current = append(current, line)
+	}
+	return append(docs, []byte(strings.Join(current, "\n")))
+}
+
+// ApplyServerDefaults replaces all items with their server-defaulted version
+// as returned by defaulter, so that defaults omitted in the templates do not
+// cause drift.
+func (l *ResourceList) ApplyServerDefaults(defaulter cli.OcClientDefaulter) error {
+	for idx, item := range l.Items {
+		defaulted, err := defaulter.Default(item.YamlConfig())
+		if err != nil {
+			return fmt.Errorf("Could not apply server defaults to %s: %s", item.ShortName(), err)
+		}
+		defaultedItem, err := item.withServerDefaults(defaulted)
+		if err != nil {
+			return fmt.Errorf("Could not apply server defaults to %s: %s", item.ShortName(), err)
+		}
+		l.Items[idx] = defaultedItem
+	}
+	return nil
+}
+
 // Length returns the number of items in the resource list
 func (l *ResourceList) Length() int {
 	return len(l.Items)
@@ -45,9 +114,113 @@ func (l *ResourceList) getItem(kind string, name string) (*ResourceItem, error)
 	return nil, errors.New("No such item")
 }
 
+// Adopt adds those items of candidates to the list which are defined in
+// templateBasedList, but are missing in the list, e.g. because they lack the
+// selector label. The adopted items are returned.
+func (l *ResourceList) Adopt(templateBasedList *ResourceList, candidates *ResourceList) []*ResourceItem {
+	adopted := []*ResourceItem{}
+	for _, item := range templateBasedList.Items {
+		if _, err := l.getItem(item.Kind, item.Name); err == nil {
+			continue
+		}
+		candidate, err := candidates.getItem(item.Kind, item.Name)
+		if err != nil {
+			continue
+		}
+		l.Items = append(l.Items, candidate)
+		adopted = append(adopted, candidate)
+	}
+	return adopted
+}
+
+// Prune adds those items of candidates to the list which are not defined in
+// templateBasedList and are missing in the list, e.g. because their kind is
+// not selected. The added items are marked as pruned and returned.
+func (l *ResourceList) Prune(templateBasedList *ResourceList, candidates *ResourceList, identities []string) ([]*ResourceItem, error) {
+	identityLabels, err := parseIdentities(identities)
+	if err != nil {
+		return nil, err
+	}
+	pruned := []*ResourceItem{}
+	for _, candidate := range candidates.Items {
+		if _, err := l.getItem(candidate.Kind, candidate.Name); err == nil {
+			continue
+		}
+		if _, err := templateBasedList.matchingItem(candidate, identityLabels); err == nil {
+			continue
+		}
+		candidate.Pruned = true
+		l.Items = append(l.Items, candidate)
+		pruned = append(pruned, candidate)
+	}
+	return pruned, nil
+}
+
+// matchingItem returns the item corresponding to other. Items are matched by
+// kind and name, unless an identity label is configured for the kind and
+// other carries that label, in which case the label value is used.
+func (l *ResourceList) matchingItem(other *ResourceItem, identityLabels map[string]string) (*ResourceItem, error) {
+	if identityLabel, ok := identityLabels[other.Kind]; ok {
+		if identity, ok := other.Labels[identityLabel]; ok {
+			return l.getItemByIdentity(other.Kind, identityLabel, fmt.Sprintf("%v", identity))
+		}
+	}
+	return l.getItem(other.Kind, other.Name)
+}
+
+// AutoscaledReplicasPaths returns preserve paths (e.g. "dc:foo:/spec/replicas")
+// for all resources targeted by a HorizontalPodAutoscaler in the list.
+func (l *ResourceList) AutoscaledReplicasPaths() []string {
+	paths := []string{}
+	kindPointer, _ := gojsonpointer.NewJsonPointer("/spec/scaleTargetRef/kind")
+	namePointer, _ := gojsonpointer.NewJsonPointer("/spec/scaleTargetRef/name")
+	for _, item := range l.Items {
+		if item.Kind != "HorizontalPodAutoscaler" {
+			continue
+		}
+		kind, _, err := kindPointer.Get(item.Config)
+		if err != nil {
+			continue
+		}
+		name, _, err := namePointer.Get(item.Config)
+		if err != nil {
+			continue
+		}
+		shortKind, ok := kindToShortMapping[fmt.Sprintf("%v", kind)]
+		if !ok {
+			continue
+		}
+		cli.DebugMsg("Preserving replicas of", shortKind+"/"+fmt.Sprintf("%v", name), "as it is autoscaled by", item.ShortName())
+		paths = append(paths, fmt.Sprintf("%s:%v:/spec/replicas", shortKind, name))
+	}
+	return paths
+}
+
+// ItemsWithAPIVersion returns the items using one of the given apiVersions.
+func (l *ResourceList) ItemsWithAPIVersion(apiVersions []string) []*ResourceItem {
+	items := []*ResourceItem{}
+	for _, item := range l.Items {
+		if utils.Includes(apiVersions, item.APIVersion()) {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getItemByIdentity returns the item of given kind which carries label
+// identityLabel with given value.
+func (l *ResourceList) getItemByIdentity(kind string, identityLabel string, identity string) (*ResourceItem, error) {
+	for _, item := range l.Items {
+		if item.Kind == kind && item.HasLabel(identityLabel+"="+identity) {
+			return item, nil
+		}
+	}
+	return nil, errors.New("No such item")
+}
+
 func (l *ResourceList) appendItems(source, itemsField string, inputs ...[]byte) error {
 	for _, input := range inputs {
-		if len(input) == 0 {
+		if len(bytes.TrimSpace(input)) == 0 {
 			cli.DebugMsg("Input config empty")
 			continue
 		}
@@ -55,20 +228,35 @@ func (l *ResourceList) appendItems(source, itemsField string, inputs ...[]byte)
 		var f interface{}
 		err := yaml.Unmarshal(input, &f)
 		if err != nil {
+			if utils.YAMLErrorLine(err) > 0 {
+				return invalidYAMLError(input, err)
+			}
 			err = utils.DisplaySyntaxError(input, err)
 			return err
 		}
-		m := f.(map[string]interface{})
+		if f == nil {
+			cli.DebugMsg("Input config empty")
+			continue
+		}
+		m, ok := f.(map[string]interface{})
+		if !ok {
+			return errors.New("Cannot find items to append")
+		}
 
+		// A template might legitimately produce no items at all (e.g. when
+		// all objects are guarded by a parameter), in which case the items
+		// field is missing or null. Such input simply contributes nothing.
 		p, _ := gojsonpointer.NewJsonPointer(itemsField)
 		items, _, err := p.Get(m)
-		if err != nil {
-			return err
+		if err != nil || items == nil {
+			cli.DebugMsg("Input config has no items")
+			continue
 		}
-		if items == nil {
+		itemList, ok := items.([]interface{})
+		if !ok {
 			return errors.New("Cannot find items to append")
 		}
-		for _, v := range items.([]interface{}) {
+		for _, v := range itemList {
 			item, err := NewResourceItem(v.(map[string]interface{}), source)
 			if err != nil {
 				return err
@@ -81,3 +269,75 @@ func (l *ResourceList) appendItems(source, itemsField string, inputs ...[]byte)
 
 	return nil
 }
+
+var (
+	itemStartRegex    = regexp.MustCompile(`^- `)
+	itemKindRegex     = regexp.MustCompile(`^(?:- |  )kind: *(\S+)`)
+	itemMetadataRegex = regexp.MustCompile(`^(?:- |  )metadata:`)
+	itemNameRegex     = regexp.MustCompile(`^    name: *(\S+)`)
+)
+
+// invalidYAMLError turns a parse error of processed template output into an
+// actionable message, naming the resource the offending line belongs to (if
+// it can be determined) and showing the surrounding lines.
+func invalidYAMLError(input []byte, err error) error {
+	line := utils.YAMLErrorLine(err)
+	location := fmt.Sprintf("line %d", line)
+	if resource := resourceAtLine(input, line); len(resource) > 0 {
+		location = fmt.Sprintf("%s (line %d)", resource, line)
+	}
+	return fmt.Errorf(
+		"Processed output is not valid YAML in %s: %s\n%s"+
+			"This is often caused by a param value which needs to be quoted in the template.",
+		location,
+		err,
+		utils.Snippet(input, line, 2),
+	)
+}
+
+// resourceAtLine returns kind and name of the list item which contains given
+// line of the YAML list, e.g. "ConfigMap/foo". An empty string is returned
+// if line does not belong to an item.
+func resourceAtLine(input []byte, line int) string {
+	lines := strings.Split(string(input), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	start := -1
+	for i := line - 1; i >= 0; i-- {
+		if itemStartRegex.MatchString(lines[i]) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	kind := ""
+	name := ""
+	inMetadata := false
+	for i := start; i < len(lines); i++ {
+		l := lines[i]
+		if i > start && (itemStartRegex.MatchString(l) || (len(l) > 0 && l[0] != ' ')) {
+			break
+		}
+		if m := itemKindRegex.FindStringSubmatch(l); m != nil {
+			kind = m[1]
+		}
+		if itemMetadataRegex.MatchString(l) {
+			inMetadata = true
+			continue
+		}
+		if inMetadata {
+			if m := itemNameRegex.FindStringSubmatch(l); m != nil {
+				name = m[1]
+			} else if !strings.HasPrefix(l, "    ") {
+				inMetadata = false
+			}
+		}
+	}
+	if len(kind) == 0 || len(name) == 0 {
+		return ""
+	}
+	return kind + "/" + name
+}
@@ -1,7 +1,11 @@
 package openshift
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/utils"
 )
 
 func TestConfigFilterByKind(t *testing.T) {
@@ -205,3 +209,430 @@ metadata: {}
 		t.Errorf("No item should have been extracted, got %v items.", len(secretList.Items))
 	}
 }
+
+func TestConfigFilterByFieldManager(t *testing.T) {
+	byteList := []byte(
+		`apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    managedFields:
+    - manager: oc
+      operation: Update
+    name: foo
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    managedFields:
+    - manager: oc
+      operation: Update
+    - manager: cert-operator
+      operation: Apply
+    name: bar
+  data:
+    bar: baz
+kind: List
+metadata: {}
+`)
+
+	filter := &ResourceFilter{ExcludedFieldManagers: []string{"cert-operator"}}
+	list, err := NewPlatformBasedResourceList(filter, byteList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 {
+		t.Fatalf("One item should have been excluded, got %d items.", len(list.Items))
+	}
+	item := list.Items[0]
+	if item.Name != "foo" {
+		t.Errorf("Item should have been foo, got %s.", item.Name)
+	}
+	if strings.Contains(item.YamlConfig(), "managedFields") {
+		t.Errorf("Managed fields should have been removed, got:\n%s", item.YamlConfig())
+	}
+}
+
+func TestItemsWithAPIVersion(t *testing.T) {
+	byteList := []byte(
+		`apiVersion: v1
+items:
+- apiVersion: extensions/v1beta1
+  kind: Deployment
+  metadata:
+    name: foo
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: bar
+kind: List
+metadata: {}
+`)
+
+	filter := &ResourceFilter{}
+	list, err := NewTemplateBasedResourceList(filter, byteList)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := list.ItemsWithAPIVersion([]string{"extensions/v1beta1"})
+	if len(items) != 1 {
+		t.Fatalf("One item should use a deprecated apiVersion, got %d items.", len(items))
+	}
+	if items[0].Name != "foo" {
+		t.Errorf("Item should have been foo, got %s.", items[0].Name)
+	}
+}
+
+func TestAdopt(t *testing.T) {
+	platformInput := []byte(
+		`apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    labels:
+      app: foo
+    name: foo
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: bar
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: baz
+  data:
+    bar: baz
+kind: List
+metadata: {}
+`)
+	templateInput := []byte(
+		`apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    labels:
+      app: foo
+    name: foo
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    labels:
+      app: foo
+    name: bar
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    labels:
+      app: foo
+    name: qux
+  data:
+    bar: baz
+kind: List
+metadata: {}
+`)
+
+	filter := &ResourceFilter{Label: "app=foo"}
+	platformBasedList, err := NewPlatformBasedResourceList(filter, platformInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	templateBasedList, err := NewTemplateBasedResourceList(filter, templateInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates, err := NewPlatformBasedResourceList(&ResourceFilter{}, platformInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adopted := platformBasedList.Adopt(templateBasedList, candidates)
+	if len(adopted) != 1 || adopted[0].Name != "bar" {
+		t.Fatalf("Only bar should have been adopted, got %d items.", len(adopted))
+	}
+
+	changeset, err := NewChangeset(platformBasedList, templateBasedList, false, OnImmutableError, false, []string{}, []string{}, []string{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changeset.Create) != 1 || changeset.Create[0].Name != "qux" {
+		t.Errorf("Only qux should be created, got %d creations.", len(changeset.Create))
+	}
+	if len(changeset.Update) != 1 || changeset.Update[0].Name != "bar" {
+		t.Errorf("Only bar should be updated, got %d updates.", len(changeset.Update))
+	}
+	if len(changeset.Delete) != 0 {
+		t.Errorf("Nothing should be deleted, got %d deletions.", len(changeset.Delete))
+	}
+}
+
+func TestPrune(t *testing.T) {
+	clusterInput := []byte(
+		`apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    labels:
+      app: foo
+    name: foo
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: Service
+  metadata:
+    labels:
+      app: foo
+    name: bar
+  spec:
+    ports:
+    - port: 8080
+- apiVersion: v1
+  kind: Service
+  metadata:
+    labels:
+      app: foo
+    name: baz
+  spec:
+    ports:
+    - port: 8080
+kind: List
+metadata: {}
+`)
+	templateInput := []byte(
+		`apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    labels:
+      app: foo
+    name: foo
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: Service
+  metadata:
+    labels:
+      app: foo
+    name: bar
+  spec:
+    ports:
+    - port: 8080
+kind: List
+metadata: {}
+`)
+
+	filter := &ResourceFilter{Kinds: []string{"ConfigMap"}, Label: "app=foo"}
+	allFilter := &ResourceFilter{Label: "app=foo"}
+	templateBasedList, err := NewTemplateBasedResourceList(filter, templateInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allTemplatesList, err := NewTemplateBasedResourceList(allFilter, templateInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates, err := NewPlatformBasedResourceList(allFilter, clusterInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, upsertOnly := range []bool{false, true} {
+		platformBasedList, err := NewPlatformBasedResourceList(filter, clusterInput)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pruned, err := platformBasedList.Prune(allTemplatesList, candidates, []string{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pruned) != 1 || pruned[0].Name != "baz" {
+			t.Fatalf("Only baz should have been pruned, got %d items.", len(pruned))
+		}
+
+		changeset, err := NewChangeset(platformBasedList, templateBasedList, upsertOnly, OnImmutableError, false, []string{}, []string{}, []string{}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if upsertOnly {
+			if len(changeset.Delete) != 0 {
+				t.Errorf("Nothing should be deleted with upsert-only, got %d deletions.", len(changeset.Delete))
+			}
+			continue
+		}
+		if len(changeset.Delete) != 1 || changeset.Delete[0].Name != "baz" || !changeset.Delete[0].Pruned {
+			t.Fatalf("Only baz should be deleted as pruned, got %d deletions.", len(changeset.Delete))
+		}
+		if changeset.PrunedDeletions() != 1 {
+			t.Errorf("Want 1 pruned deletion, got %d.", changeset.PrunedDeletions())
+		}
+	}
+}
+
+func TestDocumentsAsList(t *testing.T) {
+	input := []byte(`---
+# Source: foo/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+data:
+  bar: baz
+---
+# Source: foo/templates/empty.yaml
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: foo
+  spec:
+    ports:
+    - port: 8080
+`)
+	b, err := DocumentsAsList(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := NewPlatformBasedResourceList(&ResourceFilter{}, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, item := range list.Items {
+		got = append(got, item.ShortName())
+	}
+	want := []string{"cm/foo", "svc/foo"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Items mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestTemplateBasedResourceListWithoutItems(t *testing.T) {
+	tests := map[string]string{
+		"empty":         "",
+		"whitespace":    "  \n",
+		"null":          "null\n",
+		"empty items":   "apiVersion: v1\nitems: []\nkind: List\n",
+		"null items":    "apiVersion: v1\nitems: null\nkind: List\n",
+		"missing items": "apiVersion: v1\nkind: List\nmetadata: {}\n",
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			filter := &ResourceFilter{}
+			list, err := NewTemplateBasedResourceList(filter, []byte(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if list.Length() != 0 {
+				t.Fatalf("Want no items, got %d", list.Length())
+			}
+		})
+	}
+}
+
+func TestTemplateBasedResourceListWithInvalidYAML(t *testing.T) {
+	input := `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+  data:
+    bar: baz
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    labels:
+      app: foo
+    name: bar
+  data:
+    url: http://example.com: 8080
+kind: List
+`
+	filter := &ResourceFilter{}
+	_, err := NewTemplateBasedResourceList(filter, []byte(input))
+	if err == nil {
+		t.Fatal("Want error for invalid YAML, got none")
+	}
+	for _, want := range []string{
+		"not valid YAML in ConfigMap/bar (line 16)",
+		">   16 |     url: http://example.com: 8080",
+		"    15 |   data:",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("Want error to contain '%s', got: %s", want, err)
+		}
+	}
+}
+
+type mockOcDefaulter struct{}
+
+func (c *mockOcDefaulter) Default(config string) ([]byte, error) {
+	return []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+  uid: 1234
+  creationTimestamp: "2020-01-01T00:00:00Z"
+spec:
+  ports:
+  - port: 8080
+    protocol: TCP
+  sessionAffinity: None
+status:
+  loadBalancer: {}
+`), nil
+}
+
+func TestApplyServerDefaults(t *testing.T) {
+	byteList := []byte(
+		`apiVersion: v1
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: foo
+    labels:
+      app: foo
+  spec:
+    ports:
+    - port: 8080
+kind: List
+metadata: {}
+`)
+	list, err := NewTemplateBasedResourceList(&ResourceFilter{}, byteList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = list.ApplyServerDefaults(&mockOcDefaulter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := list.Items[0]
+	for _, want := range []string{"/spec/ports/0/protocol", "/spec/sessionAffinity", "/metadata/labels/app"} {
+		if !utils.Includes(item.Paths, want) {
+			t.Fatalf("Want path %s, got paths: %v", want, item.Paths)
+		}
+	}
+	for _, notWant := range []string{"/metadata/uid", "/status/loadBalancer"} {
+		if utils.Includes(item.Paths, notWant) {
+			t.Fatalf("Did not want path %s, got paths: %v", notWant, item.Paths)
+		}
+	}
+}
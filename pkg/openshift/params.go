@@ -2,10 +2,13 @@ package openshift
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/opendevstack/tailor/pkg/cli"
@@ -31,15 +34,190 @@ func EncodedParams(input, privateKey, passphrase string) (string, error) {
 	return transformValues(input, []converterFunc{c.decrypt, c.encode})
 }
 
+// MaskedParams masks all but the last visible characters of each (cleartext)
+// param value, allowing to verify a secret without fully revealing it.
+func MaskedParams(input string, visible int) (string, error) {
+	return transformValues(input, []converterFunc{newMaskConverter(visible)})
+}
+
 // EncryptedParams is used to save cleartext params to file
 func EncryptedParams(input, previous, publicKeyDir, privateKey, passphrase string) (string, error) {
-	c, err := newWriteConverter(previous, publicKeyDir, privateKey, passphrase)
+	previousParams := map[string]string{}
+	err := extractKeyValuePairs(previous, func(key, val string) error {
+		previousParams[key] = val
+		return nil
+	}, func(line string) {})
+	if err != nil {
+		return "", err
+	}
+	c, err := newWriteConverter(previousParams, publicKeyDir, privateKey, passphrase)
 	if err != nil {
 		return "", err
 	}
 	return transformValues(input, []converterFunc{c.encrypt})
 }
 
+// ParamsDiff describes how two sets of params differ from each other.
+type ParamsDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+	From    map[string]string
+	To      map[string]string
+}
+
+// NewParamsDiff compares the params in from and to (both in cleartext).
+func NewParamsDiff(from, to string) (*ParamsDiff, error) {
+	d := &ParamsDiff{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []string{},
+		From:    map[string]string{},
+		To:      map[string]string{},
+	}
+	err := extractKeyValuePairs(from, func(key, val string) error {
+		d.From[key] = val
+		return nil
+	}, func(line string) {})
+	if err != nil {
+		return nil, err
+	}
+	err = extractKeyValuePairs(to, func(key, val string) error {
+		d.To[key] = val
+		return nil
+	}, func(line string) {})
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range d.To {
+		if fromVal, ok := d.From[key]; !ok {
+			d.Added = append(d.Added, key)
+		} else if fromVal != val {
+			d.Changed = append(d.Changed, key)
+		}
+	}
+	for key := range d.From {
+		if _, ok := d.To[key]; !ok {
+			d.Removed = append(d.Removed, key)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d, nil
+}
+
+// Blank is true when both sets of params are equal.
+func (d *ParamsDiff) Blank() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// ParamsMap returns the params in input (in cleartext) keyed by name.
+func ParamsMap(input string) (map[string]string, error) {
+	params := map[string]string{}
+	err := extractKeyValuePairs(input, func(key, val string) error {
+		params[key] = val
+		return nil
+	}, func(line string) {})
+	return params, err
+}
+
+// RecipientsChanged returns true if any param value in input is not encrypted
+// for exactly the public keys in publicKeyDir, which means that re-encryption
+// would change the file.
+func RecipientsChanged(input, publicKeyDir string) (bool, error) {
+	d, err := NewRecipientsDiff(input, publicKeyDir)
+	if err != nil {
+		return false, err
+	}
+	return !d.Blank(), nil
+}
+
+// RecipientsDiff describes how the recipients of encrypted params would
+// change when re-encrypting them for the public keys in a directory.
+type RecipientsDiff struct {
+	// Gained lists the public keys which would gain access.
+	Gained []string
+	// Lost lists the keys which would lose access. As only the key IDs are
+	// known from the encrypted values, those are given in hex format.
+	Lost []string
+}
+
+// NewRecipientsDiff compares the recipients of all param values in input
+// with the public keys in publicKeyDir.
+func NewRecipientsDiff(input, publicKeyDir string) (*RecipientsDiff, error) {
+	d := &RecipientsDiff{Gained: []string{}, Lost: []string{}}
+	publicEntityList, err := readPublicKeys(publicKeyDir)
+	if err != nil {
+		return d, err
+	}
+	gained := map[string]bool{}
+	lost := map[string]bool{}
+	err = extractKeyValuePairs(input, func(key, val string) error {
+		recipientKeyIds, err := utils.RecipientKeyIds(val)
+		if err != nil {
+			return err
+		}
+		// Each recipient needs to be one of the public keys ...
+		for _, keyID := range recipientKeyIds {
+			if len(publicEntityList.KeysById(keyID)) == 0 {
+				cli.DebugMsg(fmt.Sprintf("Param %s is encrypted for unknown key %X", key, keyID))
+				lost[fmt.Sprintf("%X", keyID)] = true
+			}
+		}
+		// ... and each public key needs to be a recipient.
+		for _, entity := range publicEntityList {
+			if !entityIncludesAnyKey(entity, recipientKeyIds) {
+				cli.DebugMsg(fmt.Sprintf("Param %s is not encrypted for key %X", key, entity.PrimaryKey.KeyId))
+				gained[entityName(entity)] = true
+			}
+		}
+		return nil
+	}, func(line string) {})
+	for k := range gained {
+		d.Gained = append(d.Gained, k)
+	}
+	for k := range lost {
+		d.Lost = append(d.Lost, k)
+	}
+	sort.Strings(d.Gained)
+	sort.Strings(d.Lost)
+	return d, err
+}
+
+// Blank is true when the recipients would not change.
+func (d *RecipientsDiff) Blank() bool {
+	return len(d.Gained) == 0 && len(d.Lost) == 0
+}
+
+// entityName returns the (first) identity of entity, falling back to the
+// key ID if the entity has no identity.
+func entityName(entity *openpgp.Entity) string {
+	names := []string{}
+	for name := range entity.Identities {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("%X", entity.PrimaryKey.KeyId)
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+func entityIncludesAnyKey(entity *openpgp.Entity, keyIds []uint64) bool {
+	for _, keyID := range keyIds {
+		if entity.PrimaryKey.KeyId == keyID {
+			return true
+		}
+		for _, subkey := range entity.Subkeys {
+			if subkey.PublicKey.KeyId == keyID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type paramConverter struct {
 	PublicEntityList  openpgp.EntityList
 	PrivateEntityList openpgp.EntityList
@@ -57,7 +235,10 @@ func (c *paramConverter) encode(key, val string) (string, string, error) {
 // Decrypt given string
 func (c *paramConverter) decrypt(key, val string) (string, string, error) {
 	newVal, err := utils.Decrypt(val, c.PrivateEntityList)
-	return key, newVal, err
+	if err != nil {
+		return key, newVal, &DecryptError{Key: key, Err: err}
+	}
+	return key, newVal, nil
 }
 
 // Encrypt encrypts given value. If the key was already present previously
@@ -87,26 +268,43 @@ type converterFunc func(key, val string) (string, string, error)
 func newReadConverter(privateKey, passphrase string) (*paramConverter, error) {
 	el, err := utils.GetEntityList([]string{privateKey}, passphrase)
 	if err != nil {
-		return nil, err
+		return nil, &DecryptError{Err: err}
 	}
 	return &paramConverter{PrivateEntityList: el}, nil
 }
 
-func newWriteConverter(previous, publicKeyDir, privateKey, passphrase string) (*paramConverter, error) {
-	// Read previous params
-	previousParams := map[string]string{}
-	err := extractKeyValuePairs(previous, func(key, val string) error {
-		previousParams[key] = val
-		return nil
-	}, func(line string) {})
+func newWriteConverter(previousParams map[string]string, publicKeyDir, privateKey, passphrase string) (*paramConverter, error) {
+	publicEntityList, err := readPublicKeys(publicKeyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	privateEntityList, err := utils.GetEntityList([]string{privateKey}, passphrase)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prefer "public-keys" folder over current directory
+	return &paramConverter{
+		PublicEntityList:  publicEntityList,
+		PrivateEntityList: privateEntityList,
+		PreviousParams:    previousParams,
+	}, nil
+}
+
+// keyFingerprintsFile optionally lists the expected fingerprints of the
+// public keys in a public key directory.
+const keyFingerprintsFile = "key-fingerprints"
+
+// readPublicKeys reads all public keys (files ending in ".key") in
+// publicKeyDir.
+func readPublicKeys(publicKeyDir string) (openpgp.EntityList, error) {
+	// Prefer "public-keys" folder, then ".tailor/keys" at the repository
+	// root over current directory
 	if publicKeyDir == "." {
 		if _, err := os.Stat("public-keys"); err == nil {
 			publicKeyDir = "public-keys"
+		} else if d, ok := repositoryKeyDir(); ok {
+			publicKeyDir = d
 		}
 	}
 
@@ -136,21 +334,89 @@ func newWriteConverter(previous, publicKeyDir, privateKey, passphrase string) (*
 		)
 	}
 
-	publicEntityList, err := utils.GetEntityList(keyFiles, "")
+	entityList, err := utils.GetEntityList(keyFiles, "")
 	if err != nil {
 		return nil, err
 	}
-
-	privateEntityList, err := utils.GetEntityList([]string{privateKey}, passphrase)
+	err = verifyKeyFingerprints(publicKeyDir, keyFiles, entityList)
 	if err != nil {
 		return nil, err
 	}
+	return entityList, nil
+}
 
-	return &paramConverter{
-		PublicEntityList:  publicEntityList,
-		PrivateEntityList: privateEntityList,
-		PreviousParams:    previousParams,
-	}, nil
+// verifyKeyFingerprints checks the keys against the fingerprints listed in
+// the keyFingerprintsFile of publicKeyDir, if present. Each line of the file
+// holds a key filename and its fingerprint (e.g. "jane.key 1A2B ..."). Every
+// key must be listed and match, so that a tampered or sneaked in key cannot
+// receive secrets. keyFiles and entityList must correspond to each other.
+func verifyKeyFingerprints(publicKeyDir string, keyFiles []string, entityList openpgp.EntityList) error {
+	manifest := filepath.Join(publicKeyDir, keyFingerprintsFile)
+	content, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("Could not read '%s': %s", manifest, err)
+	}
+	expected := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return fmt.Errorf("Invalid line in '%s': %s", manifest, line)
+		}
+		expected[fields[0]] = normalizeFingerprint(strings.Join(fields[1:], ""))
+	}
+	cli.DebugMsg("Verifying public key fingerprints against", manifest)
+	for i, keyFile := range keyFiles {
+		name := filepath.Base(keyFile)
+		want, ok := expected[name]
+		if !ok {
+			return fmt.Errorf("Public key '%s' is not listed in '%s'", keyFile, manifest)
+		}
+		got := normalizeFingerprint(hex.EncodeToString(entityList[i].PrimaryKey.Fingerprint[:]))
+		if got != want {
+			return fmt.Errorf(
+				"Fingerprint of public key '%s' is %s, but '%s' expects %s",
+				keyFile, got, manifest, want,
+			)
+		}
+	}
+	return nil
+}
+
+// normalizeFingerprint makes fingerprints comparable regardless of their
+// formatting, e.g. when copied from "gpg --fingerprint".
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", ":", "").Replace(fingerprint))
+}
+
+// repositoryKeyDir returns the ".tailor/keys" directory at the root of the
+// repository containing the working directory, if it exists. The root is the
+// closest parent directory containing ".git".
+func repositoryKeyDir() (string, bool) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			keyDir := filepath.Join(dir, ".tailor", "keys")
+			if fi, err := os.Stat(keyDir); err == nil && fi.IsDir() {
+				return keyDir, true
+			}
+			return "", false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
 }
 
 func extractKeyValuePairs(input string, consumer func(key, val string) error, passthrough func(line string)) error {
@@ -180,6 +446,25 @@ func extractKeyValuePairs(input string, consumer func(key, val string) error, pa
 	return nil
 }
 
+func newMaskConverter(visible int) converterFunc {
+	return func(key, val string) (string, string, error) {
+		return key, maskValue(val, visible), nil
+	}
+}
+
+// maskValue replaces all but the last visible characters of val with a
+// fixed-length mask, which hides the length of the value as well. Values
+// which are too short to hide at least as many characters as are shown are
+// masked completely.
+func maskValue(val string, visible int) string {
+	mask := "****"
+	runes := []rune(val)
+	if len(runes) < 2*visible {
+		return mask
+	}
+	return mask + string(runes[len(runes)-visible:])
+}
+
 func transformValues(input string, converters []converterFunc) (string, error) {
 	output := ""
 	err := extractKeyValuePairs(input, func(key, val string) error {
@@ -1,9 +1,16 @@
 package openshift
 
 import (
+	"encoding/hex"
+	"errors"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/opendevstack/tailor/pkg/utils"
 )
 
 func TestDecryptedParams(t *testing.T) {
@@ -62,6 +69,193 @@ func TestEncryptedParams(t *testing.T) {
 	}
 }
 
+func TestMaskedParams(t *testing.T) {
+	tests := map[string]struct {
+		input string
+		want  string
+	}{
+		"long value": {
+			input: "FOO=abcdefgh12\n",
+			want:  "FOO=****gh12\n",
+		},
+		"short value": {
+			input: "FOO=abc12\n",
+			want:  "FOO=****\n",
+		},
+		"comments are kept": {
+			input: "# comment\nFOO=abcdefgh12\n",
+			want:  "# comment\nFOO=****gh12\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := MaskedParams(tc.input, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewParamsDiff(t *testing.T) {
+	from := "# comment\nFOO=foo\nBAR=bar\nBAZ=baz\n"
+	to := "FOO=foo\nBAR=changed\nQUX=qux\n"
+	d, err := NewParamsDiff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"QUX"}, d.Added); diff != "" {
+		t.Errorf("Added mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"BAZ"}, d.Removed); diff != "" {
+		t.Errorf("Removed mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"BAR"}, d.Changed); diff != "" {
+		t.Errorf("Changed mismatch (-want +got):\n%s", diff)
+	}
+	if d.Blank() {
+		t.Errorf("Diff should not be blank")
+	}
+}
+
+func TestParamsMap(t *testing.T) {
+	got, err := ParamsMap("# comment\nFOO=foo\n\nBAR=a=b\nEMPTY=\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"FOO": "foo", "BAR": "a=b", "EMPTY": ""}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Params mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestYAMLParamsRoundtrip(t *testing.T) {
+	input := "# comment\ndatabase:\n  user: admin\n  password: enc:secret\ntokens:\n  - \"enc:abc\"\n  - plain\n"
+	encrypted, err := EncryptedYAMLParams(input, "", ".", "test-private.key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(encrypted, "enc:secret") || strings.Contains(encrypted, "enc:abc") {
+		t.Errorf("Secret values should be encrypted, got: %s", encrypted)
+	}
+	if !strings.Contains(encrypted, "  user: admin\n") || !strings.Contains(encrypted, "  - plain\n") {
+		t.Errorf("Plain values should be untouched, got: %s", encrypted)
+	}
+	decrypted, err := DecryptedYAMLParams(encrypted, "test-private.key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(input, decrypted); diff != "" {
+		t.Errorf("Roundtrip mismatch (-want +got):\n%s", diff)
+	}
+	reencrypted, err := EncryptedYAMLParams(decrypted, encrypted, ".", "test-private.key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(encrypted, reencrypted); diff != "" {
+		t.Errorf("Unchanged values should keep their ciphertext (-want +got):\n%s", diff)
+	}
+}
+
+func TestYAMLSecretsAsEnv(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expected string
+	}{
+		"map and list": {
+			input:    "database:\n  user: admin\n  password: enc:secret\nlist:\n  - enc:a\n  - enc:b\n",
+			expected: "database.password=secret\nlist[0]=a\nlist[1]=b\n",
+		},
+		"nested lists": {
+			input: `users:
+- name: foo
+  password: enc:a # trailing comment
+- name: bar
+  keys:
+  - enc:b
+  -
+    - plain
+    - 'enc:c'
+matrix:
+  - - enc:d
+`,
+			expected: "users[0].password=a\nusers[1].keys[0]=b\nusers[1].keys[1][1]=c\nmatrix[0][0]=d\n",
+		},
+		"block scalars": {
+			input: `description: |
+  password: enc:notasecret
+  - enc:notasecret
+certificate: >-
+  enc:line1
+  line2
+
+quoted: "enc:a # b"
+`,
+			expected: "certificate=line1\\nline2\nquoted=a # b\n",
+		},
+		"multiple documents": {
+			input:    "---\nfoo: enc:a\n---\nbar: enc:b\n",
+			expected: "foo=a\nbar=b\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := YAMLSecretsAsEnv(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expected, actual); diff != "" {
+				t.Errorf("Mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestYAMLSecretsAsEnvInvalidIndentation(t *testing.T) {
+	_, err := YAMLSecretsAsEnv("database:\n  user: admin\n    password: enc:secret\n")
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatalf("Want indentation error pointing to line 3, got: %v", err)
+	}
+}
+
+func TestYAMLParamsRoundtripBlockScalar(t *testing.T) {
+	input := `# certificate
+tls:
+  # multi-line secret
+  key: |
+    enc:-----BEGIN KEY-----
+    abc
+
+    def
+    -----END KEY-----
+  cert: |
+    password: enc:notasecret
+after: enc:secret # trailing comment
+`
+	encrypted, err := EncryptedYAMLParams(input, "", ".", "test-private.key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(encrypted, "BEGIN KEY") || strings.Contains(encrypted, "enc:secret") {
+		t.Errorf("Secret values should be encrypted, got: %s", encrypted)
+	}
+	if !strings.Contains(encrypted, "  # multi-line secret\n  key: |\n    enc:") ||
+		!strings.Contains(encrypted, "  cert: |\n    password: enc:notasecret\n") ||
+		!strings.Contains(encrypted, " # trailing comment\n") {
+		t.Errorf("Comments, formatting and block scalar content should be untouched, got: %s", encrypted)
+	}
+	decrypted, err := DecryptedYAMLParams(encrypted, "test-private.key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(input, decrypted); diff != "" {
+		t.Errorf("Roundtrip mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func readFileContent(t *testing.T, filename string) string {
 	bytes, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -69,3 +263,180 @@ func readFileContent(t *testing.T, filename string) string {
 	}
 	return string(bytes)
 }
+
+func TestRecipientsChanged(t *testing.T) {
+	input := readFileContent(t, "test-encrypted.env")
+	changed, err := RecipientsChanged(input, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Errorf("Recipients should be unchanged")
+	}
+}
+
+func TestNewRecipientsDiff(t *testing.T) {
+	keyDir, err := ioutil.TempDir("", "tailor-keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(keyDir)
+	publicKey, err := ioutil.ReadFile("test-public.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(keyDir, "test-public.key"), publicKey, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, err := utils.CreateEntity("Jane Doe", "jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = utils.PrintPublicKey(entity, filepath.Join(keyDir, "jane-doe.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := readFileContent(t, "test-encrypted.env")
+	d, err := NewRecipientsDiff(input, keyDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"Jane Doe (Generated by tailor) <jane.doe@example.com>"}, d.Gained); diff != "" {
+		t.Errorf("Gained mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{}, d.Lost); diff != "" {
+		t.Errorf("Lost mismatch (-want +got):\n%s", diff)
+	}
+
+	// Re-encrypting for both keys and comparing with the original key only
+	// means the new key loses access.
+	encrypted, err := EncryptedParams(readFileContent(t, "test-cleartext.env"), "", keyDir, "test-private.key", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err = NewRecipientsDiff(encrypted, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Gained) != 0 || len(d.Lost) != 1 {
+		t.Errorf("Want one lost key, got gained=%v, lost=%v", d.Gained, d.Lost)
+	}
+}
+
+func TestReadPublicKeysVerifiesFingerprints(t *testing.T) {
+	entityList, err := utils.GetEntityList([]string{"test-public.key"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := strings.ToLower(hex.EncodeToString(entityList[0].PrimaryKey.Fingerprint[:]))
+	tests := map[string]struct {
+		manifest  string
+		wantError string
+	}{
+		"no manifest": {
+			manifest: "",
+		},
+		"matching fingerprint": {
+			manifest: "# trusted keys\ntest.key " + fingerprint + "\n",
+		},
+		"fingerprint formatted like gpg": {
+			manifest: "test.key " + strings.ToUpper(fingerprint[:4]+" "+fingerprint[4:]) + "\n",
+		},
+		"mismatching fingerprint": {
+			manifest:  "test.key " + strings.Repeat("0", 40) + "\n",
+			wantError: "Fingerprint of public key",
+		},
+		"unlisted key": {
+			manifest:  "other.key " + fingerprint + "\n",
+			wantError: "is not listed",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "tailor-keys")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			err = ioutil.WriteFile(filepath.Join(dir, "test.key"), []byte(readFileContent(t, "test-public.key")), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.manifest) > 0 {
+				err = ioutil.WriteFile(filepath.Join(dir, keyFingerprintsFile), []byte(tc.manifest), 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err = readPublicKeys(dir)
+			if len(tc.wantError) == 0 && err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.wantError) > 0 && (err == nil || !strings.Contains(err.Error(), tc.wantError)) {
+				t.Fatalf("Want error containing '%s', got: %v", tc.wantError, err)
+			}
+		})
+	}
+}
+
+func TestRepositoryKeyDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	root, err := ioutil.TempDir("", "tailor-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	// Resolve symlinks (e.g. on macOS) so that paths are comparable
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subDir := filepath.Join(root, "openshift", "foo")
+	for _, d := range []string{filepath.Join(root, ".git"), subDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chdir(subDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := repositoryKeyDir(); ok {
+		t.Fatal("No key dir should be found without .tailor/keys")
+	}
+
+	keyDir := filepath.Join(root, ".tailor", "keys")
+	if err := os.MkdirAll(keyDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := repositoryKeyDir()
+	if !ok {
+		t.Fatal("Key dir should be found")
+	}
+	if got != keyDir {
+		t.Fatalf("Want key dir '%s', got '%s'", keyDir, got)
+	}
+}
+
+func TestDecryptedParamsError(t *testing.T) {
+	_, err := DecryptedParams("FOO=not-encrypted\n", "test-private.key", "")
+	var decryptErr *DecryptError
+	if !errors.As(err, &decryptErr) {
+		t.Fatalf("Want DecryptError, got: %v", err)
+	}
+	if decryptErr.Key != "FOO" {
+		t.Fatalf("Want DecryptError for key FOO, got: %s", decryptErr.Key)
+	}
+
+	_, err = DecryptedParams("FOO=bar\n", "does-not-exist.key", "")
+	if !errors.As(err, &decryptErr) {
+		t.Fatalf("Want DecryptError, got: %v", err)
+	}
+}
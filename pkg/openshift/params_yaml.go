@@ -0,0 +1,121 @@
+package openshift
+
+import (
+	"strings"
+)
+
+// Values in YAML param files which are prefixed with yamlSecretPrefix are
+// secret. In files, their value is encrypted; when editing or revealing, the
+// cleartext is shown (still prefixed, so that the value stays secret).
+const yamlSecretPrefix = "enc:"
+
+// IsYAMLParamFile returns true if filename is a param file in YAML format
+// (as opposed to the ".env" format).
+func IsYAMLParamFile(filename string) bool {
+	f := strings.TrimSuffix(filename, ".enc")
+	return strings.HasSuffix(f, ".yml") || strings.HasSuffix(f, ".yaml")
+}
+
+// DecryptedYAMLParams decrypts all secret values of a YAML param file.
+func DecryptedYAMLParams(input, privateKey, passphrase string) (string, error) {
+	c, err := newReadConverter(privateKey, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return transformYAMLSecrets(input, []converterFunc{c.decrypt})
+}
+
+// MaskedYAMLParams masks all but the last visible characters of each
+// (cleartext) secret value of a YAML param file.
+func MaskedYAMLParams(input string, visible int) (string, error) {
+	return transformYAMLSecrets(input, []converterFunc{newMaskConverter(visible)})
+}
+
+// EncryptedYAMLParams encrypts all secret values of a YAML param file. Values
+// which did not change compared to previous are kept as-is.
+func EncryptedYAMLParams(input, previous, publicKeyDir, privateKey, passphrase string) (string, error) {
+	previousParams := map[string]string{}
+	_, err := transformYAMLSecrets(previous, []converterFunc{func(key, val string) (string, string, error) {
+		previousParams[key] = val
+		return key, val, nil
+	}})
+	if err != nil {
+		return "", err
+	}
+	c, err := newWriteConverter(previousParams, publicKeyDir, privateKey, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return transformYAMLSecrets(input, []converterFunc{c.encrypt})
+}
+
+// YAMLSecretsAsEnv returns the secret values of a YAML param file in ".env"
+// format, keyed by their path (e.g. "database.password" or "tokens[0]"). This
+// allows to apply functionality built for ".env" files to YAML param files.
+// Line breaks of block scalars are escaped as "\n".
+func YAMLSecretsAsEnv(input string) (string, error) {
+	lines := []string{}
+	_, err := transformYAMLSecrets(input, []converterFunc{func(key, val string) (string, string, error) {
+		lines = append(lines, key+"="+strings.Replace(val, "\n", `\n`, -1))
+		return key, val, nil
+	}})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// transformYAMLSecrets applies the converters to all secret values in input,
+// leaving everything else (including comments and formatting) untouched.
+// Converters receive the path of the value as key (e.g. "database.password"
+// or "tokens[0]").
+func transformYAMLSecrets(input string, converters []converterFunc) (string, error) {
+	documents, err := parseYAMLNodes(input)
+	if err != nil {
+		return "", err
+	}
+	secrets := []*yamlScalar{}
+	for _, document := range documents {
+		err := document.scalars("", func(key string, scalar *yamlScalar) error {
+			if !strings.HasPrefix(scalar.value, yamlSecretPrefix) {
+				return nil
+			}
+			val := strings.TrimPrefix(scalar.value, yamlSecretPrefix)
+			var err error
+			for _, converter := range converters {
+				_, val, err = converter(key, val)
+				if err != nil {
+					return err
+				}
+			}
+			scalar.value = yamlSecretPrefix + val
+			secrets = append(secrets, scalar)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Replace from the bottom up so that the location of the remaining
+	// scalars stays valid when block scalars change their number of lines.
+	lines := strings.Split(input, "\n")
+	for i := len(secrets) - 1; i >= 0; i-- {
+		scalar := secrets[i]
+		if !scalar.block {
+			line := lines[scalar.line]
+			lines[scalar.line] = line[:scalar.start] + scalar.quote + scalar.value + scalar.quote + line[scalar.end:]
+			continue
+		}
+		blockLines := []string{}
+		for _, l := range strings.Split(scalar.value, "\n") {
+			if len(l) > 0 {
+				l = scalar.indentation + l
+			}
+			blockLines = append(blockLines, l)
+		}
+		tail := append(blockLines, lines[scalar.lastLine+1:]...)
+		lines = append(lines[:scalar.firstLine], tail...)
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,186 @@
+package openshift
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/ghodss/yaml"
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+// Patch is a partial resource which is merged into the processed resource
+// of the same kind and name.
+type Patch struct {
+	Source string
+	Kind   string
+	Name   string
+	Config map[string]interface{}
+}
+
+// ReadPatches reads all patches from the subdirectory of patchDir which is
+// named after the namespace (e.g. patches/foo-dev). If there is no such
+// directory, no patches are returned.
+func ReadPatches(patchDir string, namespace string) ([]*Patch, error) {
+	patches := []*Patch{}
+	dir := filepath.Join(patchDir, namespace)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			cli.DebugMsg("No patches found in", dir)
+			return patches, nil
+		}
+		return patches, fmt.Errorf("Cannot get files in patch directory '%s': %s", dir, err)
+	}
+	re := regexp.MustCompile(`.*\.ya?ml$`)
+	for _, file := range files {
+		if file.IsDir() || !re.MatchString(file.Name()) {
+			continue
+		}
+		filename := filepath.Join(dir, file.Name())
+		cli.DebugMsg("Reading patch", filename)
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return patches, err
+		}
+		var config map[string]interface{}
+		err = yaml.Unmarshal(b, &config)
+		if err != nil {
+			return patches, fmt.Errorf("Could not parse patch '%s': %s", filename, err)
+		}
+		kind, _ := config["kind"].(string)
+		metadata, _ := config["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		if len(kind) == 0 || len(name) == 0 {
+			return patches, fmt.Errorf("Patch '%s' must specify kind and metadata.name", filename)
+		}
+		patches = append(patches, &Patch{Source: filename, Kind: kind, Name: name, Config: config})
+	}
+	sort.Slice(patches, func(i, j int) bool {
+		return patches[i].Source < patches[j].Source
+	})
+	return patches, nil
+}
+
+// ApplyPatches merges patches into the matching items of the processed
+// template. Patches are merged similar to strategic merge patches: maps are
+// merged recursively and null removes a field. Lists of named entries (see
+// namedListKeys) are merged by the name of their entries, where an entry
+// with "$patch: delete" removes the entry of the same name. Any other value
+// (including other lists) replaces the existing value.
+func ApplyPatches(processed []byte, patches []*Patch) ([]byte, error) {
+	if len(patches) == 0 {
+		return processed, nil
+	}
+	var list map[string]interface{}
+	err := yaml.Unmarshal(processed, &list)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not parse processed template: %s", err)
+	}
+	items, ok := list["items"].([]interface{})
+	if !ok {
+		return processed, nil
+	}
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _ := m["kind"].(string)
+		metadata, _ := m["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		for _, p := range patches {
+			if p.Kind == kind && p.Name == name {
+				cli.DebugMsg("Applying patch", p.Source, "to", kind, name)
+				items[i] = mergePatch(m, p.Config)
+			}
+		}
+	}
+	return yaml.Marshal(list)
+}
+
+// namedListKeys are the keys of lists whose entries are identified by their
+// name (e.g. the containers of a pod template).
+var namedListKeys = map[string]bool{
+	"containers":     true,
+	"initContainers": true,
+	"env":            true,
+	"volumes":        true,
+	"volumeMounts":   true,
+	"ports":          true,
+}
+
+// patchDirectiveKey marks an entry of a named list which should be removed
+// when set to "delete".
+const patchDirectiveKey = "$patch"
+
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = map[string]interface{}{}
+	}
+	for k, v := range patchMap {
+		if v == nil {
+			delete(targetMap, k)
+		} else if namedListKeys[k] && isNamedList(v) && isNamedList(targetMap[k]) {
+			targetMap[k] = mergeNamedList(targetMap[k].([]interface{}), v.([]interface{}))
+		} else {
+			targetMap[k] = mergePatch(targetMap[k], v)
+		}
+	}
+	return targetMap
+}
+
+// mergeNamedList merges the entries of patch into the entries of target
+// with the same name. Entries which are not in target yet are appended.
+func mergeNamedList(target []interface{}, patch []interface{}) []interface{} {
+	merged := append([]interface{}{}, target...)
+	for _, p := range patch {
+		patchEntry := p.(map[string]interface{})
+		name := patchEntry["name"]
+		index := -1
+		for i, t := range merged {
+			if t.(map[string]interface{})["name"] == name {
+				index = i
+				break
+			}
+		}
+		if patchEntry[patchDirectiveKey] == "delete" {
+			if index >= 0 {
+				merged = append(merged[:index], merged[index+1:]...)
+			}
+			continue
+		}
+		if index >= 0 {
+			merged[index] = mergePatch(merged[index], patchEntry)
+		} else {
+			merged = append(merged, mergePatch(nil, patchEntry))
+		}
+	}
+	return merged
+}
+
+// isNamedList returns true if v is a list of maps which all have a name.
+func isNamedList(v interface{}) bool {
+	list, ok := v.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, entry := range list {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if _, ok := m["name"].(string); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyPatches(t *testing.T) {
+	patches, err := ReadPatches("../../internal/test/fixtures/patches", "foo-dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 1 {
+		t.Fatalf("Want 1 patch, got %d", len(patches))
+	}
+
+	processed := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: foo
+  spec:
+    replicas: 1
+    test: false
+    triggers:
+    - type: ConfigChange
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: bar
+  spec:
+    replicas: 1
+`)
+	got, err := ApplyPatches(processed, patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: v1
+items:
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: foo
+  spec:
+    replicas: 3
+    triggers:
+    - type: ConfigChange
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: bar
+  spec:
+    replicas: 1
+kind: List
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("Patched template mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMergePatchNamedLists(t *testing.T) {
+	tests := map[string]struct {
+		target string
+		patch  string
+		want   string
+	}{
+		"entry is merged by name": {
+			target: `containers:
+- name: foo
+  image: foo:1
+  env:
+  - name: A
+    value: a
+  - name: B
+    value: b
+- name: sidecar
+  image: sidecar:1
+`,
+			patch: `containers:
+- name: foo
+  image: foo:2
+  env:
+  - name: B
+    value: c
+`,
+			want: `containers:
+- env:
+  - name: A
+    value: a
+  - name: B
+    value: c
+  image: foo:2
+  name: foo
+- image: sidecar:1
+  name: sidecar
+`,
+		},
+		"new entry is appended": {
+			target: "volumes:\n- name: foo\n  emptyDir: {}\n",
+			patch:  "volumes:\n- name: bar\n  emptyDir: {}\n",
+			want:   "volumes:\n- emptyDir: {}\n  name: foo\n- emptyDir: {}\n  name: bar\n",
+		},
+		"entry is deleted": {
+			target: "ports:\n- name: http\n  port: 80\n- name: https\n  port: 443\n",
+			patch:  "ports:\n- name: http\n  $patch: delete\n",
+			want:   "ports:\n- name: https\n  port: 443\n",
+		},
+		"other lists are replaced": {
+			target: "triggers:\n- type: ConfigChange\n- type: ImageChange\n",
+			patch:  "triggers:\n- type: ImageChange\n",
+			want:   "triggers:\n- type: ImageChange\n",
+		},
+		"unnamed entries are replaced": {
+			target: "ports:\n- port: 80\n",
+			patch:  "ports:\n- port: 8080\n",
+			want:   "ports:\n- port: 8080\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var target, patch map[string]interface{}
+			if err := yaml.Unmarshal([]byte(tc.target), &target); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tc.patch), &patch); err != nil {
+				t.Fatal(err)
+			}
+			got, err := yaml.Marshal(mergePatch(target, patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Fatalf("Merge mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReadPatchesWithoutDirectory(t *testing.T) {
+	patches, err := ReadPatches("../../internal/test/fixtures/patches", "foo-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 0 {
+		t.Fatalf("Want no patches, got %d", len(patches))
+	}
+}
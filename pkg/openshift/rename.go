@@ -0,0 +1,195 @@
+package openshift
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/opendevstack/tailor/pkg/cli"
+)
+
+// RenameResources adds prefix and suffix to the names of all items of the
+// processed template. References to items of the same template (e.g. the
+// service of a route or the config map mounted into a pod) are renamed as
+// well. References to resources outside of the template are left untouched.
+func RenameResources(processed []byte, prefix string, suffix string) ([]byte, error) {
+	if len(prefix) == 0 && len(suffix) == 0 {
+		return processed, nil
+	}
+	var list map[string]interface{}
+	err := yaml.Unmarshal(processed, &list)
+	if err != nil {
+		return []byte{}, fmt.Errorf("Could not parse processed template: %s", err)
+	}
+	items, ok := list["items"].([]interface{})
+	if !ok {
+		return processed, nil
+	}
+
+	// Collect names first so that references can be renamed regardless of
+	// the order of items in the template.
+	names := map[string]bool{}
+	for _, item := range items {
+		kind, name := kindAndName(item)
+		if len(kind) > 0 && len(name) > 0 {
+			names[kind+"/"+name] = true
+		}
+	}
+	r := &renamer{names: names, prefix: prefix, suffix: suffix}
+
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, name := kindAndName(m)
+		if len(name) == 0 {
+			continue
+		}
+		cli.DebugMsg("Renaming", kind, name, "to", r.newName(name))
+		m["metadata"].(map[string]interface{})["name"] = r.newName(name)
+		r.renameReferences(kind, m)
+	}
+	return yaml.Marshal(list)
+}
+
+type renamer struct {
+	names  map[string]bool
+	prefix string
+	suffix string
+}
+
+func (r *renamer) newName(name string) string {
+	return r.prefix + name + r.suffix
+}
+
+// rename renames the string at key of m if it references an item of given
+// kind in the template.
+func (r *renamer) rename(m map[string]interface{}, key string, kind string) {
+	name, ok := m[key].(string)
+	if !ok || !r.names[kind+"/"+name] {
+		return
+	}
+	m[key] = r.newName(name)
+}
+
+// renameImageStreamTag renames the image stream part of an image stream tag
+// reference like "foo:latest".
+func (r *renamer) renameImageStreamTag(m map[string]interface{}) {
+	if m["kind"] != "ImageStreamTag" {
+		return
+	}
+	ref, ok := m["name"].(string)
+	if !ok {
+		return
+	}
+	parts := strings.SplitN(ref, ":", 2)
+	if !r.names["ImageStream/"+parts[0]] {
+		return
+	}
+	parts[0] = r.newName(parts[0])
+	m["name"] = strings.Join(parts, ":")
+}
+
+func (r *renamer) renameReferences(kind string, m map[string]interface{}) {
+	switch kind {
+	case "Route":
+		r.rename(nestedMap(m, "spec", "to"), "name", "Service")
+		for _, backend := range nestedSlice(m, "spec", "alternateBackends") {
+			if b, ok := backend.(map[string]interface{}); ok {
+				r.rename(b, "name", "Service")
+			}
+		}
+	case "HorizontalPodAutoscaler":
+		target := nestedMap(m, "spec", "scaleTargetRef")
+		if targetKind, ok := target["kind"].(string); ok {
+			r.rename(target, "name", targetKind)
+		}
+	case "RoleBinding":
+		r.rename(nestedMap(m, "roleRef"), "name", "Role")
+		for _, subject := range nestedSlice(m, "subjects") {
+			if s, ok := subject.(map[string]interface{}); ok && s["kind"] == "ServiceAccount" {
+				r.rename(s, "name", "ServiceAccount")
+			}
+		}
+	case "BuildConfig":
+		r.renameImageStreamTag(nestedMap(m, "spec", "output", "to"))
+		for _, strategy := range []string{"sourceStrategy", "dockerStrategy", "customStrategy"} {
+			r.renameImageStreamTag(nestedMap(m, "spec", "strategy", strategy, "from"))
+		}
+	case "DeploymentConfig":
+		for _, trigger := range nestedSlice(m, "spec", "triggers") {
+			if t, ok := trigger.(map[string]interface{}); ok {
+				r.renameImageStreamTag(nestedMap(t, "imageChangeParams", "from"))
+			}
+		}
+	}
+	if metadataPath, ok := podTemplateMetadataPaths[kind]; ok {
+		specPath := append(append([]string{}, metadataPath[:len(metadataPath)-1]...), "spec")
+		r.renamePodSpecReferences(nestedMap(m, specPath...))
+	}
+}
+
+func (r *renamer) renamePodSpecReferences(spec map[string]interface{}) {
+	r.rename(spec, "serviceAccountName", "ServiceAccount")
+	for _, volume := range nestedSlice(spec, "volumes") {
+		v, ok := volume.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		r.rename(nestedMap(v, "persistentVolumeClaim"), "claimName", "PersistentVolumeClaim")
+		r.rename(nestedMap(v, "configMap"), "name", "ConfigMap")
+		r.rename(nestedMap(v, "secret"), "secretName", "Secret")
+	}
+	containers := append(nestedSlice(spec, "initContainers"), nestedSlice(spec, "containers")...)
+	for _, container := range containers {
+		c, ok := container.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, env := range nestedSlice(c, "env") {
+			if e, ok := env.(map[string]interface{}); ok {
+				r.rename(nestedMap(e, "valueFrom", "configMapKeyRef"), "name", "ConfigMap")
+				r.rename(nestedMap(e, "valueFrom", "secretKeyRef"), "name", "Secret")
+			}
+		}
+		for _, envFrom := range nestedSlice(c, "envFrom") {
+			if e, ok := envFrom.(map[string]interface{}); ok {
+				r.rename(nestedMap(e, "configMapRef"), "name", "ConfigMap")
+				r.rename(nestedMap(e, "secretRef"), "name", "Secret")
+			}
+		}
+	}
+}
+
+func kindAndName(item interface{}) (string, string) {
+	m, ok := item.(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	kind, _ := m["kind"].(string)
+	name, _ := nestedMap(m, "metadata")["name"].(string)
+	return kind, name
+}
+
+// nestedMap returns the map at given path, or an empty map if there is none.
+func nestedMap(m map[string]interface{}, path ...string) map[string]interface{} {
+	current := m
+	for _, p := range path {
+		next, ok := current[p].(map[string]interface{})
+		if !ok {
+			return map[string]interface{}{}
+		}
+		current = next
+	}
+	return current
+}
+
+// nestedSlice returns the slice at given path, or nil if there is none.
+func nestedSlice(m map[string]interface{}, path ...string) []interface{} {
+	if len(path) == 0 {
+		return nil
+	}
+	s, _ := nestedMap(m, path[:len(path)-1]...)[path[len(path)-1]].([]interface{})
+	return s
+}
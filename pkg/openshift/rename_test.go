@@ -0,0 +1,107 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenameResources(t *testing.T) {
+	processed := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: foo
+- apiVersion: route.openshift.io/v1
+  kind: Route
+  metadata:
+    name: foo
+  spec:
+    to:
+      kind: Service
+      name: foo
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: foo
+  spec:
+    template:
+      spec:
+        containers:
+        - envFrom:
+          - configMapRef:
+              name: foo
+          - secretRef:
+              name: external
+        volumes:
+        - configMap:
+            name: foo
+    triggers:
+    - imageChangeParams:
+        from:
+          kind: ImageStreamTag
+          name: foo:latest
+- apiVersion: image.openshift.io/v1
+  kind: ImageStream
+  metadata:
+    name: foo
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+`)
+	got, err := RenameResources(processed, "pr-1-", "-preview")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: pr-1-foo-preview
+- apiVersion: route.openshift.io/v1
+  kind: Route
+  metadata:
+    name: pr-1-foo-preview
+  spec:
+    to:
+      kind: Service
+      name: pr-1-foo-preview
+- apiVersion: apps.openshift.io/v1
+  kind: DeploymentConfig
+  metadata:
+    name: pr-1-foo-preview
+  spec:
+    template:
+      spec:
+        containers:
+        - envFrom:
+          - configMapRef:
+              name: pr-1-foo-preview
+          - secretRef:
+              name: external
+        volumes:
+        - configMap:
+            name: pr-1-foo-preview
+    triggers:
+    - imageChangeParams:
+        from:
+          kind: ImageStreamTag
+          name: pr-1-foo-preview:latest
+- apiVersion: image.openshift.io/v1
+  kind: ImageStream
+  metadata:
+    name: pr-1-foo-preview
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: pr-1-foo-preview
+kind: List
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("Renamed template mismatch (-want +got):\n%s", diff)
+	}
+}
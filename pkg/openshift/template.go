@@ -2,10 +2,15 @@ package openshift
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/ghodss/yaml"
@@ -14,17 +19,71 @@ import (
 	"github.com/xeipuuv/gojsonpointer"
 )
 
+// TemplateHashAnnotation holds the hash of the template source a resource
+// was generated from.
+const TemplateHashAnnotation = "tailor.opendevstack.org/template-hash"
+
+// ParamsHashAnnotation holds the hash of all params a template was processed
+// with. It is set on pod templates so that workloads roll out when any param
+// changes.
+const ParamsHashAnnotation = "tailor.opendevstack.org/params-hash"
+
+var (
+	// podTemplateMetadataPaths locates the metadata of the pod template of
+	// workload kinds.
+	podTemplateMetadataPaths = map[string][]string{
+		"DeploymentConfig": {"spec", "template", "metadata"},
+		"Deployment":       {"spec", "template", "metadata"},
+		"Job":              {"spec", "template", "metadata"},
+		"CronJob":          {"spec", "jobTemplate", "spec", "template", "metadata"},
+	}
+
+	paramReferenceRegex   = regexp.MustCompile(`\$\{([a-zA-Z0-9_\.]+)\}`)
+	includeDirectiveRegex = regexp.MustCompile(`^(\s*)# tailor:include (\S+)\s*$`)
+)
+
+const (
+	// escapedParamDelimiter allows templates to contain a literal "${", e.g.
+	// in shell scripts embedded in a config map.
+	escapedParamDelimiter = "$${"
+	// escapedParamPlaceholder replaces escapedParamDelimiter while the template
+	// is processed. It must not be recognised as a param reference by oc.
+	escapedParamPlaceholder = "__tailor_escaped_param__{"
+)
+
 // ProcessTemplate processes template "name" in "templateDir".
 func ProcessTemplate(templateDir string, name string, paramDir string, compareOptions *cli.CompareOptions, ocClient cli.OcClientProcessor) ([]byte, error) {
 	filename := templateDir + string(os.PathSeparator) + name
 
-	args := []string{"--filename=" + filename, "--output=yaml"}
-
-	if len(compareOptions.Labels) > 0 {
-		args = append(args, "--labels="+compareOptions.Labels)
+	expandedTemplate, includesFound, err := expandIncludes(filename, []string{})
+	if err != nil {
+		return []byte{}, err
 	}
+	templateHash := sha256.Sum256(expandedTemplate)
+	if len(compareOptions.UnsetParams) > 0 {
+		expandedTemplate, err = unsetTemplateParams(expandedTemplate, compareOptions.UnsetParams)
+		if err != nil {
+			return []byte{}, fmt.Errorf("Could not unset params in %s: %s", name, err)
+		}
+	}
+	expandedTemplate, escapesFound := escapeParamDelimiters(expandedTemplate)
+	if includesFound || escapesFound || len(compareOptions.UnsetParams) > 0 {
+		tempTemplateFile := ".expanded.yml"
+		defer os.Remove(tempTemplateFile)
+		cli.DebugMsg("Writing expanded template into", tempTemplateFile)
+		err = ioutil.WriteFile(tempTemplateFile, expandedTemplate, 0644)
+		if err != nil {
+			return []byte{}, err
+		}
+		filename = tempTemplateFile
+	}
+
+	args := []string{"--filename=" + filename, "--output=yaml"}
 
 	for _, param := range compareOptions.Params {
+		if utils.Includes(compareOptions.UnsetParams, strings.SplitN(param, "=", 2)[0]) {
+			continue
+		}
 		args = append(args, "--param="+param)
 	}
 	containsNamespace, err := templateContainsTailorNamespaceParam(filename)
@@ -35,11 +94,27 @@ func ProcessTemplate(templateDir string, name string, paramDir string, compareOp
 		args = append(args, "--param=TAILOR_NAMESPACE="+compareOptions.Namespace)
 	}
 
-	actualParamFiles := calculateParamFiles(name, paramDir, compareOptions)
+	paramFiles, err := calculateParamFiles(name, paramDir, compareOptions)
+	if err != nil {
+		return []byte{}, err
+	}
+	actualParamFiles := orderParamFiles(paramFiles, compareOptions.ParamFilePrecedence)
+
+	if compareOptions.StrictParamConflicts {
+		err := checkParamConflicts(
+			actualParamFiles,
+			compareOptions.PrivateKey,
+			compareOptions.Passphrase,
+		)
+		if err != nil {
+			return []byte{}, err
+		}
+	}
 
 	// Now turn the param files into arguments for the oc binary
+	paramFileBytes := []byte{}
 	if len(actualParamFiles) > 0 {
-		paramFileBytes, err := readParamFileBytes(
+		paramFileBytes, err = readParamFileBytes(
 			actualParamFiles,
 			compareOptions.PrivateKey,
 			compareOptions.Passphrase,
@@ -47,6 +122,12 @@ func ProcessTemplate(templateDir string, name string, paramDir string, compareOp
 		if err != nil {
 			return []byte{}, err
 		}
+		if len(compareOptions.UnsetParams) > 0 {
+			paramFileBytes, err = withoutParams(paramFileBytes, compareOptions.UnsetParams)
+			if err != nil {
+				return []byte{}, err
+			}
+		}
 		tempParamFile := ".combined.env"
 		defer os.Remove(tempParamFile)
 		cli.DebugMsg("Writing contents of param files into", tempParamFile)
@@ -57,22 +138,389 @@ func ProcessTemplate(templateDir string, name string, paramDir string, compareOp
 		args = append(args, "--param-file="+tempParamFile)
 	}
 
+	var resolvedParamsHash string
+	if len(compareOptions.SensitiveParams) > 0 || len(compareOptions.Labels) > 0 || compareOptions.ParamsHash {
+		params, err := mergedParams(paramFileBytes, compareOptions)
+		if err != nil {
+			return []byte{}, err
+		}
+		resolvedParamsHash = paramsHash(params)
+		for _, name := range compareOptions.SensitiveParams {
+			if !cli.AddRedactedValue(params[name]) {
+				cli.PrintYellowf("Warning: Value of sensitive param %s is too short to be masked in the output.\n", name)
+			}
+		}
+		if len(compareOptions.Labels) > 0 {
+			labels, err := substituteParams(compareOptions.Labels, params)
+			if err != nil {
+				return []byte{}, fmt.Errorf("Could not resolve labels '%s': %s", compareOptions.Labels, err)
+			}
+			args = append(args, "--labels="+labels)
+		}
+	}
+
+	if cli.DebugEnabled() {
+		report, err := paramsReport(name, actualParamFiles, paramFileBytes, compareOptions)
+		if err != nil {
+			return []byte{}, err
+		}
+		cli.DebugMsg(report)
+	}
+
+	// Without access to the cluster, templates need to be processed locally.
+	if len(compareOptions.RemoteFile) > 0 || !compareOptions.ClusterRequired {
+		args = append(args, "--local")
+	}
+
 	if compareOptions.IgnoreUnknownParameters {
 		args = append(args, "--ignore-unknown-parameters=true")
 	}
 	outBytes, errBytes, err := ocClient.Process(args)
 
 	if len(errBytes) > 0 {
-		fmt.Println(string(errBytes))
+		fmt.Println(cli.Redact(string(errBytes)))
 	}
 	if err != nil {
-		return []byte{}, err
+		return []byte{}, &TemplateProcessError{Template: name, Err: err}
 	}
 
 	cli.DebugMsg("Processed template:", filename)
+
+	if escapesFound {
+		outBytes = unescapeParamDelimiters(outBytes)
+	}
+
+	if compareOptions.TemplateHash {
+		outBytes, err = annotateTemplateHash(outBytes, hex.EncodeToString(templateHash[:]))
+		if err != nil {
+			return []byte{}, err
+		}
+	}
+	if compareOptions.ParamsHash {
+		outBytes, err = annotateParamsHash(outBytes, resolvedParamsHash)
+		if err != nil {
+			return []byte{}, err
+		}
+	}
 	return outBytes, err
 }
 
+// annotateTemplateHash sets the template hash annotation on all items of
+// the processed template.
+func annotateTemplateHash(processed []byte, hash string) ([]byte, error) {
+	return annotateItems(processed, TemplateHashAnnotation, hash, func(kind string) []string {
+		return []string{"metadata"}
+	})
+}
+
+// annotateParamsHash sets the params hash annotation on the pod template of
+// all workload items of the processed template.
+func annotateParamsHash(processed []byte, hash string) ([]byte, error) {
+	return annotateItems(processed, ParamsHashAnnotation, hash, func(kind string) []string {
+		return podTemplateMetadataPaths[kind]
+	})
+}
+
+// annotateItems sets annotation to value in the metadata located at the
+// path returned by metadataPath for the kind of each item. Items for which
+// no path is returned are left untouched.
+func annotateItems(processed []byte, annotation, value string, metadataPath func(kind string) []string) ([]byte, error) {
+	var list map[string]interface{}
+	err := yaml.Unmarshal(processed, &list)
+	if err != nil {
+		if utils.YAMLErrorLine(err) > 0 {
+			return []byte{}, invalidYAMLError(processed, err)
+		}
+		return []byte{}, fmt.Errorf("Could not parse processed template: %s", err)
+	}
+	items, ok := list["items"].([]interface{})
+	if !ok {
+		return processed, nil
+	}
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _ := m["kind"].(string)
+		path := metadataPath(kind)
+		if len(path) == 0 {
+			continue
+		}
+		metadata := m
+		for _, key := range path {
+			next, ok := metadata[key].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				metadata[key] = next
+			}
+			metadata = next
+		}
+		annotations, ok := metadata["annotations"].(map[string]interface{})
+		if !ok {
+			annotations = map[string]interface{}{}
+			metadata["annotations"] = annotations
+		}
+		annotations[annotation] = value
+	}
+	return yaml.Marshal(list)
+}
+
+// paramsHash returns a hash of given params, which is independent of the
+// order in which the params were given.
+func paramsHash(params map[string]string) string {
+	keys := []string{}
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, params[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// expandIncludes returns the content of filename, with every line of the form
+// "# tailor:include <path>" being replaced by the content of <path>. The path is
+// resolved relative to the including file, and the indentation of the
+// directive is applied to all included lines. Includes may be nested, but
+// must not form a cycle.
+func expandIncludes(filename string, includedBy []string) ([]byte, bool, error) {
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return []byte{}, false, err
+	}
+	if utils.Includes(includedBy, absFilename) {
+		return []byte{}, false, fmt.Errorf(
+			"Include cycle detected: %s -> %s",
+			strings.Join(includedBy, " -> "),
+			absFilename,
+		)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return []byte{}, false, fmt.Errorf("Could not read file '%s': %s", filename, err)
+	}
+	includesFound := false
+	lines := strings.Split(string(b), "\n")
+	expandedLines := []string{}
+	for _, line := range lines {
+		matches := includeDirectiveRegex.FindStringSubmatch(line)
+		if matches == nil {
+			expandedLines = append(expandedLines, line)
+			continue
+		}
+		includesFound = true
+		indentation := matches[1]
+		includeFilename := filepath.Join(filepath.Dir(filename), matches[2])
+		cli.DebugMsg("Including", includeFilename, "into", filename)
+		included, _, err := expandIncludes(includeFilename, append(includedBy, absFilename))
+		if err != nil {
+			return []byte{}, false, err
+		}
+		includedLines := strings.Split(strings.TrimSuffix(string(included), "\n"), "\n")
+		for _, includedLine := range includedLines {
+			if len(includedLine) > 0 {
+				includedLine = indentation + includedLine
+			}
+			expandedLines = append(expandedLines, includedLine)
+		}
+	}
+	return []byte(strings.Join(expandedLines, "\n")), includesFound, nil
+}
+
+// escapeParamDelimiters replaces every escaped delimiter "$${" in template
+// with a placeholder so that "oc process" leaves it alone. It returns whether
+// any escaped delimiter was found.
+func escapeParamDelimiters(template []byte) ([]byte, bool) {
+	if !bytes.Contains(template, []byte(escapedParamDelimiter)) {
+		return template, false
+	}
+	return bytes.Replace(template, []byte(escapedParamDelimiter), []byte(escapedParamPlaceholder), -1), true
+}
+
+// unescapeParamDelimiters turns the placeholders set by escapeParamDelimiters
+// into a literal "${" in the processed output.
+func unescapeParamDelimiters(processed []byte) []byte {
+	return bytes.Replace(processed, []byte(escapedParamPlaceholder), []byte("${"), -1)
+}
+
+// unsetTemplateParams removes the default value (including any generator) of
+// given params in template, so that they resolve to an empty string.
+func unsetTemplateParams(template []byte, names []string) ([]byte, error) {
+	var t map[string]interface{}
+	err := yaml.Unmarshal(template, &t)
+	if err != nil {
+		return template, err
+	}
+	parameters, ok := t["parameters"].([]interface{})
+	if !ok {
+		return template, nil
+	}
+	for _, p := range parameters {
+		param, ok := p.(map[string]interface{})
+		if !ok || !utils.Includes(names, fmt.Sprintf("%v", param["name"])) {
+			continue
+		}
+		cli.DebugMsg("Unsetting param", fmt.Sprintf("%v", param["name"]))
+		delete(param, "value")
+		delete(param, "generate")
+		delete(param, "from")
+		param["required"] = false
+	}
+	return yaml.Marshal(t)
+}
+
+// withoutParams removes all definitions of given params from paramFileBytes.
+func withoutParams(paramFileBytes []byte, names []string) ([]byte, error) {
+	lines := []string{}
+	err := extractKeyValuePairs(string(paramFileBytes), func(key, val string) error {
+		if !utils.Includes(names, key) {
+			lines = append(lines, key+"="+val)
+		}
+		return nil
+	}, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		return paramFileBytes, err
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// mergedParams returns the params which are passed to "oc process", with
+// params given directly taking precedence over those from param files.
+func mergedParams(paramFileBytes []byte, compareOptions *cli.CompareOptions) (map[string]string, error) {
+	params := map[string]string{}
+	err := extractKeyValuePairs(string(paramFileBytes), func(key, val string) error {
+		params[key] = val
+		return nil
+	}, func(line string) {})
+	if err != nil {
+		return params, err
+	}
+	for _, param := range compareOptions.Params {
+		pair := strings.SplitN(param, "=", 2)
+		if len(pair) == 2 {
+			params[pair[0]] = pair[1]
+		}
+	}
+	for _, name := range compareOptions.UnsetParams {
+		params[name] = ""
+	}
+	params["TAILOR_NAMESPACE"] = compareOptions.Namespace
+	return params, nil
+}
+
+// paramsReport lists the params (as passed to "oc process") which template
+// name is processed with. Values of encrypted and sensitive params are masked.
+func paramsReport(name string, paramFiles []string, paramFileBytes []byte, compareOptions *cli.CompareOptions) (string, error) {
+	params, err := mergedParams(paramFileBytes, compareOptions)
+	if err != nil {
+		return "", err
+	}
+	masked, err := encryptedParamNames(paramFiles)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range compareOptions.SensitiveParams {
+		masked[n] = true
+	}
+	names := []string{}
+	for n := range params {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	lines := []string{fmt.Sprintf("Params used for template %s:", name)}
+	for _, n := range names {
+		val := params[n]
+		if masked[n] {
+			val = "***"
+		}
+		lines = append(lines, fmt.Sprintf("    %s=%s", n, val))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// encryptedParamNames returns the names of the params in the encrypted
+// counterparts (.enc) of given param files. The names are not encrypted, so
+// no private key is needed.
+func encryptedParamNames(paramFiles []string) (map[string]bool, error) {
+	names := map[string]bool{}
+	for _, f := range paramFiles {
+		b, err := ioutil.ReadFile(f + ".enc")
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return names, err
+		}
+		err = extractKeyValuePairs(string(b), func(key, val string) error {
+			names[strings.TrimSuffix(key, ".B64")] = true
+			return nil
+		}, func(line string) {})
+		if err != nil {
+			return names, err
+		}
+	}
+	return names, nil
+}
+
+// ResolvePreservePaths substitutes param references (e.g. "${ENVIRONMENT}")
+// in preserve paths. As preserve paths apply to all templates, only params
+// which are not specific to a template are considered: --param values, the
+// explicitly given param files, <namespace>.env and TAILOR_NAMESPACE.
+func ResolvePreservePaths(paths []string, compareOptions *cli.CompareOptions) ([]string, error) {
+	if !paramReferenceRegex.MatchString(strings.Join(paths, ",")) {
+		return paths, nil
+	}
+	files := append([]string{}, compareOptions.ParamFiles...)
+	namespaceDotEnvFile := fmt.Sprintf("%s.env", compareOptions.Namespace)
+	if !utils.Includes(files, namespaceDotEnvFile) && compareOptions.FileExists(namespaceDotEnvFile) {
+		files = append(files, namespaceDotEnvFile)
+	}
+	paramFileBytes, err := readParamFileBytes(
+		orderParamFiles(files, compareOptions.ParamFilePrecedence),
+		compareOptions.PrivateKey,
+		compareOptions.Passphrase,
+	)
+	if err != nil {
+		return paths, err
+	}
+	params, err := mergedParams(paramFileBytes, compareOptions)
+	if err != nil {
+		return paths, err
+	}
+	resolved := []string{}
+	for _, path := range paths {
+		r, err := substituteParams(path, params)
+		if err != nil {
+			return paths, fmt.Errorf("Could not resolve preserve path '%s': %s", path, err)
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved, nil
+}
+
+// substituteParams replaces all occurences of "${NAME}" in s with the value
+// of the param NAME. Referencing an unknown param is an error.
+func substituteParams(s string, params map[string]string) (string, error) {
+	unknownParams := []string{}
+	substituted := paramReferenceRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		name := paramReferenceRegex.FindStringSubmatch(ref)[1]
+		if val, ok := params[name]; ok {
+			return val
+		}
+		unknownParams = append(unknownParams, name)
+		return ref
+	})
+	if len(unknownParams) > 0 {
+		return s, fmt.Errorf("Unknown parameters: %s", strings.Join(unknownParams, ", "))
+	}
+	return substituted, nil
+}
+
 // Returns true if template contains a param like "name: TAILOR_NAMESPACE"
 func templateContainsTailorNamespaceParam(filename string) (bool, error) {
 	b, err := ioutil.ReadFile(filename)
@@ -112,8 +560,19 @@ func templateContainsTailorNamespaceParam(filename string) (bool, error) {
 	return false, nil
 }
 
-func calculateParamFiles(name string, paramDir string, compareOptions *cli.CompareOptions) []string {
+// calculateParamFiles determines the param files to use for template name.
+// If compareOptions.RequireParamFile is set, it is an error if the expected
+// param file of the template does not exist, instead of falling back to the
+// defaults of the template.
+func calculateParamFiles(name string, paramDir string, compareOptions *cli.CompareOptions) ([]string, error) {
 	files := compareOptions.ParamFiles
+	if compareOptions.RequireParamFile {
+		for _, f := range files {
+			if !compareOptions.FileExists(f) {
+				return nil, fmt.Errorf("Param file '%s' does not exist", f)
+			}
+		}
+	}
 	// If param-file is not given, we assume a param-dir
 	if len(files) == 0 {
 		// Prefer <namespace> folder over current directory
@@ -133,6 +592,8 @@ func calculateParamFiles(name string, paramDir string, compareOptions *cli.Compa
 		}
 		if compareOptions.FileExists(f) {
 			files = []string{f}
+		} else if compareOptions.RequireParamFile {
+			return nil, fmt.Errorf("Param file '%s' for template '%s' does not exist", f, name)
 		}
 	}
 	// Add <namespace>.env file if it exists
@@ -143,7 +604,53 @@ func calculateParamFiles(name string, paramDir string, compareOptions *cli.Compa
 			files = append(files, namespaceDotEnvFile)
 		}
 	}
-	return files
+	return files, nil
+}
+
+// orderParamFiles orders param files such that the file which should take
+// precedence comes last, as later values override earlier ones.
+func orderParamFiles(files []string, precedence string) []string {
+	if precedence != "first-wins" {
+		return files
+	}
+	ordered := []string{}
+	for i := len(files) - 1; i >= 0; i-- {
+		ordered = append(ordered, files[i])
+	}
+	return ordered
+}
+
+// checkParamConflicts returns an error if the same param is defined with
+// different values in more than one of the given param files.
+func checkParamConflicts(paramFiles []string, privateKey string, passphrase string) error {
+	type definition struct {
+		value  string
+		source string
+	}
+	definitions := map[string]definition{}
+	conflicts := []string{}
+	for _, f := range paramFiles {
+		b, err := readParamFileBytes([]string{f}, privateKey, passphrase)
+		if err != nil {
+			return err
+		}
+		err = extractKeyValuePairs(string(b), func(key, val string) error {
+			if d, ok := definitions[key]; ok && d.source != f && d.value != val {
+				conflicts = append(conflicts, fmt.Sprintf(
+					"* %s is defined differently in '%s' and '%s'", key, d.source, f,
+				))
+			}
+			definitions[key] = definition{value: val, source: f}
+			return nil
+		}, func(line string) {})
+		if err != nil {
+			return err
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("Conflicting param definitions:\n%s", strings.Join(conflicts, "\n"))
+	}
+	return nil
 }
 
 func readParamFileBytes(paramFiles []string, privateKey string, passphrase string) ([]byte, error) {
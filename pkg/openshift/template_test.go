@@ -1,6 +1,7 @@
 package openshift
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -62,12 +63,14 @@ func TestTemplateContainsTailorNamespaceParam(t *testing.T) {
 
 func TestCalculateParamFiles(t *testing.T) {
 	tests := map[string]struct {
-		namespace     string
-		templateName  string
-		paramDir      string
-		paramFileFlag []string
-		fs            utils.FileStater
-		expected      []string
+		namespace        string
+		templateName     string
+		paramDir         string
+		paramFileFlag    []string
+		requireParamFile bool
+		fs               utils.FileStater
+		expected         []string
+		expectedErr      string
 	}{
 		"template is foo.yml and corresponding param file exists": {
 			namespace:     "foo",
@@ -117,6 +120,31 @@ func TestCalculateParamFiles(t *testing.T) {
 			fs:            &helper.SomeFilesExistFS{Existing: []string{"foo.env"}},
 			expected:      []string{"foo.env"},
 		},
+		"param file is required and exists in param dir": {
+			namespace:        "foo",
+			templateName:     "bar.yml",
+			paramDir:         "foo",
+			requireParamFile: true,
+			fs:               &helper.SomeFilesExistFS{Existing: []string{"foo/bar.env"}},
+			expected:         []string{"foo/bar.env"},
+		},
+		"param file is required but does not exist in param dir": {
+			namespace:        "foo",
+			templateName:     "bar.yml",
+			paramDir:         "foo",
+			requireParamFile: true,
+			fs:               &helper.SomeFilesExistFS{Existing: []string{"foo.env"}},
+			expectedErr:      "Param file 'foo/bar.env' for template 'bar.yml' does not exist",
+		},
+		"param file is required but given param file does not exist": {
+			namespace:        "foo",
+			templateName:     "bar.yml",
+			paramDir:         ".",
+			paramFileFlag:    []string{"typo.env"},
+			requireParamFile: true,
+			fs:               &helper.SomeFilesExistFS{Existing: []string{"foo.env"}},
+			expectedErr:      "Param file 'typo.env' does not exist",
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
@@ -125,9 +153,19 @@ func TestCalculateParamFiles(t *testing.T) {
 				GlobalOptions:    globalOptions,
 				NamespaceOptions: &cli.NamespaceOptions{Namespace: tc.namespace},
 				ParamFiles:       tc.paramFileFlag,
+				RequireParamFile: tc.requireParamFile,
 			}
 
-			actual := calculateParamFiles(tc.templateName, tc.paramDir, compareOptions)
+			actual, err := calculateParamFiles(tc.templateName, tc.paramDir, compareOptions)
+			if len(tc.expectedErr) > 0 {
+				if err == nil || err.Error() != tc.expectedErr {
+					t.Fatalf("Want error '%s', got: %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
 			if diff := cmp.Diff(tc.expected, actual); diff != "" {
 				t.Fatalf("Desired state mismatch (-want +got):\n%s", diff)
 			}
@@ -166,3 +204,390 @@ func TestReadParamFileBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestResolvePreservePaths(t *testing.T) {
+	tests := map[string]struct {
+		paths     []string
+		params    []string
+		expected  []string
+		wantError bool
+	}{
+		"literal paths": {
+			paths:    []string{"bc:/spec/output/to/name"},
+			expected: []string{"bc:/spec/output/to/name"},
+		},
+		"param references": {
+			paths:    []string{"dc:foo-${ENVIRONMENT}:/spec/replicas", "route:${TAILOR_NAMESPACE}:/spec/host"},
+			params:   []string{"ENVIRONMENT=dev"},
+			expected: []string{"dc:foo-dev:/spec/replicas", "route:foo:/spec/host"},
+		},
+		"unknown param": {
+			paths:     []string{"dc:foo-${ENVIRONMENT}:/spec/replicas"},
+			wantError: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compareOptions := &cli.CompareOptions{
+				GlobalOptions:    cli.InitGlobalOptions(&helper.SomeFilesExistFS{}),
+				NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+				Params:           tc.params,
+			}
+			got, err := ResolvePreservePaths(tc.paths, compareOptions)
+			if tc.wantError {
+				if err == nil {
+					t.Fatal("Want error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Fatalf("Resolved paths mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSubstituteParams(t *testing.T) {
+	tests := map[string]struct {
+		input     string
+		params    map[string]string
+		expected  string
+		wantError string
+	}{
+		"literal value": {
+			input:    "app=foo",
+			params:   map[string]string{"ENVIRONMENT": "dev"},
+			expected: "app=foo",
+		},
+		"single param": {
+			input:    "env=${ENVIRONMENT}",
+			params:   map[string]string{"ENVIRONMENT": "dev"},
+			expected: "env=dev",
+		},
+		"multiple params": {
+			input:    "app=${APP},env=${ENVIRONMENT}",
+			params:   map[string]string{"APP": "foo", "ENVIRONMENT": "dev"},
+			expected: "app=foo,env=dev",
+		},
+		"unknown param": {
+			input:     "env=${ENVIRONMENT}",
+			params:    map[string]string{},
+			wantError: "Unknown parameters: ENVIRONMENT",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := substituteParams(tc.input, tc.params)
+			if len(tc.wantError) > 0 {
+				if err == nil || err.Error() != tc.wantError {
+					t.Fatalf("Want error '%s', got '%v'", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expected {
+				t.Fatalf("Want '%s', got '%s'", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestExpandIncludes(t *testing.T) {
+	dir := "../../internal/test/fixtures/template-includes/"
+	got, includesFound, err := expandIncludes(dir+"template.yml", []string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !includesFound {
+		t.Fatal("Includes should have been found")
+	}
+	want := `apiVersion: v1
+kind: Template
+objects:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+    labels:
+      app: foo
+      team: bar
+  data:
+    foo: bar
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("Expanded template mismatch (-want +got):\n%s", diff)
+	}
+
+	_, _, err = expandIncludes(dir+"cycle-a.yml", []string{})
+	if err == nil || !strings.HasPrefix(err.Error(), "Include cycle detected") {
+		t.Fatalf("Want include cycle error, got: %v", err)
+	}
+}
+
+func TestOrderParamFiles(t *testing.T) {
+	tests := map[string]struct {
+		precedence string
+		expected   []string
+	}{
+		"last wins": {
+			precedence: "last-wins",
+			expected:   []string{"foo.env", "bar.env", "baz.env"},
+		},
+		"first wins": {
+			precedence: "first-wins",
+			expected:   []string{"baz.env", "bar.env", "foo.env"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := orderParamFiles([]string{"foo.env", "bar.env", "baz.env"}, tc.precedence)
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Fatalf("Order mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParamsReport(t *testing.T) {
+	dir := "../../internal/test/fixtures/params-report/"
+	compareOptions := &cli.CompareOptions{
+		GlobalOptions:    cli.InitGlobalOptions(&helper.SomeFilesExistFS{}),
+		NamespaceOptions: &cli.NamespaceOptions{Namespace: "foo"},
+		Params:           []string{"FOO=baz"},
+		SensitiveParams:  []string{"TOKEN"},
+	}
+	paramFileBytes := []byte("FOO=bar\nTOKEN=abc\nPASSWORD=c2VjcmV0\n")
+	got, err := paramsReport("app.yml", []string{dir + "app.env"}, paramFileBytes, compareOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Params used for template app.yml:
+    FOO=baz
+    PASSWORD=***
+    TAILOR_NAMESPACE=foo
+    TOKEN=***`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("Params report mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCheckParamConflicts(t *testing.T) {
+	dir := "../../internal/test/fixtures/param-conflicts/"
+	tests := map[string]struct {
+		paramFiles []string
+		wantError  string
+	}{
+		"single file": {
+			paramFiles: []string{dir + "a.env"},
+			wantError:  "",
+		},
+		"same values": {
+			paramFiles: []string{dir + "a.env", dir + "a.env"},
+			wantError:  "",
+		},
+		"different values": {
+			paramFiles: []string{dir + "a.env", dir + "b.env"},
+			wantError:  "Conflicting param definitions:\n* BAR is defined differently in '" + dir + "a.env' and '" + dir + "b.env'",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkParamConflicts(tc.paramFiles, "", "")
+			if len(tc.wantError) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.wantError {
+				t.Fatalf("Want error '%s', got '%v'", tc.wantError, err)
+			}
+		})
+	}
+}
+
+func TestUnsetTemplateParams(t *testing.T) {
+	template := []byte(`apiVersion: v1
+kind: Template
+objects: []
+parameters:
+- name: FOO
+  value: foo
+  required: true
+- name: BAR
+  generate: expression
+  from: '[a-z]{8}'
+- name: BAZ
+  value: baz
+`)
+	got, err := unsetTemplateParams(template, []string{"FOO", "BAR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: v1
+kind: Template
+objects: []
+parameters:
+- name: FOO
+  required: false
+- name: BAR
+  required: false
+- name: BAZ
+  value: baz
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("Template mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEscapeParamDelimiters(t *testing.T) {
+	tests := map[string]struct {
+		template    string
+		wantEscaped bool
+		wantOutput  string
+	}{
+		"no escapes": {
+			template:    "data:\n  foo: ${FOO}\n",
+			wantEscaped: false,
+			wantOutput:  "data:\n  foo: foo\n",
+		},
+		"escaped delimiter": {
+			template:    "data:\n  script: echo $${HOME} ${FOO}\n",
+			wantEscaped: true,
+			wantOutput:  "data:\n  script: echo ${HOME} foo\n",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			escaped, found := escapeParamDelimiters([]byte(tc.template))
+			if found != tc.wantEscaped {
+				t.Fatalf("Want escapes found=%t, got %t", tc.wantEscaped, found)
+			}
+			// Simulate "oc process" resolving FOO.
+			processed := strings.Replace(string(escaped), "${FOO}", "foo", -1)
+			if strings.Contains(processed, "$${") {
+				t.Fatal("Escaped delimiter was not replaced before processing")
+			}
+			got := string(unescapeParamDelimiters([]byte(processed)))
+			if diff := cmp.Diff(tc.wantOutput, got); diff != "" {
+				t.Fatalf("Output mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithoutParams(t *testing.T) {
+	got, err := withoutParams([]byte("# comment\nFOO=foo\nBAR=bar\n"), []string{"FOO"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# comment\nBAR=bar\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("Params mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAnnotateTemplateHash(t *testing.T) {
+	processed := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    annotations:
+      bar: baz
+    name: bar
+`)
+	got, err := annotateTemplateHash(processed, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    annotations:
+      tailor.opendevstack.org/template-hash: abc
+    name: foo
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    annotations:
+      bar: baz
+      tailor.opendevstack.org/template-hash: abc
+    name: bar
+kind: List
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("Processed template mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAnnotateParamsHash(t *testing.T) {
+	processed := []byte(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+- apiVersion: batch/v1beta1
+  kind: CronJob
+  metadata:
+    name: bar
+  spec:
+    jobTemplate:
+      spec:
+        template:
+          spec: {}
+`)
+	got, err := annotateParamsHash(processed, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `apiVersion: v1
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+- apiVersion: batch/v1beta1
+  kind: CronJob
+  metadata:
+    name: bar
+  spec:
+    jobTemplate:
+      spec:
+        template:
+          metadata:
+            annotations:
+              tailor.opendevstack.org/params-hash: abc
+          spec: {}
+kind: List
+`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("Processed template mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParamsHash(t *testing.T) {
+	a := paramsHash(map[string]string{"FOO": "1", "BAR": "2"})
+	b := paramsHash(map[string]string{"BAR": "2", "FOO": "1"})
+	if a != b {
+		t.Fatalf("Expected hash to be independent of param order, got %s and %s", a, b)
+	}
+	c := paramsHash(map[string]string{"FOO": "1", "BAR": "3"})
+	if a == c {
+		t.Fatalf("Expected hash to change when a param value changes")
+	}
+}
@@ -0,0 +1,101 @@
+package openshift
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/opendevstack/tailor/pkg/utils"
+)
+
+// podList is the subset of a list of pods needed to find out which
+// resources the pods depend on.
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Volumes []struct {
+				PersistentVolumeClaim *struct {
+					ClaimName string `json:"claimName"`
+				} `json:"persistentVolumeClaim"`
+				Secret *struct {
+					SecretName string `json:"secretName"`
+				} `json:"secret"`
+			} `json:"volumes"`
+			Containers     []podContainer `json:"containers"`
+			InitContainers []podContainer `json:"initContainers"`
+		} `json:"spec"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type podContainer struct {
+	Env []struct {
+		ValueFrom *struct {
+			SecretKeyRef *struct {
+				Name string `json:"name"`
+			} `json:"secretKeyRef"`
+		} `json:"valueFrom"`
+	} `json:"env"`
+	EnvFrom []struct {
+		SecretRef *struct {
+			Name string `json:"name"`
+		} `json:"secretRef"`
+	} `json:"envFrom"`
+}
+
+// usageGuardedKinds are the kinds which are only deleted if no active pod
+// uses them, as deleting them while in use might lose data or break the pod.
+var usageGuardedKinds = []string{"PersistentVolumeClaim", "Secret"}
+
+// UsageGuarded returns true if resources of given kind must not be deleted
+// while in use by pods.
+func UsageGuarded(kind string) bool {
+	return utils.Includes(usageGuardedKinds, kind)
+}
+
+// PodsUsing returns the names of all active (pending or running) pods in
+// given JSON pod list which use the resource of given kind and name, e.g. by
+// mounting a persistent volume claim or referencing a secret.
+func PodsUsing(pods []byte, kind string, name string) ([]string, error) {
+	var list podList
+	err := json.Unmarshal(pods, &list)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse pods: %s", err)
+	}
+	using := []string{}
+	for _, pod := range list.Items {
+		if pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed" {
+			continue
+		}
+		used := false
+		for _, v := range pod.Spec.Volumes {
+			switch kind {
+			case "PersistentVolumeClaim":
+				used = used || (v.PersistentVolumeClaim != nil && v.PersistentVolumeClaim.ClaimName == name)
+			case "Secret":
+				used = used || (v.Secret != nil && v.Secret.SecretName == name)
+			}
+		}
+		if kind == "Secret" {
+			containers := append(pod.Spec.InitContainers, pod.Spec.Containers...)
+			for _, c := range containers {
+				for _, e := range c.Env {
+					used = used || (e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil && e.ValueFrom.SecretKeyRef.Name == name)
+				}
+				for _, e := range c.EnvFrom {
+					used = used || (e.SecretRef != nil && e.SecretRef.Name == name)
+				}
+			}
+		}
+		if used {
+			using = append(using, pod.Metadata.Name)
+		}
+	}
+	sort.Strings(using)
+	return using, nil
+}
@@ -0,0 +1,75 @@
+package openshift
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPodsUsing(t *testing.T) {
+	pods := []byte(`{"items": [
+  {
+    "metadata": {"name": "db-1"},
+    "spec": {"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "db"}}], "containers": []},
+    "status": {"phase": "Running"}
+  },
+  {
+    "metadata": {"name": "db-migrate-1"},
+    "spec": {"volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "db"}}], "containers": []},
+    "status": {"phase": "Succeeded"}
+  },
+  {
+    "metadata": {"name": "app-1"},
+    "spec": {
+      "volumes": [{"name": "tls", "secret": {"secretName": "tls"}}],
+      "containers": [{
+        "env": [{"name": "PASSWORD", "valueFrom": {"secretKeyRef": {"name": "db-credentials", "key": "password"}}}],
+        "envFrom": [{"secretRef": {"name": "app-config"}}]
+      }]
+    },
+    "status": {"phase": "Pending"}
+  }
+]}`)
+	tests := map[string]struct {
+		kind string
+		name string
+		want []string
+	}{
+		"claim of running pod": {
+			kind: "PersistentVolumeClaim",
+			name: "db",
+			want: []string{"db-1"},
+		},
+		"secret volume": {
+			kind: "Secret",
+			name: "tls",
+			want: []string{"app-1"},
+		},
+		"secret key ref": {
+			kind: "Secret",
+			name: "db-credentials",
+			want: []string{"app-1"},
+		},
+		"secret env from": {
+			kind: "Secret",
+			name: "app-config",
+			want: []string{"app-1"},
+		},
+		"unused": {
+			kind: "Secret",
+			name: "db",
+			want: []string{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := PodsUsing(pods, tc.kind, tc.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Fatalf("Pods mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
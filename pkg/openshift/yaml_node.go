@@ -0,0 +1,345 @@
+package openshift
+
+import (
+	"fmt"
+	"strings"
+)
+
+// yamlNode is a node of a YAML document parsed by parseYAMLNodes. Only the
+// subset of YAML used by param files is supported: block mappings and
+// sequences, plain and quoted scalars, block scalars ("|" and ">") and
+// comments. Flow collections, anchors and tags are kept as plain scalars.
+// In contrast to unmarshalling, each scalar keeps its location in the
+// source, so that values can be replaced without touching the rest of the
+// document (e.g. comments and formatting).
+type yamlNode struct {
+	// key is the key of the node within its parent mapping, or its index
+	// (e.g. "[0]") within its parent sequence.
+	key      string
+	children []*yamlNode
+	scalar   *yamlScalar
+}
+
+// yamlScalar is the location and value of a scalar in the source lines.
+type yamlScalar struct {
+	value string
+	// line, start and end locate inline scalars, including their quotes.
+	line  int
+	start int
+	end   int
+	quote string
+	// block is set for block scalars, which span the lines from firstLine to
+	// lastLine (inclusive) with given indentation.
+	block       bool
+	firstLine   int
+	lastLine    int
+	indentation string
+}
+
+// scalars calls fn for each scalar below n with its path. Keys of mappings
+// are joined by ".", and items of sequences are indexed (e.g.
+// "database.hosts[0]").
+func (n *yamlNode) scalars(path string, fn func(path string, scalar *yamlScalar) error) error {
+	switch {
+	case len(path) == 0 || strings.HasPrefix(n.key, "["):
+		path += n.key
+	case len(n.key) > 0:
+		path += "." + n.key
+	}
+	if n.scalar != nil {
+		return fn(path, n.scalar)
+	}
+	for _, child := range n.children {
+		err := child.scalars(path, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlParser parses lines into nodes. pos is the index of the next line
+// which has not been consumed yet.
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+// parseYAMLNodes parses input into one root node per document.
+func parseYAMLNodes(input string) ([]*yamlNode, error) {
+	p := &yamlParser{lines: strings.Split(input, "\n")}
+	documents := []*yamlNode{}
+	for {
+		p.skipInsignificant()
+		if p.pos >= len(p.lines) {
+			return documents, nil
+		}
+		if isYAMLDocumentMarker(p.lines[p.pos]) {
+			p.pos++
+			continue
+		}
+		line := p.pos
+		node, err := p.parseNode(line, yamlIndentation(p.lines[line]), -1)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, node)
+		p.skipInsignificant()
+		if p.pos < len(p.lines) && !isYAMLDocumentMarker(p.lines[p.pos]) {
+			return nil, fmt.Errorf("Unexpected content in line %d: %s", p.pos+1, strings.TrimSpace(p.lines[p.pos]))
+		}
+	}
+}
+
+// skipInsignificant advances pos past blank lines and comments.
+func (p *yamlParser) skipInsignificant() {
+	for p.pos < len(p.lines) {
+		trimmed := strings.TrimSpace(p.lines[p.pos])
+		if len(trimmed) > 0 && !strings.HasPrefix(trimmed, "#") {
+			return
+		}
+		p.pos++
+	}
+}
+
+// nextIndentation returns the indentation of the next significant line, or
+// -1 if there is none (or it starts a new document).
+func (p *yamlParser) nextIndentation() int {
+	p.skipInsignificant()
+	if p.pos >= len(p.lines) || isYAMLDocumentMarker(p.lines[p.pos]) {
+		return -1
+	}
+	return yamlIndentation(p.lines[p.pos])
+}
+
+// parseNode parses the node starting in given line at column col. Block
+// scalars and nested nodes need to be indented more than parentIndentation.
+func (p *yamlParser) parseNode(line int, col int, parentIndentation int) (*yamlNode, error) {
+	content := p.lines[line][col:]
+	switch {
+	case isYAMLSequenceItem(content):
+		return p.parseSequence(line, col)
+	case yamlKeyLength(content) > 0:
+		return p.parseMapping(line, col)
+	default:
+		scalar, err := p.parseScalar(line, col, parentIndentation)
+		if err != nil {
+			return nil, err
+		}
+		return &yamlNode{scalar: scalar}, nil
+	}
+}
+
+// parseSequence parses the items of a sequence, whose first item starts in
+// given line at column col.
+func (p *yamlParser) parseSequence(line int, col int) (*yamlNode, error) {
+	node := &yamlNode{}
+	for {
+		itemCol := col + 1
+		for itemCol < len(p.lines[line]) && p.lines[line][itemCol] == ' ' {
+			itemCol++
+		}
+		var item *yamlNode
+		var err error
+		if isYAMLValueBlank(p.lines[line][itemCol:]) {
+			p.pos = line + 1
+			item, err = p.parseNested(col, false)
+		} else {
+			item, err = p.parseNode(line, itemCol, col)
+		}
+		if err != nil {
+			return nil, err
+		}
+		item.key = fmt.Sprintf("[%d]", len(node.children))
+		node.children = append(node.children, item)
+
+		if p.nextIndentation() != col || !isYAMLSequenceItem(p.lines[p.pos][col:]) {
+			return node, nil
+		}
+		line = p.pos
+	}
+}
+
+// parseMapping parses the entries of a mapping, whose first key starts in
+// given line at column col.
+func (p *yamlParser) parseMapping(line int, col int) (*yamlNode, error) {
+	node := &yamlNode{}
+	for {
+		content := p.lines[line][col:]
+		keyLength := yamlKeyLength(content)
+		if keyLength == 0 {
+			return nil, fmt.Errorf("Expected key in line %d: %s", line+1, strings.TrimSpace(content))
+		}
+		key := unquoteYAMLKey(strings.TrimSpace(content[:keyLength-1]))
+		valueCol := col + keyLength
+		for valueCol < len(p.lines[line]) && p.lines[line][valueCol] == ' ' {
+			valueCol++
+		}
+		var value *yamlNode
+		var err error
+		if isYAMLValueBlank(p.lines[line][valueCol:]) {
+			p.pos = line + 1
+			// Sequences may have the same indentation as their key.
+			value, err = p.parseNested(col, true)
+		} else {
+			value, err = p.parseNode(line, valueCol, col)
+		}
+		if err != nil {
+			return nil, err
+		}
+		value.key = key
+		node.children = append(node.children, value)
+
+		indentation := p.nextIndentation()
+		if indentation < col {
+			return node, nil
+		}
+		if indentation > col {
+			return nil, fmt.Errorf("Unexpected indentation in line %d: %s", p.pos+1, strings.TrimSpace(p.lines[p.pos]))
+		}
+		line = p.pos
+	}
+}
+
+// parseNested parses the value of a sequence item or mapping entry which
+// starts on the next significant line. If there is no such line, the value
+// is an empty scalar.
+func (p *yamlParser) parseNested(parentIndentation int, allowSequenceAtSameIndentation bool) (*yamlNode, error) {
+	indentation := p.nextIndentation()
+	if indentation > parentIndentation ||
+		(allowSequenceAtSameIndentation && indentation == parentIndentation && isYAMLSequenceItem(p.lines[p.pos][indentation:])) {
+		return p.parseNode(p.pos, indentation, parentIndentation)
+	}
+	return &yamlNode{scalar: &yamlScalar{line: -1}}, nil
+}
+
+// parseScalar parses the scalar starting in given line at column col.
+func (p *yamlParser) parseScalar(line int, col int, parentIndentation int) (*yamlScalar, error) {
+	text := p.lines[line]
+	p.pos = line + 1
+	switch text[col] {
+	case '|', '>':
+		return p.parseBlockScalar(parentIndentation), nil
+	case '"', '\'':
+		quote := text[col : col+1]
+		end := col + 1
+		for end < len(text) {
+			if text[end] == '\\' && quote == "\"" {
+				end += 2
+				continue
+			}
+			if text[end] == quote[0] {
+				if quote == "'" && end+1 < len(text) && text[end+1] == '\'' {
+					end += 2
+					continue
+				}
+				break
+			}
+			end++
+		}
+		if end >= len(text) {
+			return nil, fmt.Errorf("Unterminated quoted value in line %d: %s", line+1, strings.TrimSpace(text))
+		}
+		return &yamlScalar{value: text[col+1 : end], line: line, start: col, end: end + 1, quote: quote}, nil
+	default:
+		end := len(text)
+		if i := strings.Index(text[col:], " #"); i >= 0 {
+			end = col + i
+		}
+		value := strings.TrimRight(text[col:end], " \t")
+		return &yamlScalar{value: value, line: line, start: col, end: col + len(value)}, nil
+	}
+}
+
+// parseBlockScalar parses the content of a block scalar, which starts on
+// the line at pos. Its indentation is given by the first non-blank line.
+func (p *yamlParser) parseBlockScalar(parentIndentation int) *yamlScalar {
+	scalar := &yamlScalar{block: true, firstLine: p.pos, lastLine: p.pos - 1}
+	indentation := -1
+	contentLines := []string{}
+	for i := p.pos; i < len(p.lines); i++ {
+		if len(strings.TrimSpace(p.lines[i])) == 0 {
+			continue
+		}
+		lineIndentation := yamlIndentation(p.lines[i])
+		if indentation == -1 {
+			if lineIndentation <= parentIndentation {
+				break
+			}
+			indentation = lineIndentation
+		}
+		if lineIndentation < indentation {
+			break
+		}
+		scalar.lastLine = i
+	}
+	for i := scalar.firstLine; i <= scalar.lastLine; i++ {
+		if len(p.lines[i]) > indentation {
+			contentLines = append(contentLines, p.lines[i][indentation:])
+		} else {
+			contentLines = append(contentLines, "")
+		}
+	}
+	if indentation > 0 {
+		scalar.indentation = strings.Repeat(" ", indentation)
+	}
+	scalar.value = strings.Join(contentLines, "\n")
+	p.pos = scalar.lastLine + 1
+	return scalar
+}
+
+// yamlKeyLength returns the length of the key (including the colon) at the
+// beginning of content, or 0 if content does not start with a key.
+func yamlKeyLength(content string) int {
+	if len(content) == 0 || strings.ContainsRune("#[]{}|>&*!%@`", rune(content[0])) || isYAMLSequenceItem(content) {
+		return 0
+	}
+	start := 0
+	if content[0] == '"' || content[0] == '\'' {
+		end := strings.IndexByte(content[1:], content[0])
+		if end < 0 {
+			return 0
+		}
+		start = end + 2
+	}
+	for i := start; i < len(content); i++ {
+		if content[i] == '#' && i > 0 && content[i-1] == ' ' {
+			return 0
+		}
+		if content[i] == ':' && (i+1 == len(content) || content[i+1] == ' ' || content[i+1] == '\t') {
+			return i + 1
+		}
+		if start > 0 && content[i] != ' ' {
+			// A quoted key must be followed directly by the colon.
+			return 0
+		}
+	}
+	return 0
+}
+
+func unquoteYAMLKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
+
+func isYAMLSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ") || strings.HasPrefix(content, "-\t")
+}
+
+// isYAMLValueBlank returns true if content (the rest of a line after a key
+// or "-") contains no value, only a comment at most.
+func isYAMLValueBlank(content string) bool {
+	trimmed := strings.TrimSpace(content)
+	return len(trimmed) == 0 || strings.HasPrefix(trimmed, "#")
+}
+
+func isYAMLDocumentMarker(line string) bool {
+	trimmed := strings.TrimRight(line, " \t")
+	return trimmed == "---" || trimmed == "..." || strings.HasPrefix(trimmed, "--- ")
+}
+
+func yamlIndentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -118,6 +118,26 @@ func GetEntityList(keys []string, passphrase string) (openpgp.EntityList, error)
 	return entityList, nil
 }
 
+// PrivateKeyEncrypted returns true if the private key in filename is
+// protected by a passphrase.
+func PrivateKeyEncrypted(filename string) (bool, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	l, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return false, fmt.Errorf("Reading key '%s' failed: %s", filename, err)
+	}
+	for _, entity := range l {
+		if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // Encrypts secret with all public keys and base64-encodes the result.
 func Encrypt(secret string, entityList openpgp.EntityList) (string, error) {
 	// Encrypt message using public keys
@@ -144,6 +164,33 @@ func Encrypt(secret string, entityList openpgp.EntityList) (string, error) {
 	return str, nil
 }
 
+// RecipientKeyIds returns the IDs of the keys for which the base64-encoded
+// string was encrypted.
+func RecipientKeyIds(encoded string) ([]uint64, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("Decoding '%s' failed: %s", encoded, err)
+	}
+	keyIds := []uint64{}
+	packets := packet.NewReader(bytes.NewBuffer(encrypted))
+	for {
+		p, err := packets.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Reading '%s' failed: %s", encoded, err)
+		}
+		ek, ok := p.(*packet.EncryptedKey)
+		if !ok {
+			// Encrypted keys precede the encrypted data
+			break
+		}
+		keyIds = append(keyIds, ek.KeyId)
+	}
+	return keyIds, nil
+}
+
 // Decrypts the base64-encoded string end decrypts with the private key.
 func Decrypt(encoded string, entityList openpgp.EntityList) (string, error) {
 	// Decode bas64-encoded string
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestPrivateKeyEncrypted(t *testing.T) {
+	encrypted, err := PrivateKeyEncrypted("../openshift/test-private.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted {
+		t.Fatal("Want key without passphrase to be reported as not encrypted")
+	}
+	_, err = PrivateKeyEncrypted("does-not-exist.key")
+	if err == nil {
+		t.Fatal("Want error for missing key")
+	}
+}
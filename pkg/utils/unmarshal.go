@@ -4,8 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+var yamlErrorLineRegex = regexp.MustCompile(`\bline (\d+):`)
+
 // displaySyntaxError will display more information
 // such as line and error type given an error and
 // the data that was unmarshalled.
@@ -29,3 +34,37 @@ func DisplaySyntaxError(data []byte, syntaxError error) (err error) {
 	err = fmt.Errorf("\nError in line %d: %s \n%s\n%s^", line, syntaxError, data[start:end], bytes.Repeat(space, pos))
 	return
 }
+
+// YAMLErrorLine returns the line number a YAML parse error refers to, or 0
+// if the error does not name a line.
+func YAMLErrorLine(yamlError error) int {
+	matches := yamlErrorLineRegex.FindStringSubmatch(yamlError.Error())
+	if matches == nil {
+		return 0
+	}
+	line, _ := strconv.Atoi(matches[1])
+	return line
+}
+
+// Snippet returns the lines of data surrounding given line (starting at 1),
+// with line numbers and the line itself marked by ">".
+func Snippet(data []byte, line int, context int) string {
+	lines := strings.Split(string(data), "\n")
+	from := line - context
+	if from < 1 {
+		from = 1
+	}
+	to := line + context
+	if to > len(lines) {
+		to = len(lines)
+	}
+	var b strings.Builder
+	for i := from; i <= to; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %4d | %s\n", marker, i, lines[i-1])
+	}
+	return b.String()
+}